@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,6 +16,10 @@ import (
 	"helpdesk-backend/internal/auth"
 	"helpdesk-backend/internal/config"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/mail"
+	"helpdesk-backend/internal/metrics"
 	"helpdesk-backend/internal/repository"
 	"helpdesk-backend/internal/service"
 
@@ -30,22 +35,40 @@ func main() {
 		log.Fatal("Failed to load configuration:", err)
 	}
 
+	// Structured logging from LOG_LEVEL and LOG_FORMAT. The standard log
+	// package writes through it as well.
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+	if err != nil {
+		slog.Warn("Invalid logging configuration", "error", err)
+	}
+
+	// Background workers run until the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
 	// Initialize router with basic health check routes
-	router := setupBasicRouter(cfg)
+	registry := metrics.NewRegistry()
+	router := setupBasicRouter(cfg, logger, registry)
 
 	// Try to initialize database (optional for development)
 	db, err := initDatabase(cfg.DatabaseURL)
 	if err != nil {
-		log.Printf("WARNING: Database connection failed: %v", err)
+		slog.Warn("Database connection failed", "error", err)
 	} else {
-		// Initialize full API when database is available
-		setupFullAPI(router, db, cfg)
-
-		// Run seeder after database setup
+		// Run seeder after database setup, before the API assigns keys to
+		// tickets that do not have one
 		seeder := repository.NewSeeder(db)
 		if err := seeder.SeedInitialData(); err != nil {
-			log.Printf("WARNING: Failed to seed initial data: %v", err)
+			slog.Warn("Failed to seed initial data", "error", err)
 		}
+
+		if sqlDB, err := db.DB(); err == nil {
+			registry.Register(metrics.DBStats(sqlDB))
+		}
+
+		// Initialize full API when database is available
+		setupFullAPI(workerCtx, router, db, cfg, registry)
 	}
 
 	// Start server in a goroutine
@@ -55,13 +78,14 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
-		log.Printf("Environment: %s", cfg.Environment)
-		log.Printf("Health check: http://localhost:%s/health", cfg.Port)
-		log.Printf("API users: http://localhost:%s/api/v1/users", cfg.Port)
+		slog.Info("Server starting",
+			"port", cfg.Port,
+			"environment", cfg.Environment,
+			"health_check", fmt.Sprintf("http://localhost:%s/health", cfg.Port))
 
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			slog.Error("Failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -69,14 +93,16 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
+	stopWorkers()
 
 	// The context is used to inform the server it has 5 seconds to finish
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
 	// Close database connection if it exists
@@ -87,7 +113,7 @@ func main() {
 		}
 	}
 
-	log.Println("Server exited gracefully")
+	slog.Info("Server exited gracefully")
 }
 
 func initDatabase(databaseURL string) (*gorm.DB, error) {
@@ -96,7 +122,9 @@ func initDatabase(databaseURL string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("database URL is required")
 	}
 
-	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
+		Logger: logging.NewGormLogger(200 * time.Millisecond),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -114,21 +142,30 @@ func initDatabase(databaseURL string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("SUCCESS: Database connected successfully")
+	slog.Info("Database connected")
 
 	// Auto-migrate the schema
-	err = db.AutoMigrate(&domain.User{}, &domain.Ticket{}, &domain.Comment{}, &domain.SLA{}, &domain.Computer{})
+	err = db.AutoMigrate(&domain.User{}, &domain.Ticket{}, &domain.Comment{}, &domain.SLA{}, &domain.Computer{}, &domain.WorkLog{}, &domain.WorkTimer{},
+		&domain.Tag{}, &domain.BulkJob{}, &domain.BulkJobResult{}, &domain.Mention{}, &domain.TicketWatcher{}, &domain.Attachment{},
+		&domain.Notification{}, &domain.NotificationPreference{}, &domain.Webhook{}, &domain.WebhookDelivery{},
+		&domain.ScheduledReport{}, &domain.ReportRun{},
+		&domain.TicketKeyCounter{}, &domain.TicketStatusChange{}, &domain.PriorityMatrixEntry{}, &domain.PriorityOverride{},
+		&domain.Problem{}, &domain.ChangeRequest{}, &domain.ChangeApproval{},
+		&domain.Article{}, &domain.ArticleRevision{}, &domain.ArticleTicketLink{}, &domain.TicketDeflection{},
+		&domain.CommentRevision{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
 	}
 
-	log.Println("SUCCESS: Database schema migrated successfully")
+	slog.Info("Database schema migrated")
 
 	return db, nil
 }
 
-func setupBasicRouter(cfg *config.Config) *gin.Engine {
-	router := gin.Default()
+func setupBasicRouter(cfg *config.Config, logger *slog.Logger, registry *metrics.Registry) *gin.Engine {
+	router := gin.New()
+	// Recovery runs last so that the request log and metrics see the 500 of a panic
+	router.Use(logging.Middleware(logger), registry.Middleware(), logging.Recovery())
 
 	// Enable CORS middleware reading FRONTEND_URL from environment (comma separated)
 	frontend := os.Getenv("FRONTEND_URL")
@@ -150,6 +187,13 @@ func setupBasicRouter(cfg *config.Config) *gin.Engine {
 		})
 	})
 
+	// Prometheus metrics, protected by METRICS_API_KEY when it is set
+	metricsHandlers := []gin.HandlerFunc{registry.Handler()}
+	if cfg.MetricsAPIKey != "" {
+		metricsHandlers = append([]gin.HandlerFunc{auth.APIKeyMiddleware(cfg.MetricsAPIKey)}, metricsHandlers...)
+	}
+	router.GET("/metrics", metricsHandlers...)
+
 	// API group with basic routes
 	api := router.Group("/api/v1")
 	{
@@ -170,20 +214,94 @@ func setupBasicRouter(cfg *config.Config) *gin.Engine {
 }
 
 // This function will be used when database is available
-func setupFullAPI(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
-	log.Println("Setting up API with database...")
+func setupFullAPI(workerCtx context.Context, router *gin.Engine, db *gorm.DB, cfg *config.Config, registry *metrics.Registry) {
+	slog.Info("Setting up API with database")
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	ticketRepo := repository.NewTicketRepository(db)
 	commentRepo := repository.NewCommentRepository(db)
 	computerRepo := repository.NewComputerRepository(db)
+	workLogRepo := repository.NewWorkLogRepository(db)
+	bulkJobRepo := repository.NewBulkJobRepository(db)
+	mentionRepo := repository.NewMentionRepository(db)
+	watcherRepo := repository.NewWatcherRepository(db)
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	scheduledReportRepo := repository.NewScheduledReportRepository(db)
+	priorityRepo := repository.NewPriorityRepository(db)
+	problemRepo := repository.NewProblemRepository(db)
+	changeRepo := repository.NewChangeRepository(db)
+	articleRepo := repository.NewArticleRepository(db)
+	deflectionRepo := repository.NewDeflectionRepository(db)
+	txManager := repository.NewTxManager(db)
+
+	// Events published by services are streamed to clients over SSE
+	broker := events.NewBroker(1000)
 
 	// Initialize services
 	userService := service.NewUserService(userRepo)
-	ticketService := service.NewTicketService(ticketRepo)
-	commentService := service.NewCommentService(commentRepo)
+	priorityService := service.NewPriorityService(priorityRepo)
+	attachmentService := service.NewAttachmentService(attachmentRepo, ticketRepo, commentRepo, cfg.UploadPath, cfg.MaxFileSizeBytes(), cfg.AllowedFileTypeList(), cfg.JWTSecret)
+	ticketService := service.NewTicketService(ticketRepo, userRepo, workLogRepo, attachmentService, txManager, priorityService, broker, service.TicketKeyFormat{
+		Prefix:           strings.ToUpper(cfg.TicketKeyPrefix),
+		CategoryPrefixes: cfg.TicketKeyCategoryPrefixMap(),
+		Yearly:           cfg.TicketKeyYearly,
+		Digits:           cfg.TicketKeyDigits,
+	})
+	mentionService := service.NewMentionService(mentionRepo, watcherRepo, userRepo, broker)
+	commentService := service.NewCommentService(commentRepo, ticketRepo, attachmentService, mentionService, broker)
+	problemService := service.NewProblemService(problemRepo, ticketRepo, userRepo, ticketService, commentService)
+	changeService := service.NewChangeService(txManager, changeRepo, userRepo)
+	articleService := service.NewArticleService(articleRepo, ticketRepo)
+	suggestionService := service.NewSuggestionService(articleRepo, ticketRepo, deflectionRepo, articleService)
+	reportService := service.NewReportService(ticketRepo)
 	computerService := service.NewComputerService(computerRepo, userRepo)
+	workLogService := service.NewWorkLogService(workLogRepo, ticketRepo)
+	bulkService := service.NewBulkService(txManager, ticketRepo, userRepo, bulkJobRepo, priorityService, broker)
+	slaMonitor := service.NewSLAMonitor(ticketRepo, broker)
+	webhookService := service.NewWebhookService(webhookRepo, broker)
+	alertService := service.NewAlertService(ticketRepo, userRepo, ticketService, commentService, cfg.AlertRequesterEmail, cfg.AlertCategory)
+	mailer := mail.NewMailer(cfg)
+	notificationService := service.NewNotificationService(notificationRepo, ticketRepo, userRepo, watcherRepo, broker, mailer, strings.Split(cfg.FrontendURL, ",")[0])
+	scheduledReportService := service.NewScheduledReportService(scheduledReportRepo, reportService, mailer)
+	registry.Register(service.NewMetricsCollector(ticketRepo, bulkJobRepo, webhookRepo))
+
+	// Number tickets created before ticket keys existed. This runs before the
+	// API is served so that old tickets get the lower numbers of their year.
+	if err := ticketService.AssignMissingKeys(workerCtx); err != nil {
+		slog.Warn("Failed to assign ticket keys", "error", err)
+	}
+	// Tickets created before the priority matrix get an impact and urgency
+	// matching their priority, so that later edits do not recompute it
+	if err := priorityService.BackfillImpactUrgency(workerCtx); err != nil {
+		slog.Warn("Failed to backfill ticket impact and urgency", "error", err)
+	}
+	// Fails while duplicate alert tickets from before the index are open
+	if err := alertService.EnsureIndex(workerCtx); err != nil {
+		slog.Warn("Failed to create alert fingerprint index", "error", err)
+	}
+	// Article search still works without the index, only slower
+	if err := articleService.EnsureSearchIndex(workerCtx); err != nil {
+		slog.Warn("Failed to create article search index", "error", err)
+	}
+
+	// Start background workers
+	go bulkService.Start(logging.With(workerCtx, "worker", "bulk"))
+	go slaMonitor.Start(logging.With(workerCtx, "worker", "sla"))
+	go notificationService.Start(logging.With(workerCtx, "worker", "notifications"))
+	go webhookService.Start(logging.With(workerCtx, "worker", "webhooks"))
+	go scheduledReportService.Start(logging.With(workerCtx, "worker", "scheduled_reports"))
+	go func() {
+		// Render HTML for tickets and comments written before Markdown support
+		if err := ticketService.RenderMissingHTML(workerCtx); err != nil {
+			slog.Warn("Failed to render ticket descriptions", "error", err)
+		}
+		if err := commentService.RenderMissingHTML(workerCtx); err != nil {
+			slog.Warn("Failed to render comments", "error", err)
+		}
+	}()
 
 	// Initialize JWT service
 	jwtService := auth.NewJWTService(
@@ -193,7 +311,28 @@ func setupFullAPI(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	)
 
 	// Setup API routes with JWT authentication
-	api.SetupRoutes(router, userService, ticketService, commentService, computerService, jwtService)
-
-	log.Println("SUCCESS: Full API setup complete with JWT authentication!")
+	api.SetupRoutes(router, &api.Services{
+		User:            userService,
+		Ticket:          ticketService,
+		Comment:         commentService,
+		Computer:        computerService,
+		WorkLog:         workLogService,
+		Bulk:            bulkService,
+		Mention:         mentionService,
+		Attachment:      attachmentService,
+		Notification:    notificationService,
+		Webhook:         webhookService,
+		Alert:           alertService,
+		Priority:        priorityService,
+		Problem:         problemService,
+		Change:          changeService,
+		Article:         articleService,
+		Suggestion:      suggestionService,
+		Report:          reportService,
+		ScheduledReport: scheduledReportService,
+		Events:          broker,
+		AlertAPIKey:     cfg.AlertAPIKey,
+	}, jwtService)
+
+	slog.Info("Full API setup complete")
 }
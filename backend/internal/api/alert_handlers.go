@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// alertmanagerPayload is the body Prometheus Alertmanager POSTs to webhook receivers
+type alertmanagerPayload struct {
+	Version           string            `json:"version"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		EndsAt       time.Time         `json:"endsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// genericAlert is the simple JSON alert format for monitoring tools without
+// Alertmanager support
+type genericAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      string            `json:"status"`
+	Severity    string            `json:"severity"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Source      string            `json:"source"`
+	URL         string            `json:"url"`
+	Labels      map[string]string `json:"labels"`
+	StartsAt    time.Time         `json:"starts_at"`
+	EndsAt      time.Time         `json:"ends_at"`
+}
+
+func alertmanagerWebhookHandler(alertService *service.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload alertmanagerPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		alerts := make([]service.Alert, 0, len(payload.Alerts))
+		for _, a := range payload.Alerts {
+			title := firstNonEmpty(a.Annotations["summary"], a.Annotations["title"], a.Labels["alertname"], "Alert")
+			if instance := a.Labels["instance"]; instance != "" && !strings.Contains(title, instance) {
+				title += " (" + instance + ")"
+			}
+
+			alerts = append(alerts, service.Alert{
+				Fingerprint: a.Fingerprint,
+				Status:      parseAlertStatus(a.Status),
+				Severity:    firstNonEmpty(a.Labels["severity"], a.Labels["priority"]),
+				Title:       title,
+				Description: firstNonEmpty(a.Annotations["description"], a.Annotations["message"]),
+				Source:      firstNonEmpty(payload.Receiver, "alertmanager"),
+				URL:         firstNonEmpty(a.GeneratorURL, payload.ExternalURL),
+				Labels:      a.Labels,
+				StartsAt:    a.StartsAt,
+				EndsAt:      a.EndsAt,
+			})
+		}
+
+		respondAlertResults(c, alertService, alerts)
+	}
+}
+
+// genericAlertHandler accepts a single alert object or an array of them
+func genericAlertHandler(alertService *service.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var raw json.RawMessage
+		if err := c.ShouldBindJSON(&raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var items []genericAlert
+		if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else {
+			var item genericAlert
+			if err := json.Unmarshal(raw, &item); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			items = append(items, item)
+		}
+
+		alerts := make([]service.Alert, 0, len(items))
+		for _, item := range items {
+			if item.Title == "" && item.Fingerprint == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each alert needs a title or a fingerprint"})
+				return
+			}
+			alerts = append(alerts, service.Alert{
+				Fingerprint: item.Fingerprint,
+				Status:      parseAlertStatus(item.Status),
+				Severity:    item.Severity,
+				Title:       firstNonEmpty(item.Title, "Alert "+item.Fingerprint),
+				Description: item.Description,
+				Source:      item.Source,
+				URL:         item.URL,
+				Labels:      item.Labels,
+				StartsAt:    item.StartsAt,
+				EndsAt:      item.EndsAt,
+			})
+		}
+
+		respondAlertResults(c, alertService, alerts)
+	}
+}
+
+func respondAlertResults(c *gin.Context, alertService *service.AlertService, alerts []service.Alert) {
+	results, err := alertService.Ingest(c.Request.Context(), alerts)
+	if err != nil {
+		if errors.Is(err, service.ErrAlertIntakeDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// parseAlertStatus treats anything but "resolved" (or "ok") as firing
+func parseAlertStatus(status string) service.AlertStatus {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "resolved", "ok":
+		return service.AlertResolved
+	default:
+		return service.AlertFiring
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
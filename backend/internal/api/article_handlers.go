@@ -0,0 +1,322 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type articleRequest struct {
+	Title      *string                   `json:"title"`
+	Body       *string                   `json:"body"`
+	Category   *string                   `json:"category"`
+	Status     *domain.ArticleStatus     `json:"status"`
+	Visibility *domain.ArticleVisibility `json:"visibility"`
+}
+
+// apply copies the fields present in the request onto the article
+func (r articleRequest) apply(article *domain.Article) {
+	if r.Title != nil {
+		article.Title = *r.Title
+	}
+	if r.Body != nil {
+		article.Body = *r.Body
+	}
+	if r.Category != nil {
+		article.Category = *r.Category
+	}
+	if r.Status != nil {
+		article.Status = *r.Status
+	}
+	if r.Visibility != nil {
+		article.Visibility = *r.Visibility
+	}
+}
+
+func createArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req articleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		article := &domain.Article{}
+		req.apply(article)
+
+		authorID, _ := auth.GetCurrentUserID(c)
+		if err := articleService.CreateArticle(c.Request.Context(), article, authorID); err != nil {
+			respondArticleError(c, err, "Failed to create article")
+			return
+		}
+
+		setETag(c, article.Version)
+		c.JSON(http.StatusCreated, article)
+	}
+}
+
+// listArticlesHandler lists and searches articles. With q the results are
+// ranked by relevance.
+func listArticlesHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		filter := repository.ArticleFilter{
+			Query:      c.Query("q"),
+			Category:   c.Query("category"),
+			Status:     domain.ArticleStatus(c.Query("status")),
+			Visibility: domain.ArticleVisibility(c.Query("visibility")),
+		}
+		if filter.Status != "" && !filter.Status.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		if filter.Visibility != "" && !filter.Visibility.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visibility"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		articles, err := articleService.ListArticles(c.Request.Context(), filter, viewer, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+			return
+		}
+
+		c.JSON(http.StatusOK, articles)
+	}
+}
+
+func listArticleCategoriesHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, _ := auth.GetViewer(c)
+		categories, err := articleService.Categories(c.Request.Context(), viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+			return
+		}
+
+		c.JSON(http.StatusOK, categories)
+	}
+}
+
+// getArticleHandler returns an article and counts the view
+func getArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		article, err := articleService.ViewArticle(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch article")
+			return
+		}
+
+		setETag(c, article.Version)
+		c.JSON(http.StatusOK, article)
+	}
+}
+
+func updateArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		var req articleRequest
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		article, err := articleService.GetArticle(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch article")
+			return
+		}
+
+		if !ifMatchSatisfied(c, article.Version) {
+			respondVersionConflict(c, "Article was modified by another user", article, article.Version)
+			return
+		}
+
+		req.apply(article)
+
+		if err := articleService.UpdateArticle(c.Request.Context(), article, viewer.UserID); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := articleService.GetArticle(c.Request.Context(), article.ID, viewer); getErr == nil {
+					respondVersionConflict(c, "Article was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondArticleError(c, err, "Failed to update article")
+			return
+		}
+
+		setETag(c, article.Version)
+		c.JSON(http.StatusOK, article)
+	}
+}
+
+func deleteArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		if err := articleService.DeleteArticle(c.Request.Context(), uint(id)); err != nil {
+			respondArticleError(c, err, "Failed to delete article")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Article deleted successfully"})
+	}
+}
+
+func listArticleRevisionsHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		revisions, err := articleService.ListRevisions(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch article revisions")
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+func listArticleTicketsHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		tickets, err := articleService.ListTickets(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch article tickets")
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+func listTicketArticlesHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		articles, err := articleService.ListTicketArticles(c.Request.Context(), uint(ticketID), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch ticket articles")
+			return
+		}
+
+		c.JSON(http.StatusOK, articles)
+	}
+}
+
+// linkTicketArticleHandler records that an article solved the ticket
+func linkTicketArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		var req struct {
+			ArticleID uint `json:"article_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		if err := articleService.LinkTicket(c.Request.Context(), req.ArticleID, uint(ticketID), viewer); err != nil {
+			respondArticleError(c, err, "Failed to link article")
+			return
+		}
+
+		articles, err := articleService.ListTicketArticles(c.Request.Context(), uint(ticketID), viewer)
+		if err != nil {
+			respondArticleError(c, err, "Failed to fetch ticket articles")
+			return
+		}
+
+		c.JSON(http.StatusOK, articles)
+	}
+}
+
+func unlinkTicketArticleHandler(articleService *service.ArticleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+		articleID, err := strconv.ParseUint(c.Param("articleId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+
+		if err := articleService.UnlinkTicket(c.Request.Context(), uint(articleID), uint(ticketID)); err != nil {
+			respondArticleError(c, err, "Failed to unlink article")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Article unlinked successfully"})
+	}
+}
+
+func respondArticleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article or ticket not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	case errors.Is(err, service.ErrInvalidArticle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
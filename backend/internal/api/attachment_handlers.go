@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func uploadAttachmentHandler(attachmentService *service.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A file must be uploaded in the 'file' form field"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+
+		attachment, err := attachmentService.Upload(c.Request.Context(), uint(ticketID), header.Filename, file, viewer)
+		if err != nil {
+			respondAttachmentError(c, err, "Failed to store attachment")
+			return
+		}
+
+		c.JSON(http.StatusCreated, attachment)
+	}
+}
+
+func listAttachmentsHandler(attachmentService *service.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		attachments, err := attachmentService.ListAttachments(c.Request.Context(), uint(ticketID), viewer)
+		if err != nil {
+			respondAttachmentError(c, err, "Failed to fetch attachments")
+			return
+		}
+
+		c.JSON(http.StatusOK, attachments)
+	}
+}
+
+func downloadAttachmentHandler(attachmentService *service.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		attachment, err := attachmentService.GetAttachment(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondAttachmentError(c, err, "Failed to fetch attachment")
+			return
+		}
+
+		c.Header("X-Content-Type-Options", "nosniff")
+		if attachment.IsImage() {
+			// Images are served inline so they can be embedded in rendered comments
+			c.Header("Content-Type", attachment.ContentType)
+			c.File(attachment.StoragePath)
+			return
+		}
+		c.FileAttachment(attachment.StoragePath, attachment.FileName)
+	}
+}
+
+// inlineImageHandler serves an image embedded in rendered Markdown. It is not
+// behind AuthMiddleware, as browsers load images without the Authorization
+// header; the signature in the link proves access instead.
+func inlineImageHandler(attachmentService *service.AttachmentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+			return
+		}
+
+		attachment, err := attachmentService.GetSignedImage(c.Request.Context(), uint(id), c.Query("signature"))
+		if err != nil {
+			respondAttachmentError(c, err, "Failed to fetch attachment")
+			return
+		}
+
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "private, max-age=86400")
+		c.Header("Content-Type", attachment.ContentType)
+		c.File(attachment.StoragePath)
+	}
+}
+
+// respondAttachmentError maps attachment service errors to HTTP responses
+func respondAttachmentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment or ticket not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this ticket"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func bulkUpdateTicketsHandler(bulkService *service.BulkService, ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req struct {
+			TicketIDs []ticketRef              `json:"ticket_ids"` // IDs or ticket keys
+			Filter    *repository.TicketFilter `json:"filter"`
+			Changes   domain.BulkTicketChanges `json:"changes"`
+			Atomic    bool                     `json:"atomic"`
+			Async     bool                     `json:"async"`
+		}
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ticketIDs, ok := resolveTicketRefs(c, ticketService, req.TicketIDs...)
+		if !ok {
+			return
+		}
+
+		result, job, err := bulkService.Submit(c.Request.Context(), service.BulkRequest{
+			TicketIDs: ticketIDs,
+			Filter:    req.Filter,
+			Changes:   req.Changes,
+			Atomic:    req.Atomic,
+			Async:     req.Async,
+		}, userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if job != nil {
+			c.JSON(http.StatusAccepted, gin.H{
+				"mode": "async",
+				"job":  job,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mode":        "sync",
+			"total":       result.Total,
+			"succeeded":   result.Succeeded,
+			"failed":      result.Failed,
+			"rolled_back": result.RolledBack,
+			"results":     result.Results,
+		})
+	}
+}
+
+func getBulkJobHandler(bulkService *service.BulkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobID, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 1000 {
+			limit = 100
+		}
+
+		job, err := bulkService.GetJob(c.Request.Context(), uint(jobID))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+
+		results, err := bulkService.ListJobResults(c.Request.Context(), job.ID, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job results"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"job":     job,
+			"results": results,
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type changeRequest struct {
+	Title              *string            `json:"title"`
+	Description        *string            `json:"description"`
+	Type               *domain.ChangeType `json:"type"`
+	Risk               *domain.ChangeRisk `json:"risk"`
+	PlannedStart       *time.Time         `json:"planned_start"`
+	PlannedEnd         *time.Time         `json:"planned_end"`
+	ImplementationPlan *string            `json:"implementation_plan"`
+	RollbackPlan       *string            `json:"rollback_plan"`
+	ImplementerID      *uint              `json:"implementer_id"`
+	ComputerIDs        *[]uint            `json:"computer_ids"`
+}
+
+// apply copies the fields present in the request onto the change
+func (r changeRequest) apply(change *domain.ChangeRequest) {
+	if r.Title != nil {
+		change.Title = *r.Title
+	}
+	if r.Description != nil {
+		change.Description = *r.Description
+	}
+	if r.Type != nil {
+		change.Type = *r.Type
+	}
+	if r.Risk != nil {
+		change.Risk = *r.Risk
+	}
+	if r.PlannedStart != nil {
+		change.PlannedStart = *r.PlannedStart
+	}
+	if r.PlannedEnd != nil {
+		change.PlannedEnd = *r.PlannedEnd
+	}
+	if r.ImplementationPlan != nil {
+		change.ImplementationPlan = *r.ImplementationPlan
+	}
+	if r.RollbackPlan != nil {
+		change.RollbackPlan = *r.RollbackPlan
+	}
+	if r.ImplementerID != nil {
+		change.ImplementerID = r.ImplementerID
+		change.Implementer = nil
+	}
+}
+
+func createChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req changeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		change := &domain.ChangeRequest{}
+		req.apply(change)
+
+		var computerIDs []uint
+		if req.ComputerIDs != nil {
+			computerIDs = *req.ComputerIDs
+		}
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		conflicts, err := changeService.CreateChange(c.Request.Context(), change, computerIDs, actorID)
+		if err != nil {
+			respondChangeError(c, err, "Failed to create change request")
+			return
+		}
+
+		setETag(c, change.Version)
+		c.JSON(http.StatusCreated, gin.H{"change": change, "conflicts": conflicts})
+	}
+}
+
+func listChangesHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		filter := repository.ChangeFilter{Status: domain.ChangeStatus(c.Query("status"))}
+		if filter.Status != "" && !filter.Status.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		if computerID := c.Query("computer_id"); computerID != "" {
+			id, err := strconv.ParseUint(computerID, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid computer ID"})
+				return
+			}
+			computer := uint(id)
+			filter.ComputerID = &computer
+		}
+		if from := c.Query("from"); from != "" {
+			t, err := time.Parse(time.RFC3339, from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+				return
+			}
+			filter.From = &t
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := time.Parse(time.RFC3339, to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+				return
+			}
+			filter.To = &t
+		}
+
+		changes, err := changeService.ListChanges(c.Request.Context(), filter, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch change requests"})
+			return
+		}
+
+		c.JSON(http.StatusOK, changes)
+	}
+}
+
+func getChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change request ID"})
+			return
+		}
+
+		change, conflicts, err := changeService.GetChange(c.Request.Context(), uint(id))
+		if err != nil {
+			respondChangeError(c, err, "Failed to fetch change request")
+			return
+		}
+
+		setETag(c, change.Version)
+		c.JSON(http.StatusOK, gin.H{"change": change, "conflicts": conflicts})
+	}
+}
+
+func updateChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req changeRequest
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		change, ok := loadChangeForWrite(c, changeService)
+		if !ok {
+			return
+		}
+		req.apply(change)
+
+		conflicts, err := changeService.UpdateChange(c.Request.Context(), change, req.ComputerIDs)
+		if err != nil {
+			respondChangeWriteError(c, changeService, change.ID, err, "Failed to update change request")
+			return
+		}
+
+		setETag(c, change.Version)
+		c.JSON(http.StatusOK, gin.H{"change": change, "conflicts": conflicts})
+	}
+}
+
+func deleteChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change request ID"})
+			return
+		}
+
+		if err := changeService.DeleteChange(c.Request.Context(), uint(id)); err != nil {
+			respondChangeError(c, err, "Failed to delete change request")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Change request deleted successfully"})
+	}
+}
+
+func submitChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ApproverIDs []uint `json:"approver_ids"`
+		}
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		change, ok := loadChangeForWrite(c, changeService)
+		if !ok {
+			return
+		}
+
+		if err := changeService.SubmitChange(c.Request.Context(), change, req.ApproverIDs); err != nil {
+			respondChangeWriteError(c, changeService, change.ID, err, "Failed to submit change request")
+			return
+		}
+
+		respondChange(c, changeService, change.ID)
+	}
+}
+
+// decideChangeHandler records the current user's CAB decision
+func decideChangeHandler(changeService *service.ChangeService, approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change request ID"})
+			return
+		}
+
+		var req struct {
+			Comment string `json:"comment"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if !approve && req.Comment == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A comment is required to reject a change"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		if _, err := changeService.DecideChange(c.Request.Context(), uint(id), approve, req.Comment, viewer); err != nil {
+			if errors.Is(err, service.ErrForbidden) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You have no pending approval step on this change"})
+				return
+			}
+			respondChangeError(c, err, "Failed to record decision")
+			return
+		}
+
+		respondChange(c, changeService, uint(id))
+	}
+}
+
+// transitionChangeHandler starts, completes, rolls back or cancels a change
+func transitionChangeHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Status domain.ChangeStatus `json:"status" binding:"required"`
+		}
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		change, ok := loadChangeForWrite(c, changeService)
+		if !ok {
+			return
+		}
+
+		if err := changeService.TransitionChange(c.Request.Context(), change, req.Status); err != nil {
+			respondChangeWriteError(c, changeService, change.ID, err, "Failed to update change request status")
+			return
+		}
+
+		respondChange(c, changeService, change.ID)
+	}
+}
+
+// checkChangeConflictsHandler lists the changes that would collide with a
+// planned window on the given computers, for use before creating a change
+func checkChangeConflictsHandler(changeService *service.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ComputerIDs  []uint    `json:"computer_ids" binding:"required,min=1"`
+			PlannedStart time.Time `json:"planned_start" binding:"required"`
+			PlannedEnd   time.Time `json:"planned_end" binding:"required"`
+			ExcludeID    uint      `json:"exclude_change_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		conflicts, err := changeService.CheckConflicts(c.Request.Context(), req.ComputerIDs, req.PlannedStart, req.PlannedEnd, req.ExcludeID)
+		if err != nil {
+			respondChangeError(c, err, "Failed to check conflicts")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"conflicts": conflicts})
+	}
+}
+
+// loadChangeForWrite loads the change named in the path and checks If-Match,
+// writing the error response and returning false if it cannot be changed
+func loadChangeForWrite(c *gin.Context, changeService *service.ChangeService) (*domain.ChangeRequest, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid change request ID"})
+		return nil, false
+	}
+
+	change, _, err := changeService.GetChange(c.Request.Context(), uint(id))
+	if err != nil {
+		respondChangeError(c, err, "Failed to fetch change request")
+		return nil, false
+	}
+
+	if !ifMatchSatisfied(c, change.Version) {
+		respondVersionConflict(c, "Change request was modified by another user", change, change.Version)
+		return nil, false
+	}
+	return change, true
+}
+
+// respondChange writes the current state of a change with its conflicts
+func respondChange(c *gin.Context, changeService *service.ChangeService, id uint) {
+	change, conflicts, err := changeService.GetChange(c.Request.Context(), id)
+	if err != nil {
+		respondChangeError(c, err, "Failed to fetch change request")
+		return
+	}
+
+	setETag(c, change.Version)
+	c.JSON(http.StatusOK, gin.H{"change": change, "conflicts": conflicts})
+}
+
+func respondChangeWriteError(c *gin.Context, changeService *service.ChangeService, id uint, err error, fallback string) {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, _, getErr := changeService.GetChange(c.Request.Context(), id); getErr == nil {
+			respondVersionConflict(c, "Change request was modified by another user", current, current.Version)
+			return
+		}
+	}
+	respondChangeError(c, err, fallback)
+}
+
+func respondChangeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Change request not found"})
+	case errors.Is(err, service.ErrInvalidChange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Change request was modified by another user, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -1,22 +1,31 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"helpdesk-backend/internal/auth"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
 	"helpdesk-backend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func createCommentHandler(commentService *service.CommentService) gin.HandlerFunc {
+func createCommentHandler(commentService *service.CommentService, ticketService *service.TicketService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		// The author is always the authenticated user; author_id in the body is ignored
 		var req struct {
-			Content  string `json:"content" binding:"required"`
-			TicketID uint   `json:"ticket_id" binding:"required"`
-			AuthorID uint   `json:"author_id" binding:"required"`
-			IsPublic bool   `json:"is_public"`
+			Content  string    `json:"content" binding:"required"`
+			TicketID ticketRef `json:"ticket_id" binding:"required"` // ID or ticket key
+			IsPublic *bool     `json:"is_public"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -24,19 +33,29 @@ func createCommentHandler(commentService *service.CommentService) gin.HandlerFun
 			return
 		}
 
+		if req.IsPublic != nil && !*req.IsPublic && !viewer.IsStaff() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only agents can add internal notes"})
+			return
+		}
+
+		ticketIDs, ok := resolveTicketRefs(c, ticketService, req.TicketID)
+		if !ok {
+			return
+		}
+
 		comment := &domain.Comment{
 			Content:  req.Content,
-			TicketID: req.TicketID,
-			AuthorID: req.AuthorID,
-			IsPublic: req.IsPublic,
+			TicketID: ticketIDs[0],
+			IsPublic: req.IsPublic == nil || *req.IsPublic,
 		}
 
-		err := commentService.CreateComment(c.Request.Context(), comment)
+		err := commentService.CreateComment(c.Request.Context(), comment, viewer)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+			respondCommentError(c, err, "Failed to create comment")
 			return
 		}
 
+		setETag(c, comment.Version)
 		c.JSON(http.StatusCreated, comment)
 	}
 }
@@ -49,9 +68,10 @@ func listCommentsHandler(commentService *service.CommentService) gin.HandlerFunc
 			return
 		}
 
-		comments, err := commentService.ListCommentsByTicket(c.Request.Context(), uint(ticketID))
+		viewer, _ := auth.GetViewer(c)
+		comments, err := commentService.ListCommentsForViewer(c.Request.Context(), uint(ticketID), viewer)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+			respondCommentError(c, err, "Failed to fetch comments")
 			return
 		}
 
@@ -67,36 +87,60 @@ func updateCommentHandler(commentService *service.CommentService) gin.HandlerFun
 			return
 		}
 
+		viewer, _ := auth.GetViewer(c)
+
 		var req struct {
 			Content  string `json:"content"`
 			IsPublic *bool  `json:"is_public"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindVersionedJSON(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		comment, err := commentService.GetCommentByID(c.Request.Context(), uint(id))
+		comment, err := commentService.GetCommentForViewer(c.Request.Context(), uint(id), viewer)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			respondCommentError(c, err, "Failed to fetch comment")
+			return
+		}
+
+		if !ifMatchSatisfied(c, comment.Version) {
+			respondVersionConflict(c, "Comment was modified by another user", comment, comment.Version)
 			return
 		}
 
 		// Update fields
-		if req.Content != "" {
+		changed := false
+		if req.Content != "" && req.Content != comment.Content {
 			comment.Content = req.Content
+			changed = true
 		}
-		if req.IsPublic != nil {
+		if req.IsPublic != nil && *req.IsPublic != comment.IsPublic {
 			comment.IsPublic = *req.IsPublic
+			changed = true
+		}
+
+		// Saving an unchanged comment would mark it as edited and add an empty revision
+		if !changed {
+			setETag(c, comment.Version)
+			c.JSON(http.StatusOK, comment)
+			return
 		}
 
-		err = commentService.UpdateComment(c.Request.Context(), comment)
+		err = commentService.UpdateComment(c.Request.Context(), comment, viewer)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := commentService.GetCommentForViewer(c.Request.Context(), comment.ID, viewer); getErr == nil {
+					respondVersionConflict(c, "Comment was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondCommentError(c, err, "Failed to update comment")
 			return
 		}
 
+		setETag(c, comment.Version)
 		c.JSON(http.StatusOK, comment)
 	}
 }
@@ -109,12 +153,44 @@ func deleteCommentHandler(commentService *service.CommentService) gin.HandlerFun
 			return
 		}
 
-		err = commentService.DeleteComment(c.Request.Context(), uint(id))
+		viewer, _ := auth.GetViewer(c)
+		err = commentService.DeleteComment(c.Request.Context(), uint(id), viewer)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+			respondCommentError(c, err, "Failed to delete comment")
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 	}
 }
+
+func listCommentRevisionsHandler(commentService *service.CommentService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		revisions, err := commentService.ListRevisions(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			respondCommentError(c, err, "Failed to fetch comment revisions")
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// respondCommentError maps comment service errors to HTTP responses
+func respondCommentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment or ticket not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to access this comment"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
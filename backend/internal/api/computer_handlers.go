@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
 	"helpdesk-backend/internal/service"
+	"net"
 	"net/http"
 	"strconv"
 
@@ -32,6 +35,7 @@ func (h *ComputerHandler) CreateComputer(c *gin.Context) {
 		return
 	}
 
+	setETag(c, computer.Version)
 	c.JSON(http.StatusCreated, computer)
 }
 
@@ -49,6 +53,7 @@ func (h *ComputerHandler) GetComputer(c *gin.Context) {
 		return
 	}
 
+	setETag(c, computer.Version)
 	c.JSON(http.StatusOK, computer)
 }
 
@@ -112,20 +117,122 @@ func (h *ComputerHandler) UpdateComputer(c *gin.Context) {
 	}
 
 	var updates domain.Computer
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	if err := bindVersionedJSON(c, &updates); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// An If-Match header takes precedence over a version in the body
+	if c.GetHeader("If-Match") != "" {
+		current, err := h.computerService.GetComputer(uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "computer not found"})
+			return
+		}
+		if !ifMatchSatisfied(c, current.Version) {
+			respondVersionConflict(c, "computer was modified by another user", current, current.Version)
+			return
+		}
+		updates.Version = current.Version
+	}
+
 	if err := h.computerService.UpdateComputer(uint(id), &updates); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, getErr := h.computerService.GetComputer(uint(id)); getErr == nil {
+				respondVersionConflict(c, "computer was modified by another user", current, current.Version)
+				return
+			}
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	computer, _ := h.computerService.GetComputer(uint(id))
+	setETag(c, computer.Version)
 	c.JSON(http.StatusOK, computer)
 }
 
+// PatchComputer handles PATCH /api/computers/:id with an RFC 7396 JSON Merge Patch
+func (h *ComputerHandler) PatchComputer(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid computer ID"})
+		return
+	}
+
+	patch, err := bindMergePatch(c)
+	if err != nil {
+		respondPatchBindError(c, err)
+		return
+	}
+
+	computer, err := h.computerService.GetComputer(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "computer not found"})
+		return
+	}
+
+	if !ifMatchSatisfied(c, computer.Version) {
+		respondVersionConflict(c, "computer was modified by another user", computer, computer.Version)
+		return
+	}
+
+	validIP := func(value string) error {
+		if net.ParseIP(value) == nil {
+			return errors.New("must be a valid IP address")
+		}
+		return nil
+	}
+	validMAC := func(value string) error {
+		if _, err := net.ParseMAC(value); err != nil {
+			return errors.New("must be a valid MAC address")
+		}
+		return nil
+	}
+
+	errs, forbidden := patch.apply(map[string]patchField{
+		"hostname":         stringPatch(&computer.Hostname, true, nil),
+		"os":               stringPatch(&computer.OS, true, nil),
+		"os_version":       stringPatch(&computer.OSVersion, false, nil),
+		"manufacturer":     stringPatch(&computer.Manufacturer, false, nil),
+		"model":            stringPatch(&computer.Model, false, nil),
+		"serial_number":    stringPatch(&computer.SerialNumber, true, nil), // unique, so it cannot be blanked
+		"status":           enumPatch(&computer.Status, domain.ComputerStatus.IsValid),
+		"cpu":              stringPatch(&computer.CPU, false, nil),
+		"ram":              stringPatch(&computer.RAM, false, nil),
+		"storage":          stringPatch(&computer.Storage, false, nil),
+		"ip_address":       stringPatch(&computer.IPAddress, false, validIP),
+		"mac_address":      stringPatch(&computer.MACAddress, false, validMAC),
+		"purchase_date":    timePatch(&computer.PurchaseDate),
+		"warranty_expiry":  timePatch(&computer.WarrantyExpiry),
+		"purchase_cost":    floatPatch(&computer.PurchaseCost),
+		"assignee_id":      idPatch(&computer.AssigneeID, nil),
+		"location":         stringPatch(&computer.Location, false, nil),
+		"notes":            stringPatch(&computer.Notes, false, nil),
+		"last_maintenance": timePatch(&computer.LastMaintenace),
+		"next_maintenance": timePatch(&computer.NextMaintenance),
+	})
+	if len(errs) > 0 {
+		respondPatchErrors(c, errs, forbidden)
+		return
+	}
+
+	if err := h.computerService.SaveComputer(computer); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if current, getErr := h.computerService.GetComputer(uint(id)); getErr == nil {
+				respondVersionConflict(c, "computer was modified by another user", current, current.Version)
+				return
+			}
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, _ := h.computerService.GetComputer(uint(id))
+	setETag(c, updated.Version)
+	c.JSON(http.StatusOK, updated)
+}
+
 // DeleteComputer handles DELETE /api/computers/:id
 func (h *ComputerHandler) DeleteComputer(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -35,6 +35,19 @@ func getDashboardStatsHandler(ticketService *service.TicketService) gin.HandlerF
 	}
 }
 
+// getBacklogWidgetsHandler returns the backlog aging and SLA widgets
+func getBacklogWidgetsHandler(reportService *service.ReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		widgets, err := reportService.Backlog(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backlog widgets"})
+			return
+		}
+
+		c.JSON(http.StatusOK, widgets)
+	}
+}
+
 func getRecentTicketsHandler(ticketService *service.TicketService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
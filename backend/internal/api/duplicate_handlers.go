@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// markDuplicateHandler marks a ticket as a duplicate of another and closes it
+func markDuplicateHandler(ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		var req struct {
+			DuplicateOf ticketRef `json:"duplicate_of" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		originalIDs, ok := resolveTicketRefs(c, ticketService, req.DuplicateOf)
+		if !ok {
+			return
+		}
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		ticket, err := ticketService.MarkDuplicate(c.Request.Context(), uint(id), originalIDs[0], actorID)
+		if err != nil {
+			respondDuplicateError(c, err, "Failed to mark ticket as duplicate")
+			return
+		}
+
+		setETag(c, ticket.Version)
+		c.JSON(http.StatusOK, ticket)
+	}
+}
+
+// clearDuplicateHandler dismisses a possible duplicate flag or unmarks a duplicate
+func clearDuplicateHandler(ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		ticket, err := ticketService.ClearDuplicate(c.Request.Context(), uint(id), actorID)
+		if err != nil {
+			respondDuplicateError(c, err, "Failed to clear duplicate")
+			return
+		}
+
+		setETag(c, ticket.Version)
+		c.JSON(http.StatusOK, ticket)
+	}
+}
+
+func listDuplicatesHandler(ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		tickets, err := ticketService.ListDuplicates(c.Request.Context(), uint(id))
+		if err != nil {
+			respondDuplicateError(c, err, "Failed to fetch duplicates")
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+func respondDuplicateError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+	case errors.Is(err, service.ErrInvalidDuplicate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket was modified by another user, please retry"})
+	default:
+		respondTicketSaveError(c, err, fallback)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// fieldConflict describes a field the client tried to change that no longer
+// holds the value the client saw
+type fieldConflict struct {
+	Field   string      `json:"field"`
+	Yours   interface{} `json:"yours"`
+	Current interface{} `json:"current"`
+}
+
+// setETag exposes a resource version as a strong entity tag
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatchSatisfied reports whether the If-Match request header, if any,
+// matches the current version. A missing header or "*" always matches.
+func ifMatchSatisfied(c *gin.Context, version int) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if unquoted, err := strconv.Unquote(tag); err == nil {
+			tag = unquoted
+		}
+		if tag == strconv.Itoa(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindVersionedJSON binds the request body while keeping a copy of it so that
+// a later version conflict can report which submitted fields collide.
+func bindVersionedJSON(c *gin.Context, obj interface{}) error {
+	return c.ShouldBindBodyWith(obj, binding.JSON)
+}
+
+// respondVersionConflict writes a 412 response with the current state of the
+// resource and the submitted fields whose current value differs from the request.
+func respondVersionConflict(c *gin.Context, message string, current interface{}, version int) {
+	setETag(c, version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":           message,
+		"current_version": version,
+		"current":         current,
+		"conflicts":       conflictingFields(c, current),
+	})
+}
+
+// conflictingFields compares the cached request body against the current
+// resource and returns the fields that differ.
+func conflictingFields(c *gin.Context, current interface{}) []fieldConflict {
+	conflicts := []fieldConflict{}
+
+	raw, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return conflicts
+	}
+	body, ok := raw.([]byte)
+	if !ok {
+		return conflicts
+	}
+
+	var submitted map[string]interface{}
+	if err := json.Unmarshal(body, &submitted); err != nil {
+		return conflicts
+	}
+
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return conflicts
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(encoded, &stored); err != nil {
+		return conflicts
+	}
+
+	for field, yours := range submitted {
+		// Fields left empty are not applied by PUT handlers, so they cannot conflict
+		if field == "version" || yours == nil || yours == "" {
+			continue
+		}
+		theirs, known := stored[field]
+		if !known || reflect.DeepEqual(yours, theirs) {
+			continue
+		}
+		conflicts = append(conflicts, fieldConflict{Field: field, Yours: yours, Current: theirs})
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Field < conflicts[j].Field })
+	return conflicts
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// eventsKeepAlive is how often a comment line is sent to keep idle connections open
+const eventsKeepAlive = 25 * time.Second
+
+// streamEventsHandler streams ticket events the user may see as Server-Sent
+// Events. Clients resume after a reconnect by sending Last-Event-ID; a
+// "reset" event tells them that events were missed and they should reload.
+func streamEventsHandler(broker *events.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		var lastID uint64
+		if lastEventID != "" {
+			parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+				return
+			}
+			lastID = parsed
+		}
+
+		sub, replay, complete := broker.Subscribe(lastID)
+		defer sub.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		// Ask browsers to reconnect after three seconds
+		c.Writer.WriteString("retry: 3000\n\n")
+		if !complete {
+			c.Render(-1, sse.Event{Event: "reset", Data: gin.H{"reason": "events were missed, reload current state"}})
+		}
+		for _, event := range replay {
+			writeEvent(c, event, viewer)
+		}
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Too far behind; the client reconnects and resumes from its last event
+					return
+				}
+				writeEvent(c, event, viewer)
+			case <-keepAlive.C:
+				if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent sends an event if the viewer is allowed to see it
+func writeEvent(c *gin.Context, event events.Event, viewer domain.Viewer) {
+	if !event.VisibleTo(viewer) {
+		return
+	}
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: string(event.Type),
+		Data:  event,
+	})
+}
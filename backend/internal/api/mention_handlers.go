@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func listMyMentionsHandler(mentionService *service.MentionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		mentions, err := mentionService.ListMentionsOfUser(c.Request.Context(), viewer, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mentions"})
+			return
+		}
+
+		c.JSON(http.StatusOK, mentions)
+	}
+}
+
+func listTicketWatchersHandler(mentionService *service.MentionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		watchers, err := mentionService.ListWatchers(c.Request.Context(), uint(ticketID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, watchers)
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+var (
+	errPatchContentType = errors.New("content type must be " + mergePatchContentType)
+	errPatchNull        = errors.New("cannot be null")
+	errPatchForbidden   = errors.New("you are not allowed to change this field")
+)
+
+// mergePatch is a decoded RFC 7396 JSON Merge Patch document. Members that are
+// absent are left untouched, members set to null are cleared and any other
+// value replaces the current one.
+type mergePatch map[string]json.RawMessage
+
+// patchField knows how to apply one member of a merge patch to a resource
+type patchField struct {
+	set     func(raw json.RawMessage) error
+	clear   func() error // nil when the field cannot be cleared
+	allowed bool
+}
+
+// bindMergePatch reads the request body as a merge patch. Only JSON objects
+// are accepted since every patchable resource is an object.
+func bindMergePatch(c *gin.Context) (mergePatch, error) {
+	if ct := c.ContentType(); ct != mergePatchContentType && ct != gin.MIMEJSON {
+		return nil, errPatchContentType
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+	// Keep the body around so version conflicts can report colliding fields
+	c.Set(gin.BodyBytesKey, body)
+
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+
+	var patch mergePatch
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// apply runs every member of the patch through its field handler. It returns
+// per-field errors and whether any of them was a permission failure.
+func (p mergePatch) apply(fields map[string]patchField) (map[string]string, bool) {
+	errs := make(map[string]string)
+	forbidden := false
+
+	for name, raw := range p {
+		field, ok := fields[name]
+		if !ok {
+			errs[name] = "unknown or read-only field"
+			continue
+		}
+		if !field.allowed {
+			errs[name] = errPatchForbidden.Error()
+			forbidden = true
+			continue
+		}
+
+		var err error
+		if isJSONNull(raw) {
+			if field.clear == nil {
+				err = errPatchNull
+			} else {
+				err = field.clear()
+			}
+		} else {
+			err = field.set(raw)
+		}
+		if err != nil {
+			errs[name] = err.Error()
+		}
+	}
+	return errs, forbidden
+}
+
+// respondPatchBindError writes a 415 for a wrong content type and a 400 otherwise
+func respondPatchBindError(c *gin.Context, err error) {
+	if errors.Is(err, errPatchContentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// respondPatchErrors writes a 403 when any field was forbidden and a 422 otherwise
+func respondPatchErrors(c *gin.Context, errs map[string]string, forbidden bool) {
+	status := http.StatusUnprocessableEntity
+	if forbidden {
+		status = http.StatusForbidden
+	}
+
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(status, gin.H{
+		"error":  "Invalid patch for fields: " + strings.Join(names, ", "),
+		"fields": errs,
+	})
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// Field constructors
+
+// stringPatch patches a string. Required strings cannot be null or blank;
+// optional strings are cleared to "".
+func stringPatch(target *string, required bool, validate func(string) error) patchField {
+	field := patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return errors.New("must be a string")
+			}
+			if required && strings.TrimSpace(value) == "" {
+				return errors.New("cannot be empty")
+			}
+			if validate != nil && value != "" {
+				if err := validate(value); err != nil {
+					return err
+				}
+			}
+			*target = value
+			return nil
+		},
+	}
+	if !required {
+		field.clear = func() error {
+			*target = ""
+			return nil
+		}
+	}
+	return field
+}
+
+// enumPatch patches a string enumeration, which cannot be null
+func enumPatch[T ~string](target *T, isValid func(T) bool) patchField {
+	return patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			var value T
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return errors.New("must be a string")
+			}
+			if !isValid(value) {
+				return fmt.Errorf("invalid value %q", value)
+			}
+			*target = value
+			return nil
+		},
+	}
+}
+
+// boolPatch patches a boolean, which cannot be null
+func boolPatch(target *bool) patchField {
+	return patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			if err := json.Unmarshal(raw, target); err != nil {
+				return errors.New("must be a boolean")
+			}
+			return nil
+		},
+	}
+}
+
+// floatPatch patches a non-negative number, cleared to zero
+func floatPatch(target *float64) patchField {
+	return patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			var value float64
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return errors.New("must be a number")
+			}
+			if value < 0 {
+				return errors.New("cannot be negative")
+			}
+			*target = value
+			return nil
+		},
+		clear: func() error {
+			*target = 0
+			return nil
+		},
+	}
+}
+
+// idPatch patches an optional reference, cleared to nil
+func idPatch(target **uint, validate func(uint) error) patchField {
+	return patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			var value uint
+			if err := json.Unmarshal(raw, &value); err != nil || value == 0 {
+				return errors.New("must be a positive integer")
+			}
+			if validate != nil {
+				if err := validate(value); err != nil {
+					return err
+				}
+			}
+			*target = &value
+			return nil
+		},
+		clear: func() error {
+			*target = nil
+			return nil
+		},
+	}
+}
+
+// timePatch patches an optional timestamp given as RFC 3339 or YYYY-MM-DD, cleared to nil
+func timePatch(target **time.Time) patchField {
+	return patchField{
+		allowed: true,
+		set: func(raw json.RawMessage) error {
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return errors.New("must be a date string")
+			}
+			for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+				if t, err := time.Parse(layout, value); err == nil {
+					*target = &t
+					return nil
+				}
+			}
+			return errors.New("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		},
+		clear: func() error {
+			*target = nil
+			return nil
+		},
+	}
+}
+
+// restrict marks a field as forbidden unless allowed is true
+func restrict(field patchField, allowed bool) patchField {
+	field.allowed = field.allowed && allowed
+	return field
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func listNotificationsHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		unreadOnly, _ := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+
+		notifications, err := notificationService.ListNotifications(c.Request.Context(), viewer, unreadOnly, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+
+		unread, err := notificationService.CountUnread(c.Request.Context(), viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"notifications": notifications,
+			"unread_count":  unread,
+			"limit":         limit,
+			"offset":        offset,
+		})
+	}
+}
+
+func unreadNotificationCountHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		unread, err := notificationService.CountUnread(c.Request.Context(), viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread notifications"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+	}
+}
+
+func markNotificationReadHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+			return
+		}
+
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		if err := notificationService.MarkRead(c.Request.Context(), uint(id), viewer); err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+	}
+}
+
+func markAllNotificationsReadHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		updated, err := notificationService.MarkAllRead(c.Request.Context(), viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": updated})
+	}
+}
+
+func getNotificationPreferencesHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		prefs, err := notificationService.GetPreferences(c.Request.Context(), viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+			return
+		}
+
+		c.JSON(http.StatusOK, prefs)
+	}
+}
+
+func updateNotificationPreferencesHandler(notificationService *service.NotificationService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		viewer, exists := auth.GetViewer(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req []struct {
+			EventType string `json:"event_type" binding:"required"`
+			InApp     *bool  `json:"in_app" binding:"required"`
+			Email     *bool  `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		prefs := make([]domain.NotificationPreference, 0, len(req))
+		for _, item := range req {
+			if item.EventType == "" || item.InApp == nil || item.Email == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each preference needs event_type, in_app and email"})
+				return
+			}
+			prefs = append(prefs, domain.NotificationPreference{
+				EventType: item.EventType,
+				InApp:     *item.InApp,
+				Email:     *item.Email,
+			})
+		}
+
+		updated, err := notificationService.UpdatePreferences(c.Request.Context(), prefs, viewer)
+		if err != nil {
+			if errors.Is(err, service.ErrUnknownEventType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getPriorityMatrixHandler(priorityService *service.PriorityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entries, err := priorityService.GetMatrix(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch priority matrix"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+func updatePriorityMatrixHandler(priorityService *service.PriorityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req []domain.PriorityMatrixEntry
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entries, err := priorityService.UpdateMatrix(c.Request.Context(), req)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidPriorityMatrix) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update priority matrix"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+// getPriorityOverrideReportHandler reports priority overrides between from
+// and to, both inclusive
+func getPriorityOverrideReportHandler(priorityService *service.PriorityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter repository.PriorityOverrideFilter
+		if from := c.Query("from"); from != "" {
+			t, err := time.Parse(workDateLayout, from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be formatted as YYYY-MM-DD"})
+				return
+			}
+			filter.From = &t
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := time.Parse(workDateLayout, to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be formatted as YYYY-MM-DD"})
+				return
+			}
+			end := t.AddDate(0, 0, 1)
+			filter.To = &end
+		}
+
+		report, err := priorityService.OverrideReport(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build priority override report"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// respondTicketSaveError maps priority errors to 400 and anything else to 500
+func respondTicketSaveError(c *gin.Context, err error, message string) {
+	if errors.Is(err, service.ErrPriorityOverrideReason) || errors.Is(err, service.ErrInvalidTicketPriority) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type problemRequest struct {
+	Title       *string                `json:"title"`
+	Description *string                `json:"description"`
+	Status      *domain.ProblemStatus  `json:"status"`
+	Priority    *domain.TicketPriority `json:"priority"`
+	Symptoms    *string                `json:"symptoms"`
+	RootCause   *string                `json:"root_cause"`
+	Workaround  *string                `json:"workaround"`
+	OwnerID     *uint                  `json:"owner_id"`
+}
+
+// apply copies the fields present in the request onto the problem
+func (r problemRequest) apply(problem *domain.Problem) {
+	if r.Title != nil {
+		problem.Title = *r.Title
+	}
+	if r.Description != nil {
+		problem.Description = *r.Description
+	}
+	if r.Status != nil {
+		problem.Status = *r.Status
+	}
+	if r.Priority != nil {
+		problem.Priority = *r.Priority
+	}
+	if r.Symptoms != nil {
+		problem.Symptoms = *r.Symptoms
+	}
+	if r.RootCause != nil {
+		problem.RootCause = *r.RootCause
+	}
+	if r.Workaround != nil {
+		problem.Workaround = *r.Workaround
+	}
+	if r.OwnerID != nil {
+		problem.OwnerID = r.OwnerID
+		problem.Owner = nil
+	}
+}
+
+func createProblemHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req problemRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		problem := &domain.Problem{}
+		req.apply(problem)
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		if err := problemService.CreateProblem(c.Request.Context(), problem, actorID); err != nil {
+			respondProblemError(c, err, "Failed to create problem")
+			return
+		}
+
+		setETag(c, problem.Version)
+		c.JSON(http.StatusCreated, problem)
+	}
+}
+
+func listProblemsHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		status := domain.ProblemStatus(c.Query("status"))
+		if status != "" && !status.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+
+		problems, err := problemService.ListProblems(c.Request.Context(), status, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch problems"})
+			return
+		}
+
+		c.JSON(http.StatusOK, problems)
+	}
+}
+
+func getProblemHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		problem, err := problemService.GetProblem(c.Request.Context(), uint(id))
+		if err != nil {
+			respondProblemError(c, err, "Failed to fetch problem")
+			return
+		}
+
+		setETag(c, problem.Version)
+		c.JSON(http.StatusOK, problem)
+	}
+}
+
+func updateProblemHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		var req problemRequest
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		problem, err := problemService.GetProblem(c.Request.Context(), uint(id))
+		if err != nil {
+			respondProblemError(c, err, "Failed to fetch problem")
+			return
+		}
+
+		if !ifMatchSatisfied(c, problem.Version) {
+			respondVersionConflict(c, "Problem was modified by another user", problem, problem.Version)
+			return
+		}
+
+		previousStatus := problem.Status
+		req.apply(problem)
+
+		if err := problemService.UpdateProblem(c.Request.Context(), problem, previousStatus); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := problemService.GetProblem(c.Request.Context(), problem.ID); getErr == nil {
+					respondVersionConflict(c, "Problem was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondProblemError(c, err, "Failed to update problem")
+			return
+		}
+
+		setETag(c, problem.Version)
+		c.JSON(http.StatusOK, problem)
+	}
+}
+
+func deleteProblemHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		if err := problemService.DeleteProblem(c.Request.Context(), uint(id)); err != nil {
+			respondProblemError(c, err, "Failed to delete problem")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Problem deleted successfully"})
+	}
+}
+
+func listProblemTicketsHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		tickets, err := problemService.ListTickets(c.Request.Context(), uint(id))
+		if err != nil {
+			respondProblemError(c, err, "Failed to fetch problem tickets")
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+func linkProblemTicketsHandler(problemService *service.ProblemService, ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		var req struct {
+			TicketIDs []ticketRef `json:"ticket_ids" binding:"required,min=1"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ticketIDs, ok := resolveTicketRefs(c, ticketService, req.TicketIDs...)
+		if !ok {
+			return
+		}
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		if err := problemService.LinkTickets(c.Request.Context(), uint(id), ticketIDs, actorID); err != nil {
+			respondProblemError(c, err, "Failed to link tickets")
+			return
+		}
+
+		tickets, err := problemService.ListTickets(c.Request.Context(), uint(id))
+		if err != nil {
+			respondProblemError(c, err, "Failed to fetch problem tickets")
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+func unlinkProblemTicketHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+		ticketID, err := strconv.ParseUint(c.Param("ticketId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		actorID, _ := auth.GetCurrentUserID(c)
+		if err := problemService.UnlinkTicket(c.Request.Context(), uint(id), uint(ticketID), actorID); err != nil {
+			respondProblemError(c, err, "Failed to unlink ticket")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Ticket unlinked successfully"})
+	}
+}
+
+// resolveProblemHandler resolves a problem and, through it, its open incidents
+func resolveProblemHandler(problemService *service.ProblemService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid problem ID"})
+			return
+		}
+
+		var req struct {
+			Resolution string `json:"resolution"`
+		}
+		if err := bindVersionedJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		problem, err := problemService.GetProblem(c.Request.Context(), uint(id))
+		if err != nil {
+			respondProblemError(c, err, "Failed to fetch problem")
+			return
+		}
+
+		if !ifMatchSatisfied(c, problem.Version) {
+			respondVersionConflict(c, "Problem was modified by another user", problem, problem.Version)
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		result, err := problemService.ResolveProblem(c.Request.Context(), problem, req.Resolution, viewer)
+		if err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := problemService.GetProblem(c.Request.Context(), problem.ID); getErr == nil {
+					respondVersionConflict(c, "Problem was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondProblemError(c, err, "Failed to resolve problem")
+			return
+		}
+
+		setETag(c, problem.Version)
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func respondProblemError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Problem or ticket not found"})
+	case errors.Is(err, service.ErrInvalidProblem):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
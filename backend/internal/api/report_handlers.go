@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReportDays is the range reports cover when from is not given
+const defaultReportDays = 30
+
+// getTicketSeriesReportHandler returns ticket volume, backlog and resolution
+// time series bucketed in the requested time zone
+func getTicketSeriesReportHandler(reportService *service.ReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loc, ok := bindReportLocation(c)
+		if !ok {
+			return
+		}
+		from, to, ok := bindReportRange(c, loc)
+		if !ok {
+			return
+		}
+		filter, ok := bindTicketReportFilter(c)
+		if !ok {
+			return
+		}
+
+		report, err := reportService.TicketSeries(c.Request.Context(), service.TicketSeriesQuery{
+			From:     from,
+			To:       to,
+			Interval: service.SeriesInterval(c.DefaultQuery("interval", string(service.DayInterval))),
+			Location: loc,
+			Filter:   filter,
+		})
+		if err != nil {
+			respondReportError(c, err, "Failed to build ticket report")
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// getAgentPerformanceReportHandler reports per agent on tickets created or
+// resolved in the date range
+func getAgentPerformanceReportHandler(reportService *service.ReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		loc, ok := bindReportLocation(c)
+		if !ok {
+			return
+		}
+		from, to, ok := bindReportRange(c, loc)
+		if !ok {
+			return
+		}
+
+		report, err := reportService.AgentPerformance(c.Request.Context(), from, to)
+		if err != nil {
+			respondReportError(c, err, "Failed to build agent report")
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// bindReportLocation reads the IANA time zone in the tz parameter, UTC by default
+func bindReportLocation(c *gin.Context) (*time.Location, bool) {
+	tz := c.DefaultQuery("tz", "UTC")
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone such as Europe/Berlin"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// bindReportRange reads the from and to dates, both inclusive, in loc. The
+// range defaults to the last 30 days up to today.
+func bindReportRange(c *gin.Context, loc *time.Location) (time.Time, time.Time, bool) {
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if param := c.Query("to"); param != "" {
+		t, err := time.ParseInLocation(workDateLayout, param, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be formatted as YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, 1-defaultReportDays)
+	if param := c.Query("from"); param != "" {
+		t, err := time.ParseInLocation(workDateLayout, param, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be formatted as YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	return from, to, true
+}
+
+// bindTicketReportFilter reads the category, priority, assignee_id and
+// department filters
+func bindTicketReportFilter(c *gin.Context) (repository.TicketReportFilter, bool) {
+	filter := repository.TicketReportFilter{
+		Category:   c.Query("category"),
+		Priority:   domain.TicketPriority(c.Query("priority")),
+		Department: c.Query("department"),
+	}
+	if filter.Priority != "" && !filter.Priority.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority"})
+		return filter, false
+	}
+	if assigneeID := c.Query("assignee_id"); assigneeID != "" {
+		id, err := strconv.ParseUint(assigneeID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assignee ID"})
+			return filter, false
+		}
+		assignee := uint(id)
+		filter.AssigneeID = &assignee
+	}
+	return filter, true
+}
+
+func respondReportError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, service.ErrInvalidReport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
@@ -2,12 +2,57 @@ package api
 
 import (
 	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/events"
 	"helpdesk-backend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, userService *service.UserService, ticketService *service.TicketService, commentService *service.CommentService, computerService *service.ComputerService, jwtService *auth.JWTService) {
+// Services bundles the business services the HTTP layer depends on
+type Services struct {
+	User            *service.UserService
+	Ticket          *service.TicketService
+	Comment         *service.CommentService
+	Computer        *service.ComputerService
+	WorkLog         *service.WorkLogService
+	Bulk            *service.BulkService
+	Mention         *service.MentionService
+	Attachment      *service.AttachmentService
+	Notification    *service.NotificationService
+	Webhook         *service.WebhookService
+	Alert           *service.AlertService
+	Priority        *service.PriorityService
+	Problem         *service.ProblemService
+	Change          *service.ChangeService
+	Article         *service.ArticleService
+	Suggestion      *service.SuggestionService
+	Report          *service.ReportService
+	ScheduledReport *service.ScheduledReportService
+	Events          *events.Broker
+
+	// AlertAPIKey authenticates the monitoring alert intake; empty disables it
+	AlertAPIKey string
+}
+
+func SetupRoutes(router *gin.Engine, services *Services, jwtService *auth.JWTService) {
+	userService := services.User
+	ticketService := services.Ticket
+	commentService := services.Comment
+	computerService := services.Computer
+	workLogService := services.WorkLog
+	bulkService := services.Bulk
+	mentionService := services.Mention
+	attachmentService := services.Attachment
+	notificationService := services.Notification
+	webhookService := services.Webhook
+	priorityService := services.Priority
+	problemService := services.Problem
+	changeService := services.Change
+	articleService := services.Article
+	suggestionService := services.Suggestion
+	reportService := services.Report
+	scheduledReportService := services.ScheduledReport
+
 	api := router.Group("/api/v1")
 
 	// Health check
@@ -27,6 +72,20 @@ func SetupRoutes(router *gin.Engine, userService *service.UserService, ticketSer
 		authGroup.POST("/logout", authHandlers.Logout)
 	}
 
+	// Server-Sent Events stream. EventSource cannot send headers, so the
+	// token may also be given as ?access_token=
+	api.GET("/events", auth.TokenFromQuery(), auth.AuthMiddleware(jwtService), streamEventsHandler(services.Events))
+
+	// Images embedded in rendered Markdown, authenticated by the signature in their URL
+	api.GET("/attachments/:id/image", inlineImageHandler(attachmentService))
+
+	// Monitoring alert intake, authenticated by API key instead of JWT
+	alerts := api.Group("/alerts", auth.APIKeyMiddleware(services.AlertAPIKey))
+	{
+		alerts.POST("/alertmanager", alertmanagerWebhookHandler(services.Alert))
+		alerts.POST("/generic", genericAlertHandler(services.Alert))
+	}
+
 	// Protected routes (require authentication)
 	protected := api.Group("")
 	protected.Use(auth.AuthMiddleware(jwtService))
@@ -40,36 +99,176 @@ func SetupRoutes(router *gin.Engine, userService *service.UserService, ticketSer
 			users.GET("", auth.RequireAdminOrAgent(), listUsersHandler(userService))
 			users.GET("/:id", getUserHandler(userService))                            // Users can see their own profile
 			users.PUT("/:id", updateUserHandler(userService))                         // Users can update their own profile
+			users.PATCH("/:id", patchUserHandler(userService))                        // JSON Merge Patch; role and status are admin only
 			users.DELETE("/:id", auth.RequireAdmin(), deleteUserHandler(userService)) // Only admin can delete
 		}
 
 		// Ticket routes
-		tickets := protected.Group("/tickets")
+		tickets := protected.Group("/tickets", resolveTicketKey(ticketService, "id"))
 		{
 			tickets.POST("", createTicketHandler(ticketService))
 			tickets.GET("", listTicketsHandler(ticketService))
 			tickets.GET("/recent", getRecentTicketsHandler(ticketService))
+			tickets.POST("/suggest", suggestTicketAnswersHandler(suggestionService))
+			tickets.POST("/deflections", recordDeflectionHandler(suggestionService, ticketService))
+			tickets.POST("/bulk", auth.RequireAdminOrAgent(), bulkUpdateTicketsHandler(bulkService, ticketService))
+			tickets.GET("/bulk/jobs/:jobId", auth.RequireAdminOrAgent(), getBulkJobHandler(bulkService))
 			tickets.GET("/:id", getTicketHandler(ticketService))
 			tickets.PUT("/:id", updateTicketHandler(ticketService))
+			tickets.PATCH("/:id", patchTicketHandler(ticketService, userService))
 			tickets.DELETE("/:id", auth.RequireAdminOrAgent(), deleteTicketHandler(ticketService))
 			tickets.POST("/:id/assign", auth.RequireAdminOrAgent(), assignTicketHandler(ticketService))
+			tickets.GET("/:id/duplicates", auth.RequireAdminOrAgent(), listDuplicatesHandler(ticketService))
+			tickets.POST("/:id/duplicate", auth.RequireAdminOrAgent(), markDuplicateHandler(ticketService))
+			tickets.DELETE("/:id/duplicate", auth.RequireAdminOrAgent(), clearDuplicateHandler(ticketService))
+			tickets.GET("/:id/watchers", auth.RequireAdminOrAgent(), listTicketWatchersHandler(mentionService))
+			tickets.GET("/:id/attachments", listAttachmentsHandler(attachmentService))
+			tickets.POST("/:id/attachments", uploadAttachmentHandler(attachmentService))
+			tickets.GET("/:id/articles", listTicketArticlesHandler(articleService))
+			tickets.POST("/:id/articles", auth.RequireAdminOrAgent(), linkTicketArticleHandler(articleService))
+			tickets.DELETE("/:id/articles/:articleId", auth.RequireAdminOrAgent(), unlinkTicketArticleHandler(articleService))
+
+			// Time tracking (agents and admins only)
+			tickets.GET("/:id/worklogs", auth.RequireAdminOrAgent(), listWorkLogsHandler(workLogService))
+			tickets.POST("/:id/worklogs", auth.RequireAdminOrAgent(), createWorkLogHandler(workLogService))
+			tickets.POST("/:id/timer/start", auth.RequireAdminOrAgent(), startTimerHandler(workLogService))
+			tickets.POST("/:id/timer/stop", auth.RequireAdminOrAgent(), stopTimerHandler(workLogService))
+		}
+
+		// Work log routes
+		worklogs := protected.Group("/worklogs")
+		worklogs.Use(auth.RequireAdminOrAgent())
+		{
+			worklogs.GET("/timers", listActiveTimersHandler(workLogService))
+			worklogs.PUT("/:id", updateWorkLogHandler(workLogService))
+			worklogs.DELETE("/:id", deleteWorkLogHandler(workLogService))
+		}
+
+		// Report routes
+		reports := protected.Group("/reports")
+		reports.Use(auth.RequireAdminOrAgent())
+		{
+			reports.GET("/time", getTimeReportHandler(workLogService))
+			reports.GET("/tickets", getTicketSeriesReportHandler(reportService))
+			reports.GET("/agents", auth.RequireAdmin(), getAgentPerformanceReportHandler(reportService))
+			reports.GET("/priority-overrides", getPriorityOverrideReportHandler(priorityService))
+			reports.GET("/deflections", getDeflectionReportHandler(suggestionService))
+		}
+
+		// Problem routes (agents and admins only)
+		problems := protected.Group("/problems", auth.RequireAdminOrAgent())
+		{
+			problems.GET("", listProblemsHandler(problemService))
+			problems.POST("", createProblemHandler(problemService))
+			problems.GET("/:id", getProblemHandler(problemService))
+			problems.PUT("/:id", updateProblemHandler(problemService))
+			problems.DELETE("/:id", auth.RequireAdmin(), deleteProblemHandler(problemService))
+			problems.POST("/:id/resolve", resolveProblemHandler(problemService))
+			problems.GET("/:id/tickets", listProblemTicketsHandler(problemService))
+			problems.POST("/:id/tickets", linkProblemTicketsHandler(problemService, ticketService))
+			problems.DELETE("/:id/tickets/:ticketId", resolveTicketKey(ticketService, "ticketId"), unlinkProblemTicketHandler(problemService))
+		}
+
+		// Change management routes (agents and admins only)
+		changes := protected.Group("/changes", auth.RequireAdminOrAgent())
+		{
+			changes.GET("", listChangesHandler(changeService))
+			changes.POST("", createChangeHandler(changeService))
+			changes.POST("/conflicts", checkChangeConflictsHandler(changeService))
+			changes.GET("/:id", getChangeHandler(changeService))
+			changes.PUT("/:id", updateChangeHandler(changeService))
+			changes.DELETE("/:id", auth.RequireAdmin(), deleteChangeHandler(changeService))
+			changes.POST("/:id/submit", submitChangeHandler(changeService))
+			changes.POST("/:id/approve", decideChangeHandler(changeService, true))
+			changes.POST("/:id/reject", decideChangeHandler(changeService, false))
+			changes.POST("/:id/status", transitionChangeHandler(changeService))
+		}
+
+		// Knowledge base. End users only see published public articles.
+		kb := protected.Group("/kb")
+		{
+			kb.GET("/categories", listArticleCategoriesHandler(articleService))
+			kb.GET("/articles", listArticlesHandler(articleService))
+			kb.POST("/articles", auth.RequireAdminOrAgent(), createArticleHandler(articleService))
+			kb.GET("/articles/:id", getArticleHandler(articleService))
+			kb.PUT("/articles/:id", auth.RequireAdminOrAgent(), updateArticleHandler(articleService))
+			kb.DELETE("/articles/:id", auth.RequireAdmin(), deleteArticleHandler(articleService))
+			kb.GET("/articles/:id/revisions", auth.RequireAdminOrAgent(), listArticleRevisionsHandler(articleService))
+			kb.GET("/articles/:id/tickets", auth.RequireAdminOrAgent(), listArticleTicketsHandler(articleService))
+		}
+
+		// Impact and urgency priority matrix (admin only to change)
+		priorityMatrix := protected.Group("/priority-matrix")
+		{
+			priorityMatrix.GET("", getPriorityMatrixHandler(priorityService))
+			priorityMatrix.PUT("", auth.RequireAdmin(), updatePriorityMatrixHandler(priorityService))
 		}
 
 		// Dashboard routes
 		dashboard := protected.Group("/dashboard")
 		{
 			dashboard.GET("/stats", getDashboardStatsHandler(ticketService))
+			dashboard.GET("/backlog", auth.RequireAdminOrAgent(), getBacklogWidgetsHandler(reportService))
 		}
 
 		// Comment routes
 		comments := protected.Group("/comments")
 		{
-			comments.POST("", createCommentHandler(commentService))
-			comments.GET("/ticket/:ticketId", listCommentsHandler(commentService))
+			comments.POST("", createCommentHandler(commentService, ticketService))
+			comments.GET("/ticket/:ticketId", resolveTicketKey(ticketService, "ticketId"), listCommentsHandler(commentService))
 			comments.PUT("/:id", updateCommentHandler(commentService))
+			comments.GET("/:id/revisions", listCommentRevisionsHandler(commentService))
 			comments.DELETE("/:id", deleteCommentHandler(commentService))
 		}
 
+		// Attachment routes
+		attachments := protected.Group("/attachments")
+		{
+			attachments.GET("/:id", downloadAttachmentHandler(attachmentService))
+		}
+
+		// Mention routes
+		mentions := protected.Group("/mentions")
+		{
+			mentions.GET("/me", listMyMentionsHandler(mentionService))
+		}
+
+		// Notification routes
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", listNotificationsHandler(notificationService))
+			notifications.GET("/unread-count", unreadNotificationCountHandler(notificationService))
+			notifications.POST("/read-all", markAllNotificationsReadHandler(notificationService))
+			notifications.GET("/preferences", getNotificationPreferencesHandler(notificationService))
+			notifications.PUT("/preferences", updateNotificationPreferencesHandler(notificationService))
+			notifications.POST("/:id/read", markNotificationReadHandler(notificationService))
+		}
+
+		// Webhook routes (admin only)
+		webhooks := protected.Group("/webhooks", auth.RequireAdmin())
+		{
+			webhooks.GET("", listWebhooksHandler(webhookService))
+			webhooks.POST("", createWebhookHandler(webhookService))
+			webhooks.GET("/:id", getWebhookHandler(webhookService))
+			webhooks.PUT("/:id", updateWebhookHandler(webhookService))
+			webhooks.DELETE("/:id", deleteWebhookHandler(webhookService))
+			webhooks.POST("/:id/test", testWebhookHandler(webhookService))
+			webhooks.GET("/:id/deliveries", listWebhookDeliveriesHandler(webhookService))
+			webhooks.POST("/:id/deliveries/:deliveryId/retry", retryWebhookDeliveryHandler(webhookService))
+		}
+
+		// Scheduled report routes (admin only)
+		scheduledReports := protected.Group("/scheduled-reports", auth.RequireAdmin())
+		{
+			scheduledReports.GET("", listScheduledReportsHandler(scheduledReportService))
+			scheduledReports.POST("", createScheduledReportHandler(scheduledReportService))
+			scheduledReports.GET("/:id", getScheduledReportHandler(scheduledReportService))
+			scheduledReports.PUT("/:id", updateScheduledReportHandler(scheduledReportService))
+			scheduledReports.DELETE("/:id", deleteScheduledReportHandler(scheduledReportService))
+			scheduledReports.POST("/:id/run", runScheduledReportHandler(scheduledReportService))
+			scheduledReports.GET("/:id/runs", listScheduledReportRunsHandler(scheduledReportService))
+		}
+
 		// Computer routes
 		computerHandler := NewComputerHandler(computerService)
 		computers := protected.Group("/computers")
@@ -80,6 +279,7 @@ func SetupRoutes(router *gin.Engine, userService *service.UserService, ticketSer
 			computers.GET("/stats", computerHandler.GetComputerStats)
 			computers.GET("/:id", computerHandler.GetComputer)
 			computers.PUT("/:id", auth.RequireAdminOrAgent(), computerHandler.UpdateComputer)
+			computers.PATCH("/:id", auth.RequireAdminOrAgent(), computerHandler.PatchComputer)
 			computers.DELETE("/:id", auth.RequireAdmin(), computerHandler.DeleteComputer)
 			computers.POST("/:id/assign", auth.RequireAdminOrAgent(), computerHandler.AssignComputer)
 			computers.POST("/:id/unassign", auth.RequireAdminOrAgent(), computerHandler.UnassignComputer)
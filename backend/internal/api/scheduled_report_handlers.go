@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scheduledReportRequest struct {
+	Name       *string                           `json:"name"`
+	Kind       *domain.ScheduledReportKind       `json:"kind"`
+	Parameters *domain.ScheduledReportParameters `json:"parameters"`
+	Formats    []domain.ReportFormat             `json:"formats"`
+	Recipients []string                          `json:"recipients"`
+	Schedule   *string                           `json:"schedule"`
+	TimeZone   *string                           `json:"time_zone"`
+	IsActive   *bool                             `json:"is_active"`
+}
+
+func (r scheduledReportRequest) input() service.ScheduledReportInput {
+	return service.ScheduledReportInput{
+		Name:       r.Name,
+		Kind:       r.Kind,
+		Parameters: r.Parameters,
+		Formats:    r.Formats,
+		Recipients: r.Recipients,
+		Schedule:   r.Schedule,
+		TimeZone:   r.TimeZone,
+		IsActive:   r.IsActive,
+	}
+}
+
+func createScheduledReportHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req scheduledReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report, err := scheduledReportService.CreateScheduledReport(c.Request.Context(), req.input(), userID)
+		if err != nil {
+			respondScheduledReportError(c, err, "Failed to create scheduled report")
+			return
+		}
+
+		c.JSON(http.StatusCreated, report)
+	}
+}
+
+func listScheduledReportsHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reports, err := scheduledReportService.ListScheduledReports(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scheduled reports"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reports)
+	}
+}
+
+func getScheduledReportHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled report ID"})
+			return
+		}
+
+		report, err := scheduledReportService.GetScheduledReport(c.Request.Context(), uint(id))
+		if err != nil {
+			respondScheduledReportError(c, err, "Failed to fetch scheduled report")
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func updateScheduledReportHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled report ID"})
+			return
+		}
+
+		var req scheduledReportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		report, err := scheduledReportService.UpdateScheduledReport(c.Request.Context(), uint(id), req.input())
+		if err != nil {
+			respondScheduledReportError(c, err, "Failed to update scheduled report")
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func deleteScheduledReportHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled report ID"})
+			return
+		}
+
+		if err := scheduledReportService.DeleteScheduledReport(c.Request.Context(), uint(id)); err != nil {
+			respondScheduledReportError(c, err, "Failed to delete scheduled report")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Scheduled report deleted successfully"})
+	}
+}
+
+// runScheduledReportHandler sends a report straight away. The run is returned
+// whether or not the delivery succeeded.
+func runScheduledReportHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled report ID"})
+			return
+		}
+
+		run, err := scheduledReportService.RunNow(c.Request.Context(), uint(id), userID)
+		if err != nil {
+			respondScheduledReportError(c, err, "Failed to run scheduled report")
+			return
+		}
+
+		c.JSON(http.StatusOK, run)
+	}
+}
+
+func listScheduledReportRunsHandler(scheduledReportService *service.ScheduledReportService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled report ID"})
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		runs, err := scheduledReportService.ListRuns(c.Request.Context(), uint(id), limit, offset)
+		if err != nil {
+			respondScheduledReportError(c, err, "Failed to fetch report runs")
+			return
+		}
+
+		c.JSON(http.StatusOK, runs)
+	}
+}
+
+// respondScheduledReportError maps scheduled report service errors to HTTP responses
+func respondScheduledReportError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled report not found"})
+	case errors.Is(err, service.ErrInvalidScheduledReport):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
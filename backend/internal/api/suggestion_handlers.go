@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// suggestTicketAnswersHandler returns articles and resolved tickets that may
+// answer a ticket before it is created
+func suggestTicketAnswersHandler(suggestionService *service.SuggestionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req service.TicketDraft
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		suggestions, err := suggestionService.Suggest(c.Request.Context(), req, viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find suggestions"})
+			return
+		}
+
+		c.JSON(http.StatusOK, suggestions)
+	}
+}
+
+// recordDeflectionHandler records that a suggestion solved the problem, so
+// the ticket being written is not created
+func recordDeflectionHandler(suggestionService *service.SuggestionService, ticketService *service.TicketService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			ArticleID   *uint      `json:"article_id"`
+			TicketID    *ticketRef `json:"ticket_id"`
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		deflection := &domain.TicketDeflection{
+			ArticleID:   req.ArticleID,
+			Title:       req.Title,
+			Description: req.Description,
+		}
+		if req.TicketID != nil {
+			ids, ok := resolveTicketRefs(c, ticketService, *req.TicketID)
+			if !ok {
+				return
+			}
+			deflection.TicketID = &ids[0]
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		if err := suggestionService.RecordDeflection(c.Request.Context(), deflection, viewer); err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidDeflection):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrForbidden):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Article or ticket not found"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record deflection"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, deflection)
+	}
+}
+
+// getDeflectionReportHandler reports deflections between from and to, both inclusive
+func getDeflectionReportHandler(suggestionService *service.SuggestionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter repository.DeflectionFilter
+		if from := c.Query("from"); from != "" {
+			t, err := time.Parse(workDateLayout, from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be formatted as YYYY-MM-DD"})
+				return
+			}
+			filter.From = &t
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := time.Parse(workDateLayout, to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be formatted as YYYY-MM-DD"})
+				return
+			}
+			end := t.AddDate(0, 0, 1)
+			filter.To = &end
+		}
+
+		report, err := suggestionService.DeflectionReport(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build deflection report"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -8,19 +9,26 @@ import (
 
 	"helpdesk-backend/internal/auth"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
 	"helpdesk-backend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// createTicketHandler files a ticket. The priority follows from impact and
+// urgency; staff may set a different one when they give a reason.
 func createTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			Title       string                `json:"title" binding:"required"`
-			Description string                `json:"description"`
-			Priority    domain.TicketPriority `json:"priority"`
-			Category    string                `json:"category"`
-			RequesterID uint                  `json:"requester_id" binding:"required"`
+			Title                  string                `json:"title" binding:"required"`
+			Description            string                `json:"description"`
+			Impact                 domain.TicketImpact   `json:"impact"`
+			Urgency                domain.TicketUrgency  `json:"urgency"`
+			Priority               domain.TicketPriority `json:"priority"`
+			PriorityOverrideReason string                `json:"priority_override_reason"`
+			Category               string                `json:"category"`
+			RequesterID            uint                  `json:"requester_id" binding:"required"`
+			ComputerID             *uint                 `json:"computer_id"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -31,22 +39,28 @@ func createTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 		ticket := &domain.Ticket{
 			Title:       req.Title,
 			Description: req.Description,
-			Priority:    req.Priority,
 			Category:    req.Category,
 			RequesterID: req.RequesterID,
+			ComputerID:  req.ComputerID,
 			Status:      domain.OpenStatus,
 		}
 
-		if ticket.Priority == "" {
-			ticket.Priority = domain.MediumPriority
+		// Impact and urgency decide the priority, so end users get the defaults
+		viewer, _ := auth.GetViewer(c)
+		if viewer.IsStaff() {
+			ticket.Impact = req.Impact
+			ticket.Urgency = req.Urgency
+			ticket.Priority = req.Priority
+			ticket.PriorityOverrideReason = req.PriorityOverrideReason
 		}
 
-		err := ticketService.CreateTicket(c.Request.Context(), ticket)
+		err := ticketService.CreateTicket(c.Request.Context(), ticket, viewer.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket"})
+			respondTicketSaveError(c, err, "Failed to create ticket")
 			return
 		}
 
+		setETag(c, ticket.Version)
 		c.JSON(http.StatusCreated, ticket)
 	}
 }
@@ -131,12 +145,15 @@ func getTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 			return
 		}
 
-		ticket, err := ticketService.GetTicketByID(c.Request.Context(), uint(id))
+		viewer, _ := auth.GetViewer(c)
+
+		ticket, err := ticketService.GetTicketForViewer(c.Request.Context(), uint(id), viewer)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
 			return
 		}
 
+		setETag(c, ticket.Version)
 		c.JSON(http.StatusOK, ticket)
 	}
 }
@@ -149,25 +166,36 @@ func updateTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 			return
 		}
 
+		viewer, _ := auth.GetViewer(c)
+
 		var req struct {
-			Title       string                `json:"title"`
-			Description string                `json:"description"`
-			Status      domain.TicketStatus   `json:"status"`
-			Priority    domain.TicketPriority `json:"priority"`
-			Category    string                `json:"category"`
+			Title                  string                `json:"title"`
+			Description            string                `json:"description"`
+			Status                 domain.TicketStatus   `json:"status"`
+			Impact                 domain.TicketImpact   `json:"impact"`
+			Urgency                domain.TicketUrgency  `json:"urgency"`
+			Priority               domain.TicketPriority `json:"priority"`
+			PriorityOverrideReason string                `json:"priority_override_reason"`
+			Category               string                `json:"category"`
+			ComputerID             *uint                 `json:"computer_id"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := bindVersionedJSON(c, &req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		ticket, err := ticketService.GetTicketByID(c.Request.Context(), uint(id))
+		ticket, err := ticketService.GetTicketForViewer(c.Request.Context(), uint(id), viewer)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
 			return
 		}
 
+		if !ifMatchSatisfied(c, ticket.Version) {
+			respondVersionConflict(c, "Ticket was modified by another user", ticket, ticket.Version)
+			return
+		}
+
 		// Update fields
 		if req.Title != "" {
 			ticket.Title = req.Title
@@ -178,19 +206,42 @@ func updateTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 		if req.Status != "" {
 			ticket.Status = req.Status
 		}
-		if req.Priority != "" {
-			ticket.Priority = req.Priority
+		if viewer.IsStaff() {
+			if req.Impact != "" {
+				ticket.Impact = req.Impact
+			}
+			if req.Urgency != "" {
+				ticket.Urgency = req.Urgency
+			}
+			if req.Priority != "" && req.Priority != ticket.Priority {
+				// A new priority needs a reason of its own, not the previous one's
+				ticket.Priority = req.Priority
+				ticket.PriorityOverrideReason = req.PriorityOverrideReason
+			} else if req.PriorityOverrideReason != "" {
+				ticket.PriorityOverrideReason = req.PriorityOverrideReason
+			}
 		}
 		if req.Category != "" {
 			ticket.Category = req.Category
 		}
+		if req.ComputerID != nil {
+			ticket.ComputerID = req.ComputerID
+			ticket.Computer = nil
+		}
 
-		err = ticketService.UpdateTicket(c.Request.Context(), ticket)
+		err = ticketService.UpdateTicket(c.Request.Context(), ticket, viewer.UserID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket"})
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := ticketService.GetTicketForViewer(c.Request.Context(), ticket.ID, viewer); getErr == nil {
+					respondVersionConflict(c, "Ticket was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondTicketSaveError(c, err, "Failed to update ticket")
 			return
 		}
 
+		setETag(c, ticket.Version)
 		c.JSON(http.StatusOK, ticket)
 	}
 }
@@ -230,8 +281,21 @@ func assignTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 			return
 		}
 
-		err = ticketService.AssignTicket(c.Request.Context(), uint(ticketID), req.AssigneeID)
+		actorID, _ := auth.GetCurrentUserID(c)
+		err = ticketService.AssignTicket(c.Request.Context(), uint(ticketID), req.AssigneeID, actorID)
 		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+				return
+			}
+			if errors.Is(err, service.ErrInvalidAssignee) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, repository.ErrVersionConflict) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Ticket was modified by another user, please retry"})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign ticket"})
 			return
 		}
@@ -239,3 +303,95 @@ func assignTicketHandler(ticketService *service.TicketService) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"message": "Ticket assigned successfully"})
 	}
 }
+
+// patchTicketHandler applies an RFC 7396 JSON Merge Patch to a ticket. End
+// users may only edit the descriptive fields of their own tickets.
+func patchTicketHandler(ticketService *service.TicketService, userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		viewer, _ := auth.GetViewer(c)
+		isStaff := viewer.IsStaff()
+
+		patch, err := bindMergePatch(c)
+		if err != nil {
+			respondPatchBindError(c, err)
+			return
+		}
+
+		ticket, err := ticketService.GetTicketForViewer(c.Request.Context(), uint(id), viewer)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+			return
+		}
+
+		if !isStaff && ticket.RequesterID != viewer.UserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own tickets"})
+			return
+		}
+
+		if !ifMatchSatisfied(c, ticket.Version) {
+			respondVersionConflict(c, "Ticket was modified by another user", ticket, ticket.Version)
+			return
+		}
+
+		validateAssignee := func(assigneeID uint) error {
+			assignee, err := userService.GetUserByID(c.Request.Context(), assigneeID)
+			if err != nil {
+				return errors.New("user not found")
+			}
+			if assignee.Role != domain.AgentRole && assignee.Role != domain.AdminRole {
+				return errors.New("tickets can only be assigned to agents or admins")
+			}
+			return nil
+		}
+
+		previousPriority := ticket.Priority
+		errs, forbidden := patch.apply(map[string]patchField{
+			"title":                    stringPatch(&ticket.Title, true, nil),
+			"description":              stringPatch(&ticket.Description, false, nil),
+			"category":                 stringPatch(&ticket.Category, false, nil),
+			"computer_id":              idPatch(&ticket.ComputerID, nil),
+			"impact":                   restrict(enumPatch(&ticket.Impact, domain.TicketImpact.IsValid), isStaff),
+			"urgency":                  restrict(enumPatch(&ticket.Urgency, domain.TicketUrgency.IsValid), isStaff),
+			"status":                   restrict(enumPatch(&ticket.Status, domain.TicketStatus.IsValid), isStaff),
+			"priority":                 restrict(enumPatch(&ticket.Priority, domain.TicketPriority.IsValid), isStaff),
+			"priority_override_reason": restrict(stringPatch(&ticket.PriorityOverrideReason, false, nil), isStaff),
+			"assignee_id":              restrict(idPatch(&ticket.AssigneeID, validateAssignee), isStaff),
+		})
+		if len(errs) > 0 {
+			respondPatchErrors(c, errs, forbidden)
+			return
+		}
+
+		// A new priority needs a reason of its own, not the previous one's
+		if _, ok := patch["priority_override_reason"]; !ok && ticket.Priority != previousPriority {
+			ticket.PriorityOverrideReason = ""
+		}
+
+		if err := ticketService.UpdateTicket(c.Request.Context(), ticket, viewer.UserID); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				if current, getErr := ticketService.GetTicketForViewer(c.Request.Context(), ticket.ID, viewer); getErr == nil {
+					respondVersionConflict(c, "Ticket was modified by another user", current, current.Version)
+					return
+				}
+			}
+			respondTicketSaveError(c, err, "Failed to update ticket")
+			return
+		}
+
+		// Reload so that cleared or changed relations are reflected in the response
+		updated, err := ticketService.GetTicketForViewer(c.Request.Context(), ticket.ID, viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated ticket"})
+			return
+		}
+
+		setETag(c, updated.Version)
+		c.JSON(http.StatusOK, updated)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolveTicketKey lets routes that take a ticket ID in the path parameter
+// param also accept a ticket key such as HD-2026-00042. The key is replaced
+// by the ticket ID before the handler runs.
+func resolveTicketKey(ticketService *service.TicketService, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i := range c.Params {
+			if c.Params[i].Key != param {
+				continue
+			}
+
+			id, err := ticketService.ResolveTicketRef(c.Request.Context(), c.Params[i].Value)
+			if err != nil {
+				if errors.Is(err, service.ErrNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve ticket"})
+				}
+				c.Abort()
+				return
+			}
+			c.Params[i].Value = strconv.FormatUint(uint64(id), 10)
+		}
+		c.Next()
+	}
+}
+
+// ticketRef is a ticket referenced in a request body, either by numeric ID or by key
+type ticketRef string
+
+func (r *ticketRef) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var key string
+		if err := json.Unmarshal(data, &key); err != nil {
+			return err
+		}
+		*r = ticketRef(key)
+		return nil
+	}
+
+	var id uint
+	if err := json.Unmarshal(data, &id); err != nil {
+		return errors.New("ticket must be an ID or a ticket key")
+	}
+	*r = ticketRef(strconv.FormatUint(uint64(id), 10))
+	return nil
+}
+
+// resolveTicketRefs turns ticket references into IDs, responding with an error
+// and returning false if one of them does not exist
+func resolveTicketRefs(c *gin.Context, ticketService *service.TicketService, refs ...ticketRef) ([]uint, bool) {
+	ids := make([]uint, 0, len(refs))
+	for _, ref := range refs {
+		id, err := ticketService.ResolveTicketRef(c.Request.Context(), string(ref))
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found: " + string(ref)})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve ticket"})
+			}
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
@@ -1,10 +1,12 @@
 package api
 
 import (
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"helpdesk-backend/internal/auth"
 	"helpdesk-backend/internal/domain"
 	"helpdesk-backend/internal/service"
 
@@ -15,9 +17,6 @@ import (
 
 func listUsersHandler(userService *service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Test hot reload - added logging
-		log.Printf("📝 Fetching users list...")
-
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
@@ -27,7 +26,6 @@ func listUsersHandler(userService *service.UserService) gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("✅ Successfully fetched %d users", len(users))
 		c.JSON(http.StatusOK, users)
 	}
 }
@@ -106,6 +104,66 @@ func updateUserHandler(userService *service.UserService) gin.HandlerFunc {
 	}
 }
 
+// patchUserHandler applies an RFC 7396 JSON Merge Patch to a user. Users may
+// edit their own profile; only admins may edit others or change role and status.
+func patchUserHandler(userService *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
+		currentUserID, _ := auth.GetCurrentUserID(c)
+		role, _ := auth.GetCurrentUserRole(c)
+		isAdmin := role == domain.AdminRole
+
+		if !isAdmin && currentUserID != uint(id) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own profile"})
+			return
+		}
+
+		patch, err := bindMergePatch(c)
+		if err != nil {
+			respondPatchBindError(c, err)
+			return
+		}
+
+		user, err := userService.GetUserByID(c.Request.Context(), uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		nameLength := func(value string) error {
+			if n := len(strings.TrimSpace(value)); n < 2 || n > 50 {
+				return errors.New("must be between 2 and 50 characters")
+			}
+			return nil
+		}
+
+		errs, forbidden := patch.apply(map[string]patchField{
+			"first_name": stringPatch(&user.FirstName, true, nameLength),
+			"last_name":  stringPatch(&user.LastName, true, nameLength),
+			"department": stringPatch(&user.Department, false, nil),
+			"role":       restrict(enumPatch(&user.Role, domain.UserRole.IsValid), isAdmin),
+			"is_active":  restrict(boolPatch(&user.IsActive), isAdmin),
+		})
+		if len(errs) > 0 {
+			respondPatchErrors(c, errs, forbidden)
+			return
+		}
+
+		if err := userService.UpdateUser(c.Request.Context(), user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+			return
+		}
+
+		user.Password = ""
+		c.JSON(http.StatusOK, user)
+	}
+}
+
 func deleteUserHandler(userService *service.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
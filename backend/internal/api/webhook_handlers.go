@@ -0,0 +1,215 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type webhookRequest struct {
+	Name       *string  `json:"name"`
+	URL        *string  `json:"url"`
+	Secret     *string  `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+func (r webhookRequest) input() service.WebhookInput {
+	return service.WebhookInput{
+		Name:       r.Name,
+		URL:        r.URL,
+		Secret:     r.Secret,
+		EventTypes: r.EventTypes,
+		IsActive:   r.IsActive,
+	}
+}
+
+func createWebhookHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req webhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook, secret, err := webhookService.CreateWebhook(c.Request.Context(), req.input(), userID)
+		if err != nil {
+			respondWebhookError(c, err, "Failed to create webhook")
+			return
+		}
+
+		// The secret is only ever returned here
+		c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": secret})
+	}
+}
+
+func listWebhooksHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhooks, err := webhookService.ListWebhooks(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+			return
+		}
+
+		c.JSON(http.StatusOK, webhooks)
+	}
+}
+
+func getWebhookHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+
+		webhook, err := webhookService.GetWebhook(c.Request.Context(), uint(id))
+		if err != nil {
+			respondWebhookError(c, err, "Failed to fetch webhook")
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+func updateWebhookHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+
+		var req webhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook, err := webhookService.UpdateWebhook(c.Request.Context(), uint(id), req.input())
+		if err != nil {
+			respondWebhookError(c, err, "Failed to update webhook")
+			return
+		}
+
+		c.JSON(http.StatusOK, webhook)
+	}
+}
+
+func deleteWebhookHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+
+		if err := webhookService.DeleteWebhook(c.Request.Context(), uint(id)); err != nil {
+			respondWebhookError(c, err, "Failed to delete webhook")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+	}
+}
+
+func listWebhookDeliveriesHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit < 1 || limit > 200 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		status := domain.WebhookDeliveryStatus(c.Query("status"))
+		switch status {
+		case "", domain.WebhookDeliveryPending, domain.WebhookDeliverySucceeded, domain.WebhookDeliveryDead:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery status"})
+			return
+		}
+
+		deliveries, err := webhookService.ListDeliveries(c.Request.Context(), uint(id), status, limit, offset)
+		if err != nil {
+			respondWebhookError(c, err, "Failed to fetch webhook deliveries")
+			return
+		}
+
+		c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+func retryWebhookDeliveryHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+		deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+			return
+		}
+
+		delivery, err := webhookService.RetryDelivery(c.Request.Context(), uint(id), uint(deliveryID))
+		if err != nil {
+			respondWebhookError(c, err, "Failed to retry webhook delivery")
+			return
+		}
+
+		c.JSON(http.StatusAccepted, delivery)
+	}
+}
+
+func testWebhookHandler(webhookService *service.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+			return
+		}
+
+		delivery, err := webhookService.SendTest(c.Request.Context(), uint(id))
+		if err != nil {
+			respondWebhookError(c, err, "Failed to send test event")
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// respondWebhookError maps webhook service errors to HTTP responses
+func respondWebhookError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook or delivery not found"})
+	case errors.Is(err, service.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"helpdesk-backend/internal/auth"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const workDateLayout = "2006-01-02"
+
+func createWorkLogHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req struct {
+			DurationMinutes int    `json:"duration_minutes" binding:"required"`
+			Billable        *bool  `json:"billable"`
+			Note            string `json:"note"`
+			WorkDate        string `json:"work_date"`
+		}
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workLog := &domain.WorkLog{
+			TicketID:        uint(ticketID),
+			AgentID:         userID,
+			DurationMinutes: req.DurationMinutes,
+			Billable:        req.Billable == nil || *req.Billable,
+			Note:            req.Note,
+		}
+
+		if req.WorkDate != "" {
+			workDate, err := time.Parse(workDateLayout, req.WorkDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "work_date must be formatted as YYYY-MM-DD"})
+				return
+			}
+			workLog.WorkDate = workDate
+		}
+
+		if err := workLogService.LogWork(c.Request.Context(), workLog); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, workLog)
+	}
+}
+
+func listWorkLogsHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		workLogs, err := workLogService.ListWorkLogsByTicket(c.Request.Context(), uint(ticketID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch work logs"})
+			return
+		}
+
+		c.JSON(http.StatusOK, workLogs)
+	}
+}
+
+func updateWorkLogHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work log ID"})
+			return
+		}
+
+		var req struct {
+			DurationMinutes int     `json:"duration_minutes"`
+			Billable        *bool   `json:"billable"`
+			Note            *string `json:"note"`
+			WorkDate        string  `json:"work_date"`
+		}
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		workLog, err := workLogService.GetWorkLogByID(c.Request.Context(), uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Work log not found"})
+			return
+		}
+
+		if !canManageWorkLog(c, workLog) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only edit your own work logs"})
+			return
+		}
+
+		// Update fields
+		if req.DurationMinutes != 0 {
+			workLog.DurationMinutes = req.DurationMinutes
+		}
+		if req.Billable != nil {
+			workLog.Billable = *req.Billable
+		}
+		if req.Note != nil {
+			workLog.Note = *req.Note
+		}
+		if req.WorkDate != "" {
+			workDate, err := time.Parse(workDateLayout, req.WorkDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "work_date must be formatted as YYYY-MM-DD"})
+				return
+			}
+			workLog.WorkDate = workDate
+		}
+
+		if err := workLogService.UpdateWorkLog(c.Request.Context(), workLog); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, workLog)
+	}
+}
+
+func deleteWorkLogHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid work log ID"})
+			return
+		}
+
+		workLog, err := workLogService.GetWorkLogByID(c.Request.Context(), uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Work log not found"})
+			return
+		}
+
+		if !canManageWorkLog(c, workLog) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete your own work logs"})
+			return
+		}
+
+		if err := workLogService.DeleteWorkLog(c.Request.Context(), workLog.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete work log"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Work log deleted successfully"})
+	}
+}
+
+func startTimerHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		// The body is optional; an empty request starts a billable timer
+		var req struct {
+			Billable *bool  `json:"billable"`
+			Note     string `json:"note"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		timer, err := workLogService.StartTimer(c.Request.Context(), uint(ticketID), userID, req.Billable == nil || *req.Billable, req.Note)
+		if err != nil {
+			if errors.Is(err, service.ErrTimerAlreadyRunning) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, timer)
+	}
+}
+
+func stopTimerHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket ID"})
+			return
+		}
+
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		var req struct {
+			Note            string `json:"note"`
+			DurationMinutes *int   `json:"duration_minutes"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		workLog, err := workLogService.StopTimer(c.Request.Context(), uint(ticketID), userID, req.Note, req.DurationMinutes)
+		if err != nil {
+			if errors.Is(err, service.ErrTimerNotRunning) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrTimerTooLong) || errors.Is(err, service.ErrInvalidDuration) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop timer"})
+			return
+		}
+
+		c.JSON(http.StatusOK, workLog)
+	}
+}
+
+func listActiveTimersHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetCurrentUserID(c)
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+
+		timers, err := workLogService.ListActiveTimers(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timers"})
+			return
+		}
+
+		c.JSON(http.StatusOK, timers)
+	}
+}
+
+func getTimeReportHandler(workLogService *service.WorkLogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupBy := c.DefaultQuery("group_by", "agent")
+
+		var filter repository.TimeReportFilter
+		if from := c.Query("from"); from != "" {
+			t, err := time.Parse(workDateLayout, from)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "from must be formatted as YYYY-MM-DD"})
+				return
+			}
+			filter.From = &t
+		}
+		if to := c.Query("to"); to != "" {
+			t, err := time.Parse(workDateLayout, to)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "to must be formatted as YYYY-MM-DD"})
+				return
+			}
+			filter.To = &t
+		}
+		if agentID := c.Query("agent_id"); agentID != "" {
+			id, err := strconv.ParseUint(agentID, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agent ID"})
+				return
+			}
+			agent := uint(id)
+			filter.AgentID = &agent
+		}
+		if billable := c.Query("billable"); billable != "" {
+			b := billable == "true"
+			filter.Billable = &b
+		}
+
+		rows, err := workLogService.GetTimeReport(c.Request.Context(), groupBy, filter)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		totalMinutes, billableMinutes := 0, 0
+		for _, row := range rows {
+			totalMinutes += row.TotalMinutes
+			billableMinutes += row.BillableMinutes
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"group_by":         groupBy,
+			"rows":             rows,
+			"total_minutes":    totalMinutes,
+			"billable_minutes": billableMinutes,
+		})
+	}
+}
+
+// canManageWorkLog reports whether the current user may edit or delete a work log
+func canManageWorkLog(c *gin.Context, workLog *domain.WorkLog) bool {
+	userID, _ := auth.GetCurrentUserID(c)
+	role, _ := auth.GetCurrentUserRole(c)
+	return role == domain.AdminRole || workLog.AgentID == userID
+}
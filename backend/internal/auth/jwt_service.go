@@ -2,7 +2,7 @@ package auth
 
 import (
 	"errors"
-	"fmt"
+	"log/slog"
 	"time"
 
 	"helpdesk-backend/internal/domain"
@@ -61,14 +61,7 @@ func (j *JWTService) GenerateTokenPair(user *domain.User) (*domain.TokenPair, er
 // generateToken creates a JWT token with the specified parameters
 func (j *JWTService) generateToken(user *domain.User, isRefresh bool, expiresAt time.Time) (string, error) {
 	now := time.Now()
-
-	// Debug logging
-	if isRefresh {
-		fmt.Printf("DEBUG: Refresh token - now: %v, expiresAt: %v, diff: %v\n",
-			now.Format(time.RFC3339),
-			expiresAt.Format(time.RFC3339),
-			expiresAt.Sub(now))
-	}
+	slog.Debug("Issuing token", "user_id", user.ID, "refresh", isRefresh, "expires_at", expiresAt)
 
 	claims := Claims{
 		UserID:    user.ID,
@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
 
 	"github.com/gin-gonic/gin"
 )
@@ -45,8 +47,8 @@ func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 
 		c.Header("Vary", "Origin")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, If-Match, Last-Event-ID, X-API-Key, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "Content-Length, Authorization, ETag, X-Request-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		// Handle preflight
@@ -108,12 +110,27 @@ func AuthMiddleware(jwtService *JWTService) gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("jwt_claims", claims)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", claims.UserID))
 
 		// Continue to the next handler
 		c.Next()
 	}
 }
 
+// TokenFromQuery lets clients that cannot set headers, such as the browser
+// EventSource API, pass the access token in the access_token query parameter.
+// It must run before AuthMiddleware.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
+
 // RequireRole creates middleware that requires specific user roles
 func RequireRole(allowedRoles ...domain.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -186,6 +203,19 @@ func GetCurrentUserRole(c *gin.Context) (domain.UserRole, bool) {
 	return role, ok
 }
 
+// GetViewer returns the current user as a domain.Viewer for visibility checks
+func GetViewer(c *gin.Context) (domain.Viewer, bool) {
+	userID, ok := GetCurrentUserID(c)
+	if !ok {
+		return domain.Viewer{}, false
+	}
+	role, ok := GetCurrentUserRole(c)
+	if !ok {
+		return domain.Viewer{}, false
+	}
+	return domain.Viewer{UserID: userID, Role: role}, true
+}
+
 // GetCurrentUserEmail extracts the current user email from the JWT context
 func GetCurrentUserEmail(c *gin.Context) (string, bool) {
 	userEmail, exists := c.Get("user_email")
@@ -196,3 +226,31 @@ func GetCurrentUserEmail(c *gin.Context) (string, bool) {
 	email, ok := userEmail.(string)
 	return email, ok
 }
+
+// APIKeyMiddleware authenticates machine clients by a shared key sent as
+// "X-API-Key: <key>" or "Authorization: Bearer <key>". When apiKey is empty
+// the endpoint is disabled.
+func APIKeyMiddleware(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API key authentication is not configured"})
+			c.Abort()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+				key = token
+			}
+		}
+
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
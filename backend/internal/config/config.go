@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -52,6 +53,20 @@ type Config struct {
 	// Template Configuration
 	EmailTemplatePath string
 
+	// Ticket Key Configuration
+	TicketKeyPrefix           string
+	TicketKeyYearly           bool
+	TicketKeyDigits           int
+	TicketKeyCategoryPrefixes string
+
+	// Monitoring Alert Intake Configuration
+	AlertAPIKey         string
+	AlertRequesterEmail string
+	AlertCategory       string
+
+	// Metrics Configuration
+	MetricsAPIKey string
+
 	// Logging Configuration
 	LogLevel  string
 	LogFormat string
@@ -112,6 +127,20 @@ func Load() (*Config, error) {
 		// Template Configuration
 		EmailTemplatePath: getEnv("EMAIL_TEMPLATE_PATH", "./templates/emails"),
 
+		// Ticket Key Configuration
+		TicketKeyPrefix:           getEnv("TICKET_KEY_PREFIX", "HD"),
+		TicketKeyYearly:           getEnvAsBool("TICKET_KEY_YEARLY", true),
+		TicketKeyDigits:           getEnvAsInt("TICKET_KEY_DIGITS", 5),
+		TicketKeyCategoryPrefixes: getEnv("TICKET_KEY_CATEGORY_PREFIXES", ""),
+
+		// Monitoring Alert Intake Configuration
+		AlertAPIKey:         getEnv("ALERT_API_KEY", ""),
+		AlertRequesterEmail: getEnv("ALERT_REQUESTER_EMAIL", ""),
+		AlertCategory:       getEnv("ALERT_CATEGORY", "Monitoring"),
+
+		// Metrics Configuration
+		MetricsAPIKey: getEnv("METRICS_API_KEY", ""),
+
 		// Logging Configuration
 		LogLevel:  getEnv("LOG_LEVEL", "debug"),
 		LogFormat: getEnv("LOG_FORMAT", "json"),
@@ -120,6 +149,52 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// MaxFileSizeBytes parses MaxFileSize, which accepts a plain byte count or a
+// KB, MB or GB suffix. It falls back to 10MB when the value is invalid.
+func (c *Config) MaxFileSizeBytes() int64 {
+	const fallback = 10 << 20
+
+	value := strings.ToUpper(strings.TrimSpace(c.MaxFileSize))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n * multiplier
+}
+
+// AllowedFileTypeList returns the comma separated AllowedFileTypes as a slice
+func (c *Config) AllowedFileTypeList() []string {
+	return strings.Split(c.AllowedFileTypes, ",")
+}
+
+// TicketKeyCategoryPrefixMap parses TicketKeyCategoryPrefixes, a comma
+// separated list of category=PREFIX pairs. Categories are matched case-insensitively.
+func (c *Config) TicketKeyCategoryPrefixMap() map[string]string {
+	prefixes := make(map[string]string)
+	for _, pair := range strings.Split(c.TicketKeyCategoryPrefixes, ",") {
+		category, prefix, ok := strings.Cut(pair, "=")
+		category = strings.ToLower(strings.TrimSpace(category))
+		prefix = strings.ToUpper(strings.TrimSpace(prefix))
+		if !ok || category == "" || prefix == "" {
+			continue
+		}
+		prefixes[category] = prefix
+	}
+	return prefixes
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -135,3 +210,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
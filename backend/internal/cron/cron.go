@@ -0,0 +1,201 @@
+// Package cron parses standard five-field cron expressions and computes when
+// they next fire.
+//
+// The fields are minute, hour, day of month, month and day of week. Each
+// accepts *, a value, a range such as 1-5, a step such as */15 or 1-30/2, or a
+// comma-separated list of those. Months and weekdays may also be given by their
+// three-letter English names, and 7 means Sunday like 0. As in Vixie cron, when
+// both day fields are restricted, meaning neither starts with *, a day matches
+// if either does. The shortcuts @hourly, @daily, @weekly (Monday) and @monthly
+// are accepted too.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// Whether the day fields were restricted rather than starting with *
+	daysRestricted     bool
+	weekdaysRestricted bool
+}
+
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Parse parses a cron expression
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := shortcuts[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hours, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.days, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.months, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.weekdays, err = parseField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	// Sunday may be written as 7
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays = s.weekdays&^(1<<7) | 1
+	}
+	s.daysRestricted = !strings.HasPrefix(fields[2], "*")
+	s.weekdaysRestricted = !strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// Next returns the first time after t, to the minute, at which the schedule
+// fires, in t's location. Wall-clock times skipped by a daylight saving change
+// do not fire, and those repeated when clocks go back fire only the first
+// time. It returns the zero time if the schedule never fires, such as
+// on February 30th.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every combination repeats within a few years; leap days within eight
+	for limit := t.AddDate(8, 0, 0); t.Before(limit); {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = wallTime(t.Year(), t.Month()+1, 1, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = wallTime(t.Year(), t.Month(), t.Day()+1, 0, loc)
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = wallTime(t.Year(), t.Month(), t.Day(), t.Hour()+1, loc)
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 || repeated(t) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.daysRestricted && s.weekdaysRestricted {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// wallTime returns the start of an hour of wall-clock time in loc. An hour
+// skipped by a daylight saving change starts when clocks jump past it;
+// time.Date would return a time before the change instead, which could keep
+// Next from moving forward.
+func wallTime(year int, month time.Month, day, hour int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, 0, 0, 0, loc)
+	want := time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	return t.Add(want.Sub(got))
+}
+
+// repeated reports whether t's wall-clock time already occurred earlier that
+// day, because clocks were set back
+func repeated(t time.Time) bool {
+	start, _ := t.ZoneBounds()
+	if start.IsZero() {
+		return false
+	}
+	_, offset := t.Zone()
+	_, before := start.Add(-time.Second).Zone()
+	return t.Sub(start) < time.Duration(before-offset)*time.Second
+}
+
+// parseField returns the values a field matches as a bit set
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(from, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(to, min, max, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// "5/15" means from 5 to the end in steps of 15
+				hi = max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("value %q is not between %d and %d", value, min, max)
+	}
+	return n, nil
+}
@@ -0,0 +1,147 @@
+package cron
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@yearly",
+	}
+	for _, spec := range tests {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string // empty when the schedule never fires
+	}{
+		{"step", "*/15 * * * *", "2026-10-18T10:07:00Z", "2026-10-18T10:15:00Z"},
+		{"strictly after", "*/15 * * * *", "2026-10-18T10:15:00Z", "2026-10-18T10:30:00Z"},
+		{"seconds are dropped", "*/15 * * * *", "2026-10-18T10:14:59Z", "2026-10-18T10:15:00Z"},
+		{"step from a value", "5/20 * * * *", "2026-10-18T10:46:00Z", "2026-10-18T11:05:00Z"},
+		{"weekdays", "0 9 * * 1-5", "2026-10-16T10:00:00Z", "2026-10-19T09:00:00Z"},
+		{"sunday as 7", "0 0 * * 7", "2026-10-14T00:00:00Z", "2026-10-18T00:00:00Z"},
+		{"names", "0 0 1 jan,jul *", "2026-10-18T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"either day field", "0 0 1,15 * mon", "2026-10-02T00:00:00Z", "2026-10-05T00:00:00Z"},
+		{"day step is not a restriction", "0 0 */2 * mon", "2026-10-06T00:00:00Z", "2026-10-19T00:00:00Z"},
+		{"weekday step is not a restriction", "0 0 13 * */3", "2026-10-01T00:00:00Z", "2026-12-13T00:00:00Z"},
+		{"hourly", "@hourly", "2026-10-18T10:00:00Z", "2026-10-18T11:00:00Z"},
+		{"daily", "@daily", "2026-12-31T23:59:00Z", "2027-01-01T00:00:00Z"},
+		{"weekly", "@weekly", "2026-10-18T10:00:00Z", "2026-10-19T00:00:00Z"},
+		{"monthly", "@monthly", "2026-10-18T10:00:00Z", "2026-11-01T00:00:00Z"},
+		{"end of month", "0 0 31 * *", "2026-10-31T00:00:00Z", "2026-12-31T00:00:00Z"},
+		{"leap day", "0 12 29 2 *", "2026-03-01T00:00:00Z", "2028-02-29T12:00:00Z"},
+		{"never", "0 0 30 2 *", "2026-10-18T00:00:00Z", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkNext(t, tt.spec, parseTime(t, tt.from, time.UTC), tt.want)
+		})
+	}
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In 2026 New York clocks go from 02:00 to 03:00 on March 8th and from
+	// 02:00 back to 01:00 on November 1st
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"skipped time does not fire", "30 2 * * *", "2026-03-07T12:00:00-05:00", "2026-03-09T02:30:00-04:00"},
+		{"hourly across the gap", "0 * * * *", "2026-03-08T01:30:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"daily after the gap", "0 9 * * *", "2026-03-08T00:00:00-05:00", "2026-03-08T09:00:00-04:00"},
+		{"repeated time fires first", "30 1 * * *", "2026-11-01T00:00:00-04:00", "2026-11-01T01:30:00-04:00"},
+		{"repeated time fires once", "30 1 * * *", "2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00"},
+		{"from within the repeated hour", "30 1 * * *", "2026-11-01T01:10:00-05:00", "2026-11-02T01:30:00-05:00"},
+		{"hourly across the repeat", "0 * * * *", "2026-11-01T01:00:00-04:00", "2026-11-01T02:00:00-05:00"},
+		{"every minute across the repeat", "* * * * *", "2026-11-01T01:59:00-04:00", "2026-11-01T02:00:00-05:00"},
+		{"daily after the repeat", "0 9 * * *", "2026-11-01T00:00:00-04:00", "2026-11-01T09:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkNext(t, tt.spec, parseTime(t, tt.from, newYork), tt.want)
+		})
+	}
+}
+
+func TestNextDaylightSavingAtMidnight(t *testing.T) {
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In 2026 Santiago clocks go from midnight to 01:00 on September 6th
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{"skipped midnight does not fire", "0 0 * * *", "2026-09-05T12:00:00-04:00", "2026-09-07T00:00:00-03:00"},
+		{"next day", "0 12 * * *", "2026-09-05T13:00:00-04:00", "2026-09-06T12:00:00-03:00"},
+		{"next month", "0 12 * oct *", "2026-09-05T13:00:00-04:00", "2026-10-01T12:00:00-03:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkNext(t, tt.spec, parseTime(t, tt.from, santiago), tt.want)
+		})
+	}
+}
+
+func checkNext(t *testing.T, spec string, from time.Time, want string) {
+	t.Helper()
+	schedule, err := Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", spec, err)
+	}
+
+	got := schedule.Next(from)
+	if want == "" {
+		if !got.IsZero() {
+			t.Errorf("Next(%v) = %v, want the zero time", from, got)
+		}
+		return
+	}
+	if expected := parseTime(t, want, from.Location()); !got.Equal(expected) {
+		t.Errorf("Next(%v) = %v, want %v", from, got, expected)
+	}
+	if got.Location() != from.Location() {
+		t.Errorf("Next(%v) is in %v, want %v", from, got.Location(), from.Location())
+	}
+}
+
+func parseTime(t *testing.T, value string, loc *time.Location) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.In(loc)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,6 +16,27 @@ const (
 	EndUserRole UserRole = "end_user"
 )
 
+// IsValid reports whether r is a known user role
+func (r UserRole) IsValid() bool {
+	switch r {
+	case AdminRole, AgentRole, EndUserRole:
+		return true
+	}
+	return false
+}
+
+// Viewer identifies the user on whose behalf data is read, so that
+// visibility rules such as hiding internal notes can be applied
+type Viewer struct {
+	UserID uint
+	Role   UserRole
+}
+
+// IsStaff reports whether the viewer is an agent or an admin
+func (v Viewer) IsStaff() bool {
+	return v.Role == AdminRole || v.Role == AgentRole
+}
+
 // JWT-related structures
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
@@ -70,6 +92,15 @@ const (
 	ClosedStatus     TicketStatus = "closed"
 )
 
+// IsValid reports whether s is a known ticket status
+func (s TicketStatus) IsValid() bool {
+	switch s {
+	case OpenStatus, InProgressStatus, ResolvedStatus, ClosedStatus:
+		return true
+	}
+	return false
+}
+
 // TicketPriority defines the priority level of a ticket
 type TicketPriority string
 
@@ -80,14 +111,69 @@ const (
 	CriticalPriority TicketPriority = "critical"
 )
 
+// IsValid reports whether p is a known ticket priority
+func (p TicketPriority) IsValid() bool {
+	switch p {
+	case LowPriority, MediumPriority, HighPriority, CriticalPriority:
+		return true
+	}
+	return false
+}
+
+// TicketImpact describes how widely an incident affects the business
+type TicketImpact string
+
+const (
+	LowImpact    TicketImpact = "low"
+	MediumImpact TicketImpact = "medium"
+	HighImpact   TicketImpact = "high"
+)
+
+// IsValid reports whether i is a known impact level
+func (i TicketImpact) IsValid() bool {
+	switch i {
+	case LowImpact, MediumImpact, HighImpact:
+		return true
+	}
+	return false
+}
+
+// TicketUrgency describes how quickly an incident needs to be resolved
+type TicketUrgency string
+
+const (
+	LowUrgency    TicketUrgency = "low"
+	MediumUrgency TicketUrgency = "medium"
+	HighUrgency   TicketUrgency = "high"
+)
+
+// IsValid reports whether u is a known urgency level
+func (u TicketUrgency) IsValid() bool {
+	switch u {
+	case LowUrgency, MediumUrgency, HighUrgency:
+		return true
+	}
+	return false
+}
+
 // Ticket represents a support ticket
 type Ticket struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Title       string         `json:"title" gorm:"not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Status      TicketStatus   `json:"status" gorm:"not null;default:'open';index"`
-	Priority    TicketPriority `json:"priority" gorm:"not null;default:'medium'"`
-	Category    string         `json:"category"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Key             *string        `json:"key" gorm:"size:40;uniqueIndex"` // human-readable reference such as HD-2026-00042
+	Title           string         `json:"title" gorm:"not null"`
+	Description     string         `json:"description" gorm:"type:text"`
+	DescriptionHTML string         `json:"description_html" gorm:"type:text"` // sanitized HTML rendered from the Markdown description
+	Status          TicketStatus   `json:"status" gorm:"not null;default:'open';index"`
+	Priority        TicketPriority `json:"priority" gorm:"not null;default:'medium'"`
+	Category        string         `json:"category"`
+	Version         int            `json:"version" gorm:"not null;default:1"`
+
+	// Priority is computed from impact and urgency through the priority
+	// matrix unless an agent overrode it, giving a reason. Tickets created
+	// before the matrix have neither until they are backfilled at startup.
+	Impact                 TicketImpact  `json:"impact" gorm:"not null;default:''"`
+	Urgency                TicketUrgency `json:"urgency" gorm:"not null;default:''"`
+	PriorityOverrideReason string        `json:"priority_override_reason,omitempty" gorm:"type:text"`
 
 	// Relationships
 	RequesterID uint `json:"requester_id" gorm:"not null"`
@@ -96,10 +182,30 @@ type Ticket struct {
 	AssigneeID *uint `json:"assignee_id"`
 	Assignee   *User `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID"`
 
+	// Affected asset, used for time and cost reporting per machine
+	ComputerID *uint     `json:"computer_id" gorm:"index"`
+	Computer   *Computer `json:"computer,omitempty" gorm:"foreignKey:ComputerID"`
+
 	// SLA fields
 	SLABreachAt *time.Time `json:"sla_breach_at" gorm:"index"`
+	SLAWarnedAt *time.Time `json:"sla_warned_at"` // set once an SLA warning was raised
 	ResolvedAt  *time.Time `json:"resolved_at"`
 
+	// Set on tickets opened by the monitoring alert intake. AlertStartsAt is
+	// the start of the firing the ticket last heard about.
+	AlertFingerprint *string    `json:"alert_fingerprint,omitempty" gorm:"index"`
+	AlertStartsAt    *time.Time `json:"-"`
+
+	// Problem whose root cause this incident is linked to
+	ProblemID *uint `json:"problem_id" gorm:"index"`
+
+	// A new ticket resembling a recent open one is flagged with the likely
+	// original and a confidence from 0 to 1, until an agent marks it as a
+	// duplicate of that or another ticket or dismisses the flag
+	PossibleDuplicateOfID *uint    `json:"possible_duplicate_of_id" gorm:"index"`
+	DuplicateConfidence   *float64 `json:"duplicate_confidence"`
+	DuplicateOfID         *uint    `json:"duplicate_of_id" gorm:"index"`
+
 	// Timestamps
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
@@ -107,13 +213,328 @@ type Ticket struct {
 
 	// Associated data
 	Comments []Comment `json:"comments,omitempty" gorm:"foreignKey:TicketID"`
+	Tags     []Tag     `json:"tags,omitempty" gorm:"many2many:ticket_tags"`
+
+	// Time tracking totals, computed from work logs (not persisted)
+	TimeSpentMinutes int `json:"time_spent_minutes" gorm:"-"`
+	BillableMinutes  int `json:"billable_minutes" gorm:"-"`
+}
+
+// ProblemStatus tracks a problem from investigation to resolution
+type ProblemStatus string
+
+const (
+	ProblemOpen          ProblemStatus = "open"
+	ProblemInvestigating ProblemStatus = "investigating"
+	ProblemKnownError    ProblemStatus = "known_error" // root cause found and a workaround documented
+	ProblemResolved      ProblemStatus = "resolved"
+	ProblemClosed        ProblemStatus = "closed"
+)
+
+// IsValid reports whether s is a known problem status
+func (s ProblemStatus) IsValid() bool {
+	switch s {
+	case ProblemOpen, ProblemInvestigating, ProblemKnownError, ProblemResolved, ProblemClosed:
+		return true
+	}
+	return false
+}
+
+// Problem is the underlying cause of one or more incident tickets
+type Problem struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Title       string         `json:"title" gorm:"not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	Status      ProblemStatus  `json:"status" gorm:"not null;default:'open';index"`
+	Priority    TicketPriority `json:"priority" gorm:"not null;default:'medium'"`
+	Version     int            `json:"version" gorm:"not null;default:1"`
+
+	// Root-cause analysis
+	Symptoms   string `json:"symptoms" gorm:"type:text"`
+	RootCause  string `json:"root_cause" gorm:"type:text"`
+	Workaround string `json:"workaround" gorm:"type:text"`
+	Resolution string `json:"resolution" gorm:"type:text"`
+
+	OwnerID     *uint `json:"owner_id"`
+	Owner       *User `json:"owner,omitempty" gorm:"foreignKey:OwnerID"`
+	CreatedByID uint  `json:"created_by_id" gorm:"not null"`
+	CreatedBy   User  `json:"created_by" gorm:"foreignKey:CreatedByID"`
+
+	// Number of incident tickets linked to the problem, filled in on read
+	TicketCount int `json:"ticket_count" gorm:"-"`
+
+	KnownErrorAt *time.Time `json:"known_error_at"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ChangeType classifies a change request by how it is approved
+type ChangeType string
+
+const (
+	StandardChange  ChangeType = "standard"  // pre-approved, routine change
+	NormalChange    ChangeType = "normal"    // approved by every CAB step in order
+	EmergencyChange ChangeType = "emergency" // approved by the first emergency CAB member to approve
+)
+
+// IsValid reports whether t is a known change type
+func (t ChangeType) IsValid() bool {
+	switch t {
+	case StandardChange, NormalChange, EmergencyChange:
+		return true
+	}
+	return false
+}
+
+// ChangeRisk is the assessed risk of a change
+type ChangeRisk string
+
+const (
+	LowRisk    ChangeRisk = "low"
+	MediumRisk ChangeRisk = "medium"
+	HighRisk   ChangeRisk = "high"
+)
+
+// IsValid reports whether r is a known risk level
+func (r ChangeRisk) IsValid() bool {
+	switch r {
+	case LowRisk, MediumRisk, HighRisk:
+		return true
+	}
+	return false
+}
+
+// ChangeStatus tracks a change request from draft to completion
+type ChangeStatus string
+
+const (
+	ChangeDraft           ChangeStatus = "draft"
+	ChangePendingApproval ChangeStatus = "pending_approval"
+	ChangeApproved        ChangeStatus = "approved"
+	ChangeRejected        ChangeStatus = "rejected"
+	ChangeInProgress      ChangeStatus = "in_progress"
+	ChangeCompleted       ChangeStatus = "completed"
+	ChangeRolledBack      ChangeStatus = "rolled_back"
+	ChangeCancelled       ChangeStatus = "cancelled"
+)
+
+// IsValid reports whether s is a known change status
+func (s ChangeStatus) IsValid() bool {
+	switch s {
+	case ChangeDraft, ChangePendingApproval, ChangeApproved, ChangeRejected,
+		ChangeInProgress, ChangeCompleted, ChangeRolledBack, ChangeCancelled:
+		return true
+	}
+	return false
+}
+
+// ChangeRequest is a planned change to one or more computers
+type ChangeRequest struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	Title       string       `json:"title" gorm:"not null"`
+	Description string       `json:"description" gorm:"type:text"`
+	Type        ChangeType   `json:"type" gorm:"not null;default:'normal'"`
+	Risk        ChangeRisk   `json:"risk" gorm:"not null;default:'medium'"`
+	Status      ChangeStatus `json:"status" gorm:"not null;default:'draft';index"`
+	Version     int          `json:"version" gorm:"not null;default:1"`
+
+	// Planned window during which the affected computers are worked on
+	PlannedStart time.Time `json:"planned_start" gorm:"not null;index"`
+	PlannedEnd   time.Time `json:"planned_end" gorm:"not null;index"`
+
+	ImplementationPlan string `json:"implementation_plan" gorm:"type:text"`
+	RollbackPlan       string `json:"rollback_plan" gorm:"type:text"`
+
+	RequestedByID uint  `json:"requested_by_id" gorm:"not null;index"`
+	RequestedBy   User  `json:"requested_by" gorm:"foreignKey:RequestedByID"`
+	ImplementerID *uint `json:"implementer_id"`
+	Implementer   *User `json:"implementer,omitempty" gorm:"foreignKey:ImplementerID"`
+
+	Computers []Computer       `json:"computers" gorm:"many2many:change_request_computers"`
+	Approvals []ChangeApproval `json:"approvals,omitempty" gorm:"foreignKey:ChangeRequestID"`
+
+	// Each submission starts a new approval round; earlier rounds are kept as history
+	ApprovalRound int `json:"approval_round" gorm:"not null;default:0"`
+
+	SubmittedAt *time.Time `json:"submitted_at"`
+	ApprovedAt  *time.Time `json:"approved_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ChangeApprovalStatus is the decision of one CAB approval step
+type ChangeApprovalStatus string
+
+const (
+	ApprovalPending  ChangeApprovalStatus = "pending"
+	ApprovalApproved ChangeApprovalStatus = "approved"
+	ApprovalRejected ChangeApprovalStatus = "rejected"
+	ApprovalSkipped  ChangeApprovalStatus = "skipped" // not needed once the change was decided
+)
+
+// ChangeApproval is one CAB approval step of a change request
+type ChangeApproval struct {
+	ID              uint                 `json:"id" gorm:"primaryKey"`
+	ChangeRequestID uint                 `json:"change_request_id" gorm:"not null;index"`
+	Round           int                  `json:"round" gorm:"not null"`
+	Step            int                  `json:"step" gorm:"not null"`
+	ApproverID      uint                 `json:"approver_id" gorm:"not null;index"`
+	Approver        User                 `json:"approver" gorm:"foreignKey:ApproverID"`
+	Status          ChangeApprovalStatus `json:"status" gorm:"not null;default:'pending'"`
+	Comment         string               `json:"comment" gorm:"type:text"`
+	DecidedAt       *time.Time           `json:"decided_at"`
+	CreatedAt       time.Time            `json:"created_at"`
+}
+
+// ArticleStatus is the publication state of a knowledge base article
+type ArticleStatus string
+
+const (
+	ArticleDraft     ArticleStatus = "draft"
+	ArticlePublished ArticleStatus = "published"
+	ArticleArchived  ArticleStatus = "archived"
+)
+
+// IsValid reports whether s is a known article status
+func (s ArticleStatus) IsValid() bool {
+	switch s {
+	case ArticleDraft, ArticlePublished, ArticleArchived:
+		return true
+	}
+	return false
+}
+
+// ArticleVisibility decides who may read a published article
+type ArticleVisibility string
+
+const (
+	PublicArticle   ArticleVisibility = "public"   // every user
+	InternalArticle ArticleVisibility = "internal" // agents and admins only
+)
+
+// IsValid reports whether v is a known article visibility
+func (v ArticleVisibility) IsValid() bool {
+	return v == PublicArticle || v == InternalArticle
+}
+
+// VisibleTo reports whether a user with role may see the article
+func (v ArticleVisibility) VisibleTo(role UserRole) bool {
+	return v == PublicArticle || role == AgentRole || role == AdminRole
+}
+
+// Article is a knowledge base article written in Markdown
+type Article struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	Title      string            `json:"title" gorm:"not null"`
+	Body       string            `json:"body" gorm:"type:text"`
+	BodyHTML   string            `json:"body_html" gorm:"type:text"` // sanitized HTML rendered from the Markdown body
+	Category   string            `json:"category" gorm:"index"`
+	Status     ArticleStatus     `json:"status" gorm:"not null;default:'draft';index"`
+	Visibility ArticleVisibility `json:"visibility" gorm:"not null;default:'public'"`
+	Version    int               `json:"version" gorm:"not null;default:1"`
+	ViewCount  int               `json:"view_count" gorm:"not null;default:0"`
+	// Tickets the article kept from being created, see TicketDeflection
+	DeflectionCount int `json:"deflection_count" gorm:"not null;default:0"`
+
+	AuthorID    uint  `json:"author_id" gorm:"not null"`
+	Author      User  `json:"author" gorm:"foreignKey:AuthorID"`
+	UpdatedByID *uint `json:"updated_by_id"`
+	UpdatedBy   *User `json:"updated_by,omitempty" gorm:"foreignKey:UpdatedByID"`
+
+	PublishedAt *time.Time     `json:"published_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// ArticleRevision is a snapshot of an article as it was at one version
+type ArticleRevision struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	ArticleID  uint              `json:"article_id" gorm:"not null;uniqueIndex:idx_revision_article_version"`
+	Version    int               `json:"version" gorm:"not null;uniqueIndex:idx_revision_article_version"`
+	Title      string            `json:"title" gorm:"not null"`
+	Body       string            `json:"body" gorm:"type:text"`
+	Category   string            `json:"category"`
+	Status     ArticleStatus     `json:"status" gorm:"not null"`
+	Visibility ArticleVisibility `json:"visibility" gorm:"not null"`
+
+	EditedByID uint `json:"edited_by_id" gorm:"not null"`
+	EditedBy   User `json:"edited_by" gorm:"foreignKey:EditedByID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArticleTicketLink records that an article solved a ticket
+type ArticleTicketLink struct {
+	ArticleID  uint      `json:"article_id" gorm:"primaryKey"`
+	TicketID   uint      `json:"ticket_id" gorm:"primaryKey;index"`
+	LinkedByID uint      `json:"linked_by_id" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TicketDeflection records that a user found the answer to a ticket they
+// were about to create in a suggested article or a similar resolved ticket,
+// so the ticket was never created. Exactly one of ArticleID and TicketID is set.
+type TicketDeflection struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	UserID      uint   `json:"user_id" gorm:"not null;index"`
+	User        User   `json:"user" gorm:"foreignKey:UserID"`
+	ArticleID   *uint  `json:"article_id" gorm:"index"`
+	TicketID    *uint  `json:"ticket_id" gorm:"index"`
+	Title       string `json:"title"` // title of the draft ticket
+	Description string `json:"description" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// PriorityMatrixEntry maps an impact and urgency combination to a priority
+type PriorityMatrixEntry struct {
+	Impact   TicketImpact   `json:"impact" gorm:"primaryKey"`
+	Urgency  TicketUrgency  `json:"urgency" gorm:"primaryKey"`
+	Priority TicketPriority `json:"priority" gorm:"not null"`
+}
+
+// PriorityOverride records an agent setting a priority other than the one
+// computed from the priority matrix
+type PriorityOverride struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	TicketID         uint           `json:"ticket_id" gorm:"not null;index"`
+	ComputedPriority TicketPriority `json:"computed_priority" gorm:"not null"`
+	Priority         TicketPriority `json:"priority" gorm:"not null"`
+	Reason           string         `json:"reason" gorm:"type:text;not null"`
+	OverriddenByID   uint           `json:"overridden_by_id" gorm:"not null;index"`
+	OverriddenBy     User           `json:"overridden_by" gorm:"foreignKey:OverriddenByID"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"index"`
+}
+
+// TicketStatusChange records a ticket moving from one status to another,
+// which is how reopened tickets are counted
+type TicketStatusChange struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	TicketID    uint         `json:"ticket_id" gorm:"not null;index"`
+	FromStatus  TicketStatus `json:"from_status" gorm:"not null"`
+	ToStatus    TicketStatus `json:"to_status" gorm:"not null"`
+	ChangedByID uint         `json:"changed_by_id" gorm:"not null"`
+	CreatedAt   time.Time    `json:"created_at" gorm:"index"`
+}
+
+// TicketKeyCounter holds the last sequence number issued for a ticket key
+// scope such as "HD-2026"
+type TicketKeyCounter struct {
+	Scope string `gorm:"primaryKey;size:40"`
+	Value int    `gorm:"not null"`
 }
 
 // Comment represents a comment on a ticket
 type Comment struct {
-	ID       uint   `json:"id" gorm:"primaryKey"`
-	Content  string `json:"content" gorm:"type:text;not null"`
-	IsPublic bool   `json:"is_public" gorm:"default:true"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Content     string `json:"content" gorm:"type:text;not null"`
+	ContentHTML string `json:"content_html" gorm:"type:text"` // sanitized HTML rendered from the Markdown content
+	IsPublic    bool   `json:"is_public" gorm:"not null"`
+	Version     int    `json:"version" gorm:"not null;default:1"`
 
 	// Relationships
 	TicketID uint   `json:"ticket_id" gorm:"not null"`
@@ -122,12 +543,363 @@ type Comment struct {
 	AuthorID uint `json:"author_id" gorm:"not null"`
 	Author   User `json:"author" gorm:"foreignKey:AuthorID"`
 
+	// Edit and delete tracking. Deleted comments are kept as tombstones for agents.
+	EditedAt    *time.Time `json:"edited_at"`
+	EditedByID  *uint      `json:"edited_by_id"`
+	DeletedByID *uint      `json:"deleted_by_id"`
+	DeletedBy   *User      `json:"deleted_by,omitempty" gorm:"foreignKey:DeletedByID"`
+
+	// Timestamps
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// CommentRevision is a snapshot of a comment as it was at one version
+type CommentRevision struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	CommentID   uint   `json:"comment_id" gorm:"not null;uniqueIndex:idx_revision_comment_version"`
+	Version     int    `json:"version" gorm:"not null;uniqueIndex:idx_revision_comment_version"`
+	Content     string `json:"content" gorm:"type:text;not null"`
+	ContentHTML string `json:"content_html" gorm:"type:text"`
+	IsPublic    bool   `json:"is_public" gorm:"not null"`
+
+	EditedByID uint `json:"edited_by_id" gorm:"not null"`
+	EditedBy   User `json:"edited_by" gorm:"foreignKey:EditedByID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Mention records that a comment @mentioned a user
+type Mention struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	// Relationships
+	CommentID uint     `json:"comment_id" gorm:"not null;uniqueIndex:idx_mention_comment_user"`
+	Comment   *Comment `json:"comment,omitempty" gorm:"foreignKey:CommentID"`
+
+	TicketID uint    `json:"ticket_id" gorm:"not null;index"`
+	Ticket   *Ticket `json:"ticket,omitempty" gorm:"foreignKey:TicketID"`
+
+	MentionedUserID uint  `json:"mentioned_user_id" gorm:"not null;uniqueIndex:idx_mention_comment_user;index"`
+	MentionedUser   *User `json:"mentioned_user,omitempty" gorm:"foreignKey:MentionedUserID"`
+
+	MentionedByID uint  `json:"mentioned_by_id" gorm:"not null"`
+	MentionedBy   *User `json:"mentioned_by,omitempty" gorm:"foreignKey:MentionedByID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TicketWatcher subscribes a user to updates on a ticket
+type TicketWatcher struct {
+	ID uint `json:"id" gorm:"primaryKey"`
+
+	TicketID uint `json:"ticket_id" gorm:"not null;uniqueIndex:idx_watcher_ticket_user"`
+	UserID   uint `json:"user_id" gorm:"not null;uniqueIndex:idx_watcher_ticket_user;index"`
+	User     User `json:"user" gorm:"foreignKey:UserID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notification is an in-app message for a user about something that happened on a ticket
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index:idx_notification_user_read"`
+	EventType string     `json:"event_type" gorm:"not null"`
+	TicketID  *uint      `json:"ticket_id"`
+	ActorID   *uint      `json:"actor_id"`
+	Title     string     `json:"title" gorm:"not null"`
+	Body      string     `json:"body" gorm:"type:text"`
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notification_user_read"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+}
+
+// NotificationPreference controls how a user is notified about one event type.
+// Event types without a stored preference use the defaults.
+type NotificationPreference struct {
+	ID        uint   `json:"-" gorm:"primaryKey"`
+	UserID    uint   `json:"-" gorm:"not null;uniqueIndex:idx_notification_pref_user_event"`
+	EventType string `json:"event_type" gorm:"not null;uniqueIndex:idx_notification_pref_user_event"`
+	InApp     bool   `json:"in_app" gorm:"not null"`
+	Email     bool   `json:"email" gorm:"not null"`
+}
+
+// Attachment is a file uploaded to a ticket
+type Attachment struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	FileName    string `json:"file_name" gorm:"not null"`
+	ContentType string `json:"content_type" gorm:"not null"`
+	Size        int64  `json:"size" gorm:"not null"`
+	StoragePath string `json:"-" gorm:"not null"`
+
+	// Relationships
+	TicketID uint   `json:"ticket_id" gorm:"not null;index"`
+	Ticket   Ticket `json:"-" gorm:"foreignKey:TicketID"`
+
+	UploadedByID uint `json:"uploaded_by_id" gorm:"not null"`
+	UploadedBy   User `json:"uploaded_by" gorm:"foreignKey:UploadedByID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsImage reports whether the attachment can be embedded as an inline image
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// Tag is a free-form label that can be attached to tickets
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkLog represents time an agent spent working on a ticket
+type WorkLog struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	DurationMinutes int       `json:"duration_minutes" gorm:"not null"`
+	Billable        bool      `json:"billable" gorm:"not null"`
+	Note            string    `json:"note" gorm:"type:text"`
+	WorkDate        time.Time `json:"work_date" gorm:"type:date;not null;index"`
+
+	// Relationships
+	TicketID uint   `json:"ticket_id" gorm:"not null;index"`
+	Ticket   Ticket `json:"-" gorm:"foreignKey:TicketID"`
+
+	AgentID uint `json:"agent_id" gorm:"not null;index"`
+	Agent   User `json:"agent" gorm:"foreignKey:AgentID"`
+
 	// Timestamps
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// WorkTimer represents a running timer an agent started on a ticket.
+// Stopping the timer converts it into a WorkLog.
+type WorkTimer struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Billable  bool      `json:"billable" gorm:"not null"`
+	Note      string    `json:"note" gorm:"type:text"`
+	StartedAt time.Time `json:"started_at" gorm:"not null"`
+
+	// Relationships
+	TicketID uint   `json:"ticket_id" gorm:"not null;uniqueIndex:idx_work_timers_ticket_agent"`
+	Ticket   Ticket `json:"-" gorm:"foreignKey:TicketID"`
+
+	AgentID uint `json:"agent_id" gorm:"not null;uniqueIndex:idx_work_timers_ticket_agent"`
+	Agent   User `json:"-" gorm:"foreignKey:AgentID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BulkJobStatus defines the state of a background bulk operation
+type BulkJobStatus string
+
+const (
+	BulkJobQueued    BulkJobStatus = "queued"
+	BulkJobRunning   BulkJobStatus = "running"
+	BulkJobCompleted BulkJobStatus = "completed"
+	BulkJobFailed    BulkJobStatus = "failed"
+)
+
+// BulkTicketChanges describes the changes a bulk operation applies to every ticket.
+// Nil or empty fields are left untouched.
+type BulkTicketChanges struct {
+	Status     *TicketStatus   `json:"status,omitempty"`
+	Priority   *TicketPriority `json:"priority,omitempty"`
+	AssigneeID *uint           `json:"assignee_id,omitempty"`
+	Unassign   bool            `json:"unassign,omitempty"`
+	AddTags    []string        `json:"add_tags,omitempty"`
+	RemoveTags []string        `json:"remove_tags,omitempty"`
+	Comment    *BulkComment    `json:"comment,omitempty"`
+}
+
+// BulkComment is a comment added to every ticket in a bulk operation
+type BulkComment struct {
+	Content  string `json:"content"`
+	IsPublic bool   `json:"is_public"`
+}
+
+// BulkJob tracks a bulk ticket operation that runs in the background
+type BulkJob struct {
+	ID        uint          `json:"id" gorm:"primaryKey"`
+	Status    BulkJobStatus `json:"status" gorm:"not null;default:'queued';index"`
+	Changes   string        `json:"-" gorm:"type:text;not null"` // JSON encoded BulkTicketChanges
+	TicketIDs string        `json:"-" gorm:"type:text;not null"` // JSON encoded list of ticket IDs
+	Error     string        `json:"error,omitempty" gorm:"type:text"`
+
+	// Progress counters
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+
+	// Relationships
+	RequestedByID uint `json:"requested_by_id" gorm:"not null"`
+	RequestedBy   User `json:"-" gorm:"foreignKey:RequestedByID"`
+
+	// Timestamps
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// BulkJobResult records the outcome of a bulk operation for a single ticket
+type BulkJobResult struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	JobID     uint      `json:"-" gorm:"not null;uniqueIndex:idx_bulk_job_results_job_ticket"`
+	TicketID  uint      `json:"ticket_id" gorm:"not null;uniqueIndex:idx_bulk_job_results_job_ticket"`
+	Success   bool      `json:"success" gorm:"not null"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Webhook is an admin-managed subscription that POSTs events to an external URL
+type Webhook struct {
+	ID         uint     `json:"id" gorm:"primaryKey"`
+	Name       string   `json:"name" gorm:"not null"`
+	URL        string   `json:"url" gorm:"not null"`
+	Secret     string   `json:"-" gorm:"not null"` // HMAC-SHA256 signing key
+	EventTypes []string `json:"event_types" gorm:"type:text;serializer:json"`
+	IsActive   bool     `json:"is_active" gorm:"not null"` // no default, or GORM would insert false as the default
+
+	CreatedByID uint `json:"created_by_id" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook wants events of the given type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType || t == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus defines the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead is a delivery that ran out of retries
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event sent to a webhook, together with the outcome
+// of its latest attempt
+type WebhookDelivery struct {
+	ID            uint                  `json:"id" gorm:"primaryKey"`
+	WebhookID     uint                  `json:"webhook_id" gorm:"not null;index"`
+	EventID       uint64                `json:"event_id"`
+	EventType     string                `json:"event_type" gorm:"not null"`
+	Payload       string                `json:"payload" gorm:"type:text;not null"`
+	Status        WebhookDeliveryStatus `json:"status" gorm:"not null;default:'pending';index:idx_webhook_deliveries_due"`
+	Attempts      int                   `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due"`
+	LastAttemptAt *time.Time            `json:"last_attempt_at"`
+	ResponseCode  *int                  `json:"response_code"`
+	ResponseBody  string                `json:"response_body" gorm:"type:text"` // truncated
+	DurationMs    int64                 `json:"duration_ms"`
+	Error         string                `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time             `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+// ScheduledReportKind selects the report a scheduled report delivers
+type ScheduledReportKind string
+
+const (
+	TicketsReport ScheduledReportKind = "tickets"
+	AgentsReport  ScheduledReportKind = "agents"
+	BacklogReport ScheduledReportKind = "backlog"
+)
+
+// IsValid reports whether k is a known scheduled report kind
+func (k ScheduledReportKind) IsValid() bool {
+	switch k {
+	case TicketsReport, AgentsReport, BacklogReport:
+		return true
+	}
+	return false
+}
+
+// ReportFormat is a format a scheduled report is rendered in
+type ReportFormat string
+
+const (
+	// HTMLReportFormat is sent as the body of the email
+	HTMLReportFormat ReportFormat = "html"
+	// CSVReportFormat is sent as an attachment
+	CSVReportFormat ReportFormat = "csv"
+)
+
+// IsValid reports whether f is a known report format
+func (f ReportFormat) IsValid() bool {
+	return f == HTMLReportFormat || f == CSVReportFormat
+}
+
+// ScheduledReportParameters narrow down what a scheduled report covers.
+// RangeDays is the number of complete days before the run that are reported
+// on; the backlog report is a snapshot and ignores it.
+type ScheduledReportParameters struct {
+	RangeDays  int            `json:"range_days,omitempty"`
+	Interval   string         `json:"interval,omitempty"` // tickets report only
+	Category   string         `json:"category,omitempty"`
+	Priority   TicketPriority `json:"priority,omitempty"`
+	AssigneeID *uint          `json:"assignee_id,omitempty"`
+	Department string         `json:"department,omitempty"`
+}
+
+// ScheduledReport is a saved report that is emailed to its recipients on a
+// cron-style schedule
+type ScheduledReport struct {
+	ID         uint                      `json:"id" gorm:"primaryKey"`
+	Name       string                    `json:"name" gorm:"not null"`
+	Kind       ScheduledReportKind       `json:"kind" gorm:"not null"`
+	Parameters ScheduledReportParameters `json:"parameters" gorm:"type:text;serializer:json"`
+	Formats    []ReportFormat            `json:"formats" gorm:"type:text;serializer:json"`
+	Recipients []string                  `json:"recipients" gorm:"type:text;serializer:json"`
+	Schedule   string                    `json:"schedule" gorm:"not null"`  // five-field cron expression
+	TimeZone   string                    `json:"time_zone" gorm:"not null"` // IANA zone the schedule and date ranges use
+	IsActive   bool                      `json:"is_active" gorm:"not null"` // no default, or GORM would insert false as the default
+	NextRunAt  *time.Time                `json:"next_run_at" gorm:"index"`  // nil when inactive
+	LastRunAt  *time.Time                `json:"last_run_at"`
+
+	CreatedByID uint `json:"created_by_id" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReportRunStatus defines the state of a scheduled report run
+type ReportRunStatus string
+
+const (
+	ReportRunRunning   ReportRunStatus = "running"
+	ReportRunSucceeded ReportRunStatus = "succeeded"
+	ReportRunFailed    ReportRunStatus = "failed"
+)
+
+// ReportRun is one delivery of a scheduled report, either on its schedule or
+// started by hand
+type ReportRun struct {
+	ID                uint            `json:"id" gorm:"primaryKey"`
+	ScheduledReportID uint            `json:"scheduled_report_id" gorm:"not null;index"`
+	Manual            bool            `json:"manual" gorm:"not null;default:false"`
+	TriggeredByID     *uint           `json:"triggered_by_id"`
+	Status            ReportRunStatus `json:"status" gorm:"not null"`
+	Recipients        int             `json:"recipients"`
+	Error             string          `json:"error,omitempty" gorm:"type:text"`
+	StartedAt         time.Time       `json:"started_at" gorm:"index"`
+	FinishedAt        *time.Time      `json:"finished_at"`
+}
+
 // SLA represents Service Level Agreement configuration
 type SLA struct {
 	ID                  uint           `json:"id" gorm:"primaryKey"`
@@ -152,6 +924,15 @@ const (
 	RetiredStatus     ComputerStatus = "retired"
 )
 
+// IsValid reports whether s is a known computer status
+func (s ComputerStatus) IsValid() bool {
+	switch s {
+	case ActiveStatus, InactiveStatus, MaintenanceStatus, RetiredStatus:
+		return true
+	}
+	return false
+}
+
 // Computer represents a computer asset in the organization
 type Computer struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -162,6 +943,7 @@ type Computer struct {
 	Model        string         `json:"model"`                    // e.g., Latitude 5420, MacBook Pro
 	SerialNumber string         `json:"serial_number" gorm:"uniqueIndex"`
 	Status       ComputerStatus `json:"status" gorm:"not null;default:'active';index"`
+	Version      int            `json:"version" gorm:"not null;default:1"`
 
 	// Hardware Specifications
 	CPU     string `json:"cpu"`     // e.g., Intel Core i7-11800H
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"helpdesk-backend/internal/domain"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	TicketCreated  Type = "ticket.created"
+	TicketUpdated  Type = "ticket.updated"
+	TicketAssigned Type = "ticket.assigned"
+	CommentCreated Type = "comment.created"
+	MentionCreated Type = "mention.created"
+	SLAWarning     Type = "sla.warning"
+)
+
+// Types lists every event type that is published
+var Types = []Type{TicketCreated, TicketUpdated, TicketAssigned, CommentCreated, MentionCreated, SLAWarning}
+
+// Event is a change published to subscribers. TicketID, RequesterID and
+// Internal are used to decide who may receive it.
+type Event struct {
+	ID          uint64      `json:"id"`
+	Type        Type        `json:"type"`
+	TicketID    uint        `json:"ticket_id"`
+	RequesterID uint        `json:"-"`
+	ActorID     uint        `json:"actor_id,omitempty"`
+	Internal    bool        `json:"-"`
+	Data        interface{} `json:"data"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+}
+
+// VisibleTo reports whether the viewer may receive the event. Staff receive
+// everything; end users only public events about tickets they requested.
+func (e Event) VisibleTo(viewer domain.Viewer) bool {
+	if viewer.IsStaff() {
+		return true
+	}
+	return !e.Internal && e.RequesterID == viewer.UserID
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	ch     chan Event
+	broker *Broker
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is closed or falls too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close stops delivery to the subscription
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans out published events to subscribers and keeps the most recent
+// ones so that reconnecting clients can resume where they left off.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	history     []Event // ring buffer, oldest event at start
+	start       int
+	size        int
+	nextID      uint64
+}
+
+// subscriberBuffer is how many undelivered events a subscriber may queue
+// before it is disconnected
+const subscriberBuffer = 64
+
+// NewBroker creates a broker remembering the last historySize events
+func NewBroker(historySize int) *Broker {
+	if historySize < 1 {
+		historySize = 1
+	}
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+		history:     make([]Event, historySize),
+		// IDs start from the boot time so that IDs from before a restart are
+		// always older than the history and are detected as a gap
+		nextID: uint64(time.Now().UnixMilli()) * 1000,
+	}
+}
+
+// Publish assigns the event an ID, stores it and delivers it to every
+// subscriber. Subscribers that cannot keep up are disconnected; they can
+// reconnect and resume from their last event ID.
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	if b.size < len(b.history) {
+		b.history[(b.start+b.size)%len(b.history)] = event
+		b.size++
+	} else {
+		b.history[b.start] = event
+		b.start = (b.start + 1) % len(b.history)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			b.removeLocked(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber. When lastID is non-zero the events after
+// it are returned for replay; complete is false when some of them are no
+// longer in the history and the client has to reload its state.
+func (b *Broker) Subscribe(lastID uint64) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{ch: make(chan Event, subscriberBuffer), broker: b}
+	b.subscribers[sub] = struct{}{}
+
+	if lastID == 0 {
+		return sub, nil, true
+	}
+
+	switch {
+	case lastID == b.nextID:
+		complete = true
+	case lastID > b.nextID:
+		// The ID was issued before a restart
+		complete = false
+	default:
+		complete = b.size > 0 && lastID+1 >= b.at(0).ID
+	}
+	for i := 0; i < b.size; i++ {
+		if event := b.at(i); event.ID > lastID {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, complete
+}
+
+// at returns the i-th oldest event in the history
+func (b *Broker) at(i int) Event {
+	return b.history[(b.start+i)%len(b.history)]
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(sub)
+}
+
+func (b *Broker) removeLocked(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,132 @@
+package events
+
+import (
+	"time"
+
+	"helpdesk-backend/internal/domain"
+)
+
+// TicketData is the ticket snapshot carried by ticket events
+type TicketData struct {
+	ID          uint                  `json:"id"`
+	Key         string                `json:"key"`
+	Title       string                `json:"title"`
+	Status      domain.TicketStatus   `json:"status"`
+	Impact      domain.TicketImpact   `json:"impact"`
+	Urgency     domain.TicketUrgency  `json:"urgency"`
+	Priority    domain.TicketPriority `json:"priority"`
+	Category    string                `json:"category"`
+	RequesterID uint                  `json:"requester_id"`
+	AssigneeID  *uint                 `json:"assignee_id"`
+	SLABreachAt *time.Time            `json:"sla_breach_at"`
+	Version     int                   `json:"version"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// CommentData is the comment snapshot carried by comment events
+type CommentData struct {
+	ID          uint      `json:"id"`
+	TicketID    uint      `json:"ticket_id"`
+	TicketKey   string    `json:"ticket_key"`
+	TicketTitle string    `json:"ticket_title"`
+	AuthorID    uint      `json:"author_id"`
+	ContentHTML string    `json:"content_html"`
+	IsPublic    bool      `json:"is_public"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// MentionedUserIDs are notified of the comment through mention events
+	MentionedUserIDs []uint `json:"mentioned_user_ids,omitempty"`
+}
+
+// MentionData is carried by mention events
+type MentionData struct {
+	CommentID       uint   `json:"comment_id"`
+	TicketID        uint   `json:"ticket_id"`
+	TicketKey       string `json:"ticket_key"`
+	TicketTitle     string `json:"ticket_title"`
+	MentionedUserID uint   `json:"mentioned_user_id"`
+	MentionedByID   uint   `json:"mentioned_by_id"`
+	Content         string `json:"content"`
+}
+
+// NewTicketEvent builds an event about a ticket
+func NewTicketEvent(eventType Type, ticket *domain.Ticket, actorID uint) Event {
+	return Event{
+		Type:        eventType,
+		TicketID:    ticket.ID,
+		RequesterID: ticket.RequesterID,
+		ActorID:     actorID,
+		Data: TicketData{
+			ID:          ticket.ID,
+			Key:         ticketKey(ticket),
+			Title:       ticket.Title,
+			Status:      ticket.Status,
+			Impact:      ticket.Impact,
+			Urgency:     ticket.Urgency,
+			Priority:    ticket.Priority,
+			Category:    ticket.Category,
+			RequesterID: ticket.RequesterID,
+			AssigneeID:  ticket.AssigneeID,
+			SLABreachAt: ticket.SLABreachAt,
+			Version:     ticket.Version,
+			UpdatedAt:   ticket.UpdatedAt,
+		},
+	}
+}
+
+// NewCommentEvent builds a comment.created event. Internal notes are only
+// delivered to staff. mentions are the mentions the comment created.
+func NewCommentEvent(comment *domain.Comment, ticket *domain.Ticket, mentions ...domain.Mention) Event {
+	var mentioned []uint
+	for _, mention := range mentions {
+		mentioned = append(mentioned, mention.MentionedUserID)
+	}
+
+	return Event{
+		Type:        CommentCreated,
+		TicketID:    ticket.ID,
+		RequesterID: ticket.RequesterID,
+		ActorID:     comment.AuthorID,
+		Internal:    !comment.IsPublic,
+		Data: CommentData{
+			ID:          comment.ID,
+			TicketID:    comment.TicketID,
+			TicketKey:   ticketKey(ticket),
+			TicketTitle: ticket.Title,
+			AuthorID:    comment.AuthorID,
+			ContentHTML: comment.ContentHTML,
+			IsPublic:    comment.IsPublic,
+			CreatedAt:   comment.CreatedAt,
+
+			MentionedUserIDs: mentioned,
+		},
+	}
+}
+
+// NewMentionEvent builds a mention.created event for one mentioned user.
+// Mentions in internal notes are only delivered to staff.
+func NewMentionEvent(mention domain.Mention, comment *domain.Comment, ticket *domain.Ticket) Event {
+	return Event{
+		Type:        MentionCreated,
+		TicketID:    ticket.ID,
+		RequesterID: ticket.RequesterID,
+		ActorID:     mention.MentionedByID,
+		Internal:    !comment.IsPublic,
+		Data: MentionData{
+			CommentID:       mention.CommentID,
+			TicketID:        mention.TicketID,
+			TicketKey:       ticketKey(ticket),
+			TicketTitle:     ticket.Title,
+			MentionedUserID: mention.MentionedUserID,
+			MentionedByID:   mention.MentionedByID,
+			Content:         comment.Content,
+		},
+	}
+}
+
+func ticketKey(ticket *domain.Ticket) string {
+	if ticket.Key == nil {
+		return ""
+	}
+	return *ticket.Key
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger logs database queries through the context logger. Failed queries
+// are errors, queries slower than the threshold are warnings and all other
+// queries are debug lines. Query parameters are never logged, as they may
+// hold passwords and tokens.
+type GormLogger struct {
+	slowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{slowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	logger := FromContext(ctx)
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error &&
+		!errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "Database query failed", queryAttrs(sql, rows, elapsed, "error", err)...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "Slow database query", queryAttrs(sql, rows, elapsed)...)
+	case l.level >= gormlogger.Info && logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		logger.DebugContext(ctx, "Database query", queryAttrs(sql, rows, elapsed)...)
+	}
+}
+
+// ParamsFilter drops the query parameters, leaving their placeholders in the SQL
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func queryAttrs(sql string, rows int64, elapsed time.Duration, extra ...any) []any {
+	attrs := []any{
+		"sql", sql,
+		"rows", rows,
+		"duration_ms", float64(elapsed.Microseconds()) / 1000,
+	}
+	return append(attrs, extra...)
+}
@@ -0,0 +1,82 @@
+// Package logging configures the structured logger of the server and carries
+// request-scoped loggers through contexts.
+//
+// Code that has a context logs through FromContext, so that its lines carry
+// the request ID, route and user of the request, or the worker they came from.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New returns a logger writing to w at level ("debug", "info", "warn" or
+// "error") in format ("json" or "text"). Secrets and tokens are redacted from
+// every line. Invalid settings fall back to info and json, and are reported
+// in the error next to the usable logger.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var problems []string
+
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	options := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json", "":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		problems = append(problems, fmt.Sprintf("unknown log format %q, using json", format))
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	logger := slog.New(redactingHandler{handler})
+	if len(problems) > 0 {
+		return logger, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return logger, nil
+}
+
+// ParseLevel parses a log level name. Unknown names return info with an error.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q, using info", level)
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx that carries logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds the given attributes to every line
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
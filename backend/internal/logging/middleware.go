@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request to and from clients and proxies
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from clients
+const maxRequestIDLength = 128
+
+// Middleware gives every request an ID, taken from the X-Request-ID header when
+// the client sent a usable one, and echoes it in the response. The request
+// context carries a logger that adds the request ID, method and route to every
+// line, and each request is logged once it completes.
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestLogger := logger.With("request_id", id, "method", c.Request.Method, "route", route)
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		// Later middleware may have added attributes such as the user ID
+		ctx := c.Request.Context()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		// The query is left out as it may hold an access token
+		FromContext(ctx).LogAttrs(ctx, level, "Request completed",
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()))
+	}
+}
+
+// Recovery turns panics in handlers into 500 responses and logs them with
+// the request's logger
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				ctx := c.Request.Context()
+				FromContext(ctx).ErrorContext(ctx, "Panic while handling request",
+					"panic", recovered,
+					"stack", string(debug.Stack()))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+		}()
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so that they
+// cannot break log lines or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-backend/internal/config"
+)
+
+// Message is an email with a plain text body and, optionally, an HTML
+// alternative and attachments
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    string
+
+	Attachments []Attachment
+
+	// ThreadID groups related messages, such as all mail about one ticket.
+	// It is sent in the In-Reply-To and References headers.
+	ThreadID string
+}
+
+// Attachment is a file attached to a message
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer sends email through the SMTP server from the configuration
+type Mailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     mail.Address
+}
+
+func NewMailer(cfg *config.Config) *Mailer {
+	return &Mailer{
+		host:     cfg.SMTPHost,
+		port:     cfg.SMTPPort,
+		username: cfg.SMTPUser,
+		password: cfg.SMTPPass,
+		from:     mail.Address{Name: cfg.SMTPFromName, Address: cfg.SMTPFromEmail},
+	}
+}
+
+// Enabled reports whether an SMTP server is configured
+func (m *Mailer) Enabled() bool {
+	return m.host != "" && m.from.Address != ""
+}
+
+// Send delivers the message to all recipients
+func (m *Mailer) Send(msg Message) error {
+	if !m.Enabled() {
+		return fmt.Errorf("smtp is not configured")
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := net.JoinHostPort(m.host, m.port)
+	return smtp.SendMail(addr, auth, m.from.Address, msg.To, m.build(msg))
+}
+
+// build renders the message headers and body in RFC 5322 format
+func (m *Mailer) build(msg Message) []byte {
+	var buf bytes.Buffer
+
+	writeHeader := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	writeHeader("From", m.from.String())
+	writeHeader("To", strings.Join(msg.To, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", m.messageID(randomToken()))
+	if msg.ThreadID != "" {
+		thread := m.messageID(msg.ThreadID)
+		writeHeader("In-Reply-To", thread)
+		writeHeader("References", thread)
+	}
+	writeHeader("MIME-Version", "1.0")
+
+	if msg.HTML == "" && len(msg.Attachments) == 0 {
+		writeHeader("Content-Type", "text/plain; charset=utf-8")
+		writeHeader("Content-Transfer-Encoding", "8bit")
+		buf.WriteString("\r\n")
+		buf.WriteString(crlf(msg.Body))
+		return buf.Bytes()
+	}
+
+	// multipart/mixed holds the text, or the text and HTML alternatives,
+	// followed by the attachments
+	mixed := multipart.NewWriter(&buf)
+	writeHeader("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+
+	if msg.HTML == "" {
+		writeTextPart(mixed, "text/plain", msg.Body)
+	} else {
+		var alternatives bytes.Buffer
+		alternative := multipart.NewWriter(&alternatives)
+		writeTextPart(alternative, "text/plain", msg.Body)
+		writeTextPart(alternative, "text/html", msg.HTML)
+		alternative.Close()
+
+		part, _ := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+		})
+		part.Write(alternatives.Bytes())
+	}
+
+	for _, attachment := range msg.Attachments {
+		part, _ := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(attachment.ContentType, map[string]string{"name": attachment.FileName})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		writeBase64(part, attachment.Data)
+	}
+	mixed.Close()
+	return buf.Bytes()
+}
+
+// writeTextPart writes a UTF-8 text part with CRLF line endings
+func writeTextPart(w *multipart.Writer, contentType, text string) {
+	part, _ := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	io.WriteString(part, crlf(text))
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// crlf converts line endings to the CRLF that SMTP requires
+func crlf(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// messageID formats id as a message ID in the sender's domain
+func (m *Mailer) messageID(id string) string {
+	domain := "localhost"
+	if _, host, ok := strings.Cut(m.from.Address, "@"); ok && host != "" {
+		domain = host
+	}
+	return "<" + id + "@" + domain + ">"
+}
+
+func randomToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,81 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// ImageResolver maps the destination of a Markdown image to the URL of an
+// uploaded attachment. Images it cannot resolve are rendered as plain links so
+// that comments never embed content from arbitrary hosts.
+type ImageResolver func(destination string) (url string, ok bool)
+
+var (
+	// Raw HTML in the source is not rendered since goldmark's unsafe mode stays off
+	md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	policy = newPolicy()
+)
+
+// newPolicy builds the allowlist applied to every rendered document
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	// GFM task list items
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render converts Markdown to sanitized HTML. resolve may be nil, in which case
+// every image is rendered as a link.
+func Render(source string, resolve ImageResolver) (string, error) {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	rewriteImages(doc, resolve)
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// rewriteImages points images at their attachments and turns the rest into links
+func rewriteImages(doc ast.Node, resolve ImageResolver) {
+	var images []*ast.Image
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			images = append(images, img)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, img := range images {
+		if resolve != nil {
+			if url, ok := resolve(string(img.Destination)); ok {
+				img.Destination = []byte(url)
+				continue
+			}
+		}
+
+		link := ast.NewLink()
+		link.Destination = img.Destination
+		link.Title = img.Title
+		for child := img.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			link.AppendChild(link, child)
+			child = next
+		}
+		img.Parent().ReplaceChild(img.Parent(), img, link)
+	}
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBStats collects the connection pool statistics of db
+func DBStats(db *sql.DB) Collector {
+	return CollectorFunc(func(ctx context.Context, w *Writer) error {
+		stats := db.Stats()
+
+		w.Gauge("helpdesk_db_connections_max_open", "Maximum number of open database connections, 0 for unlimited.",
+			NewSample(float64(stats.MaxOpenConnections)))
+		w.Gauge("helpdesk_db_connections", "Open database connections by state.",
+			NewSample(float64(stats.InUse), "state", "in_use"),
+			NewSample(float64(stats.Idle), "state", "idle"))
+		w.Counter("helpdesk_db_wait_total", "Connections waited for because the pool was exhausted.",
+			NewSample(float64(stats.WaitCount)))
+		w.Counter("helpdesk_db_wait_duration_seconds_total", "Time spent waiting for a database connection.",
+			NewSample(stats.WaitDuration.Seconds()))
+		w.Counter("helpdesk_db_connections_closed_total", "Database connections closed by the pool, by reason.",
+			NewSample(float64(stats.MaxIdleClosed), "reason", "max_idle"),
+			NewSample(float64(stats.MaxIdleTimeClosed), "reason", "max_idle_time"),
+			NewSample(float64(stats.MaxLifetimeClosed), "reason", "max_lifetime"))
+		return nil
+	})
+}
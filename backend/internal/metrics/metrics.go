@@ -0,0 +1,252 @@
+// Package metrics collects server metrics and exposes them in the Prometheus
+// text exposition format.
+//
+// HTTP requests are recorded by the registry's middleware. Everything else is
+// read at scrape time from the collectors registered with it.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"helpdesk-backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestBuckets are the upper bounds in seconds of the request latency histogram
+var requestBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collectTimeout bounds the time collectors may spend on one scrape
+const collectTimeout = 10 * time.Second
+
+// Collector writes metrics that are read at scrape time
+type Collector interface {
+	Collect(ctx context.Context, w *Writer) error
+}
+
+// CollectorFunc adapts a function to the Collector interface
+type CollectorFunc func(ctx context.Context, w *Writer) error
+
+func (f CollectorFunc) Collect(ctx context.Context, w *Writer) error {
+	return f(ctx, w)
+}
+
+// requestKey identifies a series of the HTTP metrics. Route is the route
+// template rather than the path, so that IDs in paths do not create series.
+type requestKey struct {
+	Method string
+	Route  string
+	Status string
+}
+
+type requestStats struct {
+	buckets []uint64 // cumulative counts per requestBuckets bound
+	count   uint64
+	sum     float64
+}
+
+// Registry records HTTP requests and holds the collectors of other metrics
+type Registry struct {
+	mu         sync.Mutex
+	requests   map[requestKey]*requestStats
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{requests: make(map[requestKey]*requestStats)}
+}
+
+// Register adds a collector that is called on every scrape
+func (r *Registry) Register(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collector)
+}
+
+// Middleware records the count and latency of every request by method, route
+// and status code
+func (r *Registry) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		r.observe(requestKey{
+			Method: normalizeMethod(c.Request.Method),
+			Route:  route,
+			Status: strconv.Itoa(c.Writer.Status()),
+		}, time.Since(start).Seconds())
+	}
+}
+
+func (r *Registry) observe(key requestKey, seconds float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.requests[key]
+	if !ok {
+		stats = &requestStats{buckets: make([]uint64, len(requestBuckets))}
+		r.requests[key] = stats
+	}
+	for i, bound := range requestBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
+	}
+	stats.count++
+	stats.sum += seconds
+}
+
+// Handler serves all metrics. A collector that fails is logged and skipped so
+// that the others are still reported.
+func (r *Registry) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &Writer{}
+		r.writeRequests(w)
+
+		r.mu.Lock()
+		collectors := append([]Collector(nil), r.collectors...)
+		r.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), collectTimeout)
+		defer cancel()
+		for _, collector := range collectors {
+			if err := collector.Collect(ctx, w); err != nil {
+				logging.FromContext(ctx).Warn("Failed to collect metrics", "error", err)
+			}
+		}
+
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", w.buf.Bytes())
+	}
+}
+
+func (r *Registry) writeRequests(w *Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Route != keys[j].Route {
+			return keys[i].Route < keys[j].Route
+		}
+		if keys[i].Method != keys[j].Method {
+			return keys[i].Method < keys[j].Method
+		}
+		return keys[i].Status < keys[j].Status
+	})
+
+	counts := make([]Sample, 0, len(keys))
+	for _, key := range keys {
+		counts = append(counts, NewSample(float64(r.requests[key].count), "method", key.Method, "route", key.Route, "status", key.Status))
+	}
+	w.Counter("helpdesk_http_requests_total", "HTTP requests by method, route and status code.", counts...)
+
+	w.header("helpdesk_http_request_duration_seconds", "HTTP request latency by method, route and status code.", "histogram")
+	for _, key := range keys {
+		stats := r.requests[key]
+		labels := []string{"method", key.Method, "route", key.Route, "status", key.Status}
+		for i, bound := range requestBuckets {
+			w.sample("helpdesk_http_request_duration_seconds_bucket", NewSample(float64(stats.buckets[i]), append(labels, "le", formatValue(bound))...))
+		}
+		w.sample("helpdesk_http_request_duration_seconds_bucket", NewSample(float64(stats.count), append(labels, "le", "+Inf")...))
+		w.sample("helpdesk_http_request_duration_seconds_sum", NewSample(stats.sum, labels...))
+		w.sample("helpdesk_http_request_duration_seconds_count", NewSample(float64(stats.count), labels...))
+	}
+}
+
+// normalizeMethod keeps arbitrary request methods out of the label values
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// Sample is one value of a metric with its labels
+type Sample struct {
+	Labels []string // name, value pairs
+	Value  float64
+}
+
+// NewSample returns a sample with labels given as name, value pairs
+func NewSample(value float64, labels ...string) Sample {
+	return Sample{Labels: labels, Value: value}
+}
+
+// Writer writes metric families in the text exposition format
+type Writer struct {
+	buf bytes.Buffer
+}
+
+// Gauge writes a gauge with its samples
+func (w *Writer) Gauge(name, help string, samples ...Sample) {
+	w.header(name, help, "gauge")
+	for _, sample := range samples {
+		w.sample(name, sample)
+	}
+}
+
+// Counter writes a counter with its samples. The name should end in _total.
+func (w *Writer) Counter(name, help string, samples ...Sample) {
+	w.header(name, help, "counter")
+	for _, sample := range samples {
+		w.sample(name, sample)
+	}
+}
+
+func (w *Writer) header(name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (w *Writer) sample(name string, sample Sample) {
+	w.buf.WriteString(name)
+	if len(sample.Labels) > 0 {
+		w.buf.WriteByte('{')
+		for i := 0; i+1 < len(sample.Labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&w.buf, "%s=\"%s\"", sample.Labels[i], escapeLabel(sample.Labels[i+1]))
+		}
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatValue(sample.Value))
+	w.buf.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// articleSearchVector is the full-text document of an article, weighting the
+// title above the body. It must match the expression of the search index.
+const articleSearchVector = "(setweight(to_tsvector('english', coalesce(articles.title, '')), 'A') || " +
+	"setweight(to_tsvector('english', coalesce(articles.body, '')), 'B'))"
+
+type ArticleRepository struct {
+	db *gorm.DB
+}
+
+func NewArticleRepository(db *gorm.DB) *ArticleRepository {
+	return &ArticleRepository{db: db}
+}
+
+// ArticleFilter narrows the articles returned by List
+type ArticleFilter struct {
+	Query      string // full-text search; results are ranked by relevance
+	Category   string
+	Status     domain.ArticleStatus
+	Visibility domain.ArticleVisibility
+}
+
+// ArticleCategory is a category with the number of articles in it
+type ArticleCategory struct {
+	Category string `json:"category"`
+	Articles int    `json:"articles"`
+}
+
+// EnsureSearchIndex creates the full-text index used by article search
+func (r *ArticleRepository) EnsureSearchIndex(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Exec("CREATE INDEX IF NOT EXISTS idx_articles_search ON articles USING GIN (" + articleSearchVector + ")").Error
+}
+
+// Create stores a new article together with its first revision
+func (r *ArticleRepository) Create(ctx context.Context, article *domain.Article) error {
+	if article.Version == 0 {
+		article.Version = 1
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(article).Error; err != nil {
+			return err
+		}
+		revision := newArticleRevision(article, article.AuthorID, article.CreatedAt)
+		return tx.Create(&revision).Error
+	})
+}
+
+func (r *ArticleRepository) GetByID(ctx context.Context, id uint) (*domain.Article, error) {
+	var article domain.Article
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Preload("UpdatedBy").
+		First(&article, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+// Update saves the article if it is unchanged since it was read, bumps its
+// version and records the new state as a revision. It returns
+// ErrVersionConflict when another update got there first. The view and
+// deflection counters are left alone, as they are incremented in place.
+func (r *ArticleRepository) Update(ctx context.Context, article *domain.Article, editorID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, article, &article.Version, "view_count", "deflection_count"); err != nil {
+			return err
+		}
+		revision := newArticleRevision(article, editorID, time.Now())
+		return tx.Create(&revision).Error
+	})
+}
+
+// Delete soft deletes an article and removes its ticket links
+func (r *ArticleRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", id).Delete(&domain.ArticleTicketLink{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.Article{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// List returns articles matching the filter, ranked by relevance when
+// searching and most recently updated first otherwise
+func (r *ArticleRepository) List(ctx context.Context, filter ArticleFilter, limit, offset int) ([]domain.Article, error) {
+	query := r.filter(ctx, filter)
+	if filter.Query != "" {
+		query = query.Clauses(clause.OrderBy{
+			Expression: clause.Expr{
+				SQL:  "ts_rank(" + articleSearchVector + ", websearch_to_tsquery('english', ?)) DESC, articles.id DESC",
+				Vars: []interface{}{filter.Query},
+			},
+		})
+	} else {
+		query = query.Order("articles.updated_at DESC, articles.id DESC")
+	}
+
+	var articles []domain.Article
+	err := query.
+		Preload("Author").
+		Limit(limit).
+		Offset(offset).
+		Find(&articles).Error
+	return articles, err
+}
+
+// Categories returns the categories of the articles matching the filter
+func (r *ArticleRepository) Categories(ctx context.Context, filter ArticleFilter) ([]ArticleCategory, error) {
+	var categories []ArticleCategory
+	err := r.filter(ctx, filter).
+		Select("COALESCE(NULLIF(articles.category, ''), 'uncategorized') AS category, COUNT(*) AS articles").
+		Group("1").
+		Order("category ASC").
+		Scan(&categories).Error
+	return categories, err
+}
+
+func (r *ArticleRepository) filter(ctx context.Context, filter ArticleFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&domain.Article{})
+	if filter.Query != "" {
+		query = query.Where(articleSearchVector+" @@ websearch_to_tsquery('english', ?)", filter.Query)
+	}
+	if filter.Category != "" {
+		query = query.Where("articles.category = ?", filter.Category)
+	}
+	if filter.Status != "" {
+		query = query.Where("articles.status = ?", filter.Status)
+	}
+	if filter.Visibility != "" {
+		query = query.Where("articles.visibility = ?", filter.Visibility)
+	}
+	return query
+}
+
+// IncrementViews counts a view without touching the version or update time
+func (r *ArticleRepository) IncrementViews(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Article{}).
+		Where("id = ?", id).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
+}
+
+// ListRevisions returns an article's revisions, oldest first
+func (r *ArticleRepository) ListRevisions(ctx context.Context, articleID uint) ([]domain.ArticleRevision, error) {
+	var revisions []domain.ArticleRevision
+	err := r.db.WithContext(ctx).
+		Where("article_id = ?", articleID).
+		Preload("EditedBy").
+		Order("version ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func (r *ArticleRepository) GetRevision(ctx context.Context, articleID uint, version int) (*domain.ArticleRevision, error) {
+	var revision domain.ArticleRevision
+	err := r.db.WithContext(ctx).
+		Where("article_id = ? AND version = ?", articleID, version).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// LinkTicket records that an article solved a ticket. Linking twice is a no-op.
+func (r *ArticleRepository) LinkTicket(ctx context.Context, link *domain.ArticleTicketLink) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error
+}
+
+func (r *ArticleRepository) UnlinkTicket(ctx context.Context, articleID, ticketID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("article_id = ? AND ticket_id = ?", articleID, ticketID).
+		Delete(&domain.ArticleTicketLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListByTicket returns the articles linked to a ticket
+func (r *ArticleRepository) ListByTicket(ctx context.Context, ticketID uint, publicOnly bool) ([]domain.Article, error) {
+	query := r.db.WithContext(ctx).
+		Joins("JOIN article_ticket_links ON article_ticket_links.article_id = articles.id").
+		Where("article_ticket_links.ticket_id = ?", ticketID)
+	if publicOnly {
+		query = query.Where("articles.visibility = ? AND articles.status = ?", domain.PublicArticle, domain.ArticlePublished)
+	}
+
+	var articles []domain.Article
+	err := query.Order("article_ticket_links.created_at ASC").Find(&articles).Error
+	return articles, err
+}
+
+// ListTickets returns the tickets an article was linked to, newest link first
+func (r *ArticleRepository) ListTickets(ctx context.Context, articleID uint) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Joins("JOIN article_ticket_links ON article_ticket_links.ticket_id = tickets.id").
+		Where("article_ticket_links.article_id = ?", articleID).
+		Order("article_ticket_links.created_at DESC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func newArticleRevision(article *domain.Article, editorID uint, at time.Time) domain.ArticleRevision {
+	return domain.ArticleRevision{
+		ArticleID:  article.ID,
+		Version:    article.Version,
+		Title:      article.Title,
+		Body:       article.Body,
+		Category:   article.Category,
+		Status:     article.Status,
+		Visibility: article.Visibility,
+		EditedByID: editorID,
+		CreatedAt:  at,
+	}
+}
+
+// ListPublished returns the published articles used as suggestion
+// candidates, most recently updated first
+func (r *ArticleRepository) ListPublished(ctx context.Context, visibility domain.ArticleVisibility, limit int) ([]domain.Article, error) {
+	query := r.db.WithContext(ctx).Where("status = ?", domain.ArticlePublished)
+	if visibility != "" {
+		query = query.Where("visibility = ?", visibility)
+	}
+
+	var articles []domain.Article
+	err := query.Order("updated_at DESC, id DESC").Limit(limit).Find(&articles).Error
+	return articles, err
+}
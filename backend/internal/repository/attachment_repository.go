@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *domain.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id uint) (*domain.Attachment, error) {
+	var attachment domain.Attachment
+	err := r.db.WithContext(ctx).Preload("UploadedBy").First(&attachment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentRepository) ListByTicket(ctx context.Context, ticketID uint) ([]domain.Attachment, error) {
+	var attachments []domain.Attachment
+	err := r.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Preload("UploadedBy").
+		Order("created_at ASC").
+		Find(&attachments).Error
+	return attachments, err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"helpdesk-backend/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BulkJobRepository struct {
+	db *gorm.DB
+}
+
+func NewBulkJobRepository(db *gorm.DB) *BulkJobRepository {
+	return &BulkJobRepository{db: db}
+}
+
+func (r *BulkJobRepository) Create(ctx context.Context, job *domain.BulkJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *BulkJobRepository) GetByID(ctx context.Context, id uint) (*domain.BulkJob, error) {
+	var job domain.BulkJob
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *BulkJobRepository) Update(ctx context.Context, job *domain.BulkJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+// ClaimNext atomically moves the oldest queued job to running and returns it.
+// It returns nil when no job is waiting.
+func (r *BulkJobRepository) ClaimNext(ctx context.Context) (*domain.BulkJob, error) {
+	var claimed *domain.BulkJob
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job domain.BulkJob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", domain.BulkJobQueued).
+			Order("id ASC").
+			First(&job).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		job.Status = domain.BulkJobRunning
+		if err := tx.Save(&job).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+// RequeueRunning puts jobs that were interrupted mid-run back in the queue
+func (r *BulkJobRepository) RequeueRunning(ctx context.Context) error {
+	return r.db.WithContext(ctx).Model(&domain.BulkJob{}).
+		Where("status = ?", domain.BulkJobRunning).
+		Update("status", domain.BulkJobQueued).Error
+}
+
+// CountByStatus returns the number of jobs in the given state
+func (r *BulkJobRepository) CountByStatus(ctx context.Context, status domain.BulkJobStatus) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.BulkJob{}).Where("status = ?", status).Count(&count).Error
+	return int(count), err
+}
+
+// SaveResults stores per-ticket outcomes, ignoring tickets already recorded
+func (r *BulkJobRepository) SaveResults(ctx context.Context, results []domain.BulkJobResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&results).Error
+}
+
+func (r *BulkJobRepository) ListResults(ctx context.Context, jobID uint, limit, offset int) ([]domain.BulkJobResult, error) {
+	var results []domain.BulkJobResult
+	err := r.db.WithContext(ctx).
+		Where("job_id = ?", jobID).
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&results).Error
+	return results, err
+}
+
+// CountResults returns how many tickets of a job succeeded and failed
+func (r *BulkJobRepository) CountResults(ctx context.Context, jobID uint) (succeeded, failed int, err error) {
+	var rows []struct {
+		Success bool
+		Count   int
+	}
+	err = r.db.WithContext(ctx).Model(&domain.BulkJobResult{}).
+		Select("success, COUNT(*) AS count").
+		Where("job_id = ?", jobID).
+		Group("success").
+		Scan(&rows).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, row := range rows {
+		if row.Success {
+			succeeded = row.Count
+		} else {
+			failed = row.Count
+		}
+	}
+	return succeeded, failed, nil
+}
+
+// ProcessedTicketIDs returns the tickets a job has already handled, used to resume interrupted jobs
+func (r *BulkJobRepository) ProcessedTicketIDs(ctx context.Context, jobID uint) (map[uint]bool, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&domain.BulkJobResult{}).Where("job_id = ?", jobID).Pluck("ticket_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	processed := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		processed[id] = true
+	}
+	return processed, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewChangeRepository(db *gorm.DB) *ChangeRepository {
+	return &ChangeRepository{db: db}
+}
+
+// ChangeFilter narrows the change requests returned by List
+type ChangeFilter struct {
+	Status     domain.ChangeStatus
+	ComputerID *uint
+	From       *time.Time // changes whose window ends after From
+	To         *time.Time // changes whose window starts before To
+}
+
+// Create saves a change request and links it to its computers
+func (r *ChangeRepository) Create(ctx context.Context, change *domain.ChangeRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		computers := change.Computers
+		if err := tx.Omit(clause.Associations).Create(change).Error; err != nil {
+			return err
+		}
+		change.Computers = computers
+		return tx.Model(change).Association("Computers").Replace(computers)
+	})
+}
+
+func (r *ChangeRepository) GetByID(ctx context.Context, id uint) (*domain.ChangeRequest, error) {
+	var change domain.ChangeRequest
+	err := r.db.WithContext(ctx).
+		Preload("RequestedBy").
+		Preload("Implementer").
+		Preload("Computers").
+		Preload("Approvals", func(db *gorm.DB) *gorm.DB {
+			return db.Order("round ASC, step ASC, id ASC")
+		}).
+		Preload("Approvals.Approver").
+		First(&change, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// Update saves the change request if it is unchanged since it was read and
+// bumps its version. The linked computers are replaced by change.Computers.
+func (r *ChangeRepository) Update(ctx context.Context, change *domain.ChangeRequest) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, change, &change.Version); err != nil {
+			return err
+		}
+		return tx.Model(change).Association("Computers").Replace(change.Computers)
+	})
+}
+
+// Delete removes a change request with its approvals and computer links
+func (r *ChangeRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		change := &domain.ChangeRequest{ID: id}
+		if err := tx.Model(change).Association("Computers").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("change_request_id = ?", id).Delete(&domain.ChangeApproval{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(change)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// List returns change requests ordered by their planned start
+func (r *ChangeRepository) List(ctx context.Context, filter ChangeFilter, limit, offset int) ([]domain.ChangeRequest, error) {
+	query := r.db.WithContext(ctx).Model(&domain.ChangeRequest{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ComputerID != nil {
+		query = query.Where("id IN (?)", r.db.Table("change_request_computers").
+			Select("change_request_id").
+			Where("computer_id = ?", *filter.ComputerID))
+	}
+	if filter.From != nil {
+		query = query.Where("planned_end > ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("planned_start < ?", *filter.To)
+	}
+
+	var changes []domain.ChangeRequest
+	err := query.
+		Preload("RequestedBy").
+		Preload("Computers").
+		Order("planned_start ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&changes).Error
+	return changes, err
+}
+
+// FindOverlapping returns the changes in one of the given statuses whose
+// window overlaps [start, end) and that touch any of the computers. The
+// change with excludeID is left out so a change does not conflict with itself.
+func (r *ChangeRepository) FindOverlapping(ctx context.Context, computerIDs []uint, start, end time.Time, statuses []domain.ChangeStatus, excludeID uint) ([]domain.ChangeRequest, error) {
+	var changes []domain.ChangeRequest
+	if len(computerIDs) == 0 {
+		return changes, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("id <> ? AND status IN ? AND planned_start < ? AND planned_end > ?", excludeID, statuses, end, start).
+		Where("id IN (?)", r.db.Table("change_request_computers").
+			Select("change_request_id").
+			Where("computer_id IN ?", computerIDs)).
+		Preload("Computers").
+		Order("planned_start ASC, id ASC").
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *ChangeRepository) CreateApprovals(ctx context.Context, approvals []domain.ChangeApproval) error {
+	if len(approvals) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit(clause.Associations).Create(&approvals).Error
+}
+
+func (r *ChangeRepository) UpdateApproval(ctx context.Context, approval *domain.ChangeApproval) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(approval).Error
+}
+
+// SkipPendingApprovals marks the undecided approvals of a change as skipped
+func (r *ChangeRepository) SkipPendingApprovals(ctx context.Context, changeID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.ChangeApproval{}).
+		Where("change_request_id = ? AND status = ?", changeID, domain.ApprovalPending).
+		Update("status", domain.ApprovalSkipped).Error
+}
+
+// FindComputers loads the computers with the given IDs
+func (r *ChangeRepository) FindComputers(ctx context.Context, ids []uint) ([]domain.Computer, error) {
+	var computers []domain.Computer
+	if len(ids) == 0 {
+		return computers, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&computers).Error
+	return computers, err
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"helpdesk-backend/internal/domain"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CommentRepository struct {
@@ -15,8 +17,18 @@ func NewCommentRepository(db *gorm.DB) *CommentRepository {
 	return &CommentRepository{db: db}
 }
 
+// Create stores a new comment together with its first revision
 func (r *CommentRepository) Create(ctx context.Context, comment *domain.Comment) error {
-	return r.db.WithContext(ctx).Create(comment).Error
+	if comment.Version == 0 {
+		comment.Version = 1
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		revision := newRevision(comment, comment.AuthorID, comment.CreatedAt)
+		return tx.Create(&revision).Error
+	})
 }
 
 func (r *CommentRepository) GetByID(ctx context.Context, id uint) (*domain.Comment, error) {
@@ -28,20 +40,132 @@ func (r *CommentRepository) GetByID(ctx context.Context, id uint) (*domain.Comme
 	return &comment, nil
 }
 
+// GetByIDWithDeleted returns a comment even if it has been deleted
+func (r *CommentRepository) GetByIDWithDeleted(ctx context.Context, id uint) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := r.db.WithContext(ctx).Unscoped().Preload("Author").Preload("DeletedBy").First(&comment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Update saves the comment if it is unchanged since it was read, bumps its
+// version and records the new state as a revision. It returns
+// ErrVersionConflict when another update got there first.
 func (r *CommentRepository) Update(ctx context.Context, comment *domain.Comment) error {
-	return r.db.WithContext(ctx).Save(comment).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Comments written before revisions were kept have no history yet,
+		// so snapshot the stored state before overwriting it
+		var stored domain.Comment
+		if err := tx.First(&stored, comment.ID).Error; err != nil {
+			return err
+		}
+		editorID, editedAt := stored.AuthorID, stored.CreatedAt
+		if stored.EditedByID != nil && stored.EditedAt != nil {
+			editorID, editedAt = *stored.EditedByID, *stored.EditedAt
+		}
+		previous := newRevision(&stored, editorID, editedAt)
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&previous).Error; err != nil {
+			return err
+		}
+
+		if err := updateVersioned(tx, comment, &comment.Version); err != nil {
+			return err
+		}
+
+		editorID, editedAt = comment.AuthorID, time.Now()
+		if comment.EditedByID != nil && comment.EditedAt != nil {
+			editorID, editedAt = *comment.EditedByID, *comment.EditedAt
+		}
+		revision := newRevision(comment, editorID, editedAt)
+		return tx.Create(&revision).Error
+	})
 }
 
-func (r *CommentRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Comment{}, id).Error
+// Delete soft deletes a comment, remembering who deleted it
+func (r *CommentRepository) Delete(ctx context.Context, id uint, deletedByID uint) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Comment{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at":    time.Now(),
+			"deleted_by_id": deletedByID,
+		}).Error
 }
 
-func (r *CommentRepository) ListByTicket(ctx context.Context, ticketID uint) ([]domain.Comment, error) {
+// ListByTicket returns a ticket's comments, leaving out internal notes unless
+// includeInternal is set and deleted comments unless includeDeleted is set
+func (r *CommentRepository) ListByTicket(ctx context.Context, ticketID uint, includeInternal, includeDeleted bool) ([]domain.Comment, error) {
+	query := r.db.WithContext(ctx).Where("ticket_id = ?", ticketID)
+	if !includeInternal {
+		query = query.Where("is_public = ?", true)
+	}
+	if includeDeleted {
+		query = query.Unscoped().Preload("DeletedBy")
+	}
+
 	var comments []domain.Comment
-	err := r.db.WithContext(ctx).
-		Where("ticket_id = ?", ticketID).
+	err := query.
 		Preload("Author").
 		Order("created_at ASC").
 		Find(&comments).Error
 	return comments, err
 }
+
+// ListRevisions returns a comment's revisions, oldest first. Revisions made
+// while the comment was an internal note are left out unless includeInternal is set.
+func (r *CommentRepository) ListRevisions(ctx context.Context, commentID uint, includeInternal bool) ([]domain.CommentRevision, error) {
+	query := r.db.WithContext(ctx).Where("comment_id = ?", commentID)
+	if !includeInternal {
+		query = query.Where("is_public = ?", true)
+	}
+
+	var revisions []domain.CommentRevision
+	err := query.
+		Preload("EditedBy").
+		Order("version ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+func newRevision(comment *domain.Comment, editorID uint, at time.Time) domain.CommentRevision {
+	return domain.CommentRevision{
+		CommentID:   comment.ID,
+		Version:     comment.Version,
+		Content:     comment.Content,
+		ContentHTML: comment.ContentHTML,
+		IsPublic:    comment.IsPublic,
+		EditedByID:  editorID,
+		CreatedAt:   at,
+	}
+}
+
+// Rendered HTML from before inline images were signed embeds them as
+// src="/api/v1/attachments/<id>", which browsers cannot load. Markdown source
+// cannot produce a src attribute itself, as raw HTML is never rendered.
+const (
+	unsignedImagePattern = `%src="/api/v1/attachments/%`
+	signedImagePattern   = `%/image?signature=%`
+)
+
+// ListUnrendered returns up to limit comments after afterID whose HTML has not
+// been rendered yet or still embeds images by unsigned URLs. Comments that
+// render to nothing are stored as ” and not returned again.
+func (r *CommentRepository) ListUnrendered(ctx context.Context, afterID uint, limit int) ([]domain.Comment, error) {
+	var comments []domain.Comment
+	err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Where("content_html IS NULL OR (content_html LIKE ? AND content_html NOT LIKE ?)", unsignedImagePattern, signedImagePattern).
+		Order("id ASC").
+		Limit(limit).
+		Find(&comments).Error
+	return comments, err
+}
+
+// SetContentHTML stores rendered HTML without touching the version or timestamps.
+// Nothing is stored if the record was saved since version was read, as that
+// save rendered its own HTML.
+func (r *CommentRepository) SetContentHTML(ctx context.Context, id uint, version int, html string) error {
+	return r.db.WithContext(ctx).Model(&domain.Comment{}).Where("id = ? AND version = ?", id, version).UpdateColumn("content_html", html).Error
+}
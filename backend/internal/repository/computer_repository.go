@@ -18,6 +18,9 @@ func NewComputerRepository(db *gorm.DB) *ComputerRepository {
 
 // Create creates a new computer
 func (r *ComputerRepository) Create(computer *domain.Computer) error {
+	if computer.Version == 0 {
+		computer.Version = 1
+	}
 	return r.db.Create(computer).Error
 }
 
@@ -99,9 +102,10 @@ func (r *ComputerRepository) GetByOS() (map[string][]domain.Computer, error) {
 	return grouped, nil
 }
 
-// Update updates a computer
+// Update updates a computer if it is unchanged since it was read and bumps its version.
+// It returns ErrVersionConflict when another update got there first.
 func (r *ComputerRepository) Update(computer *domain.Computer) error {
-	return r.db.Save(computer).Error
+	return updateVersioned(r.db, computer, &computer.Version)
 }
 
 // Delete soft deletes a computer
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DeflectionRepository struct {
+	db *gorm.DB
+}
+
+func NewDeflectionRepository(db *gorm.DB) *DeflectionRepository {
+	return &DeflectionRepository{db: db}
+}
+
+// DeflectionFilter limits deflections to those recorded in [From, To)
+type DeflectionFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// DeflectionArticleRow counts the deflections credited to one article
+type DeflectionArticleRow struct {
+	ArticleID   uint   `json:"article_id"`
+	Title       string `json:"title"`
+	Deflections int    `json:"deflections"`
+}
+
+// Create records a deflection and, when an article solved the problem,
+// counts it on the article
+func (r *DeflectionRepository) Create(ctx context.Context, deflection *domain.TicketDeflection) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("User").Create(deflection).Error; err != nil {
+			return err
+		}
+		if deflection.ArticleID == nil {
+			return nil
+		}
+		return tx.Model(&domain.Article{}).
+			Where("id = ?", *deflection.ArticleID).
+			UpdateColumn("deflection_count", gorm.Expr("deflection_count + 1")).Error
+	})
+}
+
+// Count returns how many deflections were credited to articles and to
+// resolved tickets
+func (r *DeflectionRepository) Count(ctx context.Context, filter DeflectionFilter) (articles, tickets int, err error) {
+	var row struct {
+		Articles int
+		Tickets  int
+	}
+	err = r.filter(ctx, filter).
+		Select("COUNT(article_id) AS articles, COUNT(ticket_id) AS tickets").
+		Scan(&row).Error
+	return row.Articles, row.Tickets, err
+}
+
+// SummarizeByArticle returns the articles that deflected the most tickets
+func (r *DeflectionRepository) SummarizeByArticle(ctx context.Context, filter DeflectionFilter, limit int) ([]DeflectionArticleRow, error) {
+	var rows []DeflectionArticleRow
+	err := r.filter(ctx, filter).
+		Select("ticket_deflections.article_id, articles.title, COUNT(*) AS deflections").
+		Joins("JOIN articles ON articles.id = ticket_deflections.article_id").
+		Group("ticket_deflections.article_id, articles.title").
+		Order("deflections DESC, ticket_deflections.article_id ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *DeflectionRepository) filter(ctx context.Context, filter DeflectionFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&domain.TicketDeflection{})
+	if filter.From != nil {
+		query = query.Where("ticket_deflections.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("ticket_deflections.created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrDuplicate is returned when a write would violate a unique index
+var ErrDuplicate = errors.New("record already exists")
+
+// uniqueViolation is the Postgres error code for unique index violations
+const uniqueViolation = "23505"
+
+// translateUnique turns unique index violations into ErrDuplicate and leaves
+// other errors untouched
+func translateUnique(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrDuplicate
+	}
+	return err
+}
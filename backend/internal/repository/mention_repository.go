@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MentionRepository struct {
+	db *gorm.DB
+}
+
+func NewMentionRepository(db *gorm.DB) *MentionRepository {
+	return &MentionRepository{db: db}
+}
+
+// CreateMany stores mentions, skipping users already mentioned by the same comment
+func (r *MentionRepository) CreateMany(ctx context.Context, mentions []domain.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&mentions).Error
+}
+
+// MentionedUserIDs returns the users a comment already mentions
+func (r *MentionRepository) MentionedUserIDs(ctx context.Context, commentID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&domain.Mention{}).
+		Where("comment_id = ?", commentID).
+		Pluck("mentioned_user_id", &ids).Error
+	return ids, err
+}
+
+// ListForUser returns the mentions of a user, newest first. Mentions in deleted
+// comments are skipped, as are internal notes unless includeInternal is set.
+func (r *MentionRepository) ListForUser(ctx context.Context, userID uint, includeInternal bool, limit, offset int) ([]domain.Mention, error) {
+	query := r.db.WithContext(ctx).
+		Joins("JOIN comments ON comments.id = mentions.comment_id AND comments.deleted_at IS NULL").
+		Where("mentions.mentioned_user_id = ?", userID)
+	if !includeInternal {
+		query = query.Where("comments.is_public = ?", true)
+	}
+
+	var mentions []domain.Mention
+	err := query.
+		Preload("Comment.Author").
+		Preload("Ticket").
+		Preload("MentionedBy").
+		Order("mentions.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&mentions).Error
+	return mentions, err
+}
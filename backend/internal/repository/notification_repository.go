@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) CreateMany(ctx context.Context, notifications []domain.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&notifications).Error
+}
+
+// ListForUser returns a user's notifications, newest first
+func (r *NotificationRepository) ListForUser(ctx context.Context, userID uint, unreadOnly bool, limit, offset int) ([]domain.Notification, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []domain.Notification
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read. It returns
+// gorm.ErrRecordNotFound when the notification does not belong to the user.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Where("read_at IS NULL").
+		Update("read_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Either unknown or already read
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&domain.Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and returns how many changed
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// ListPreferences returns the preferences stored for the given users
+func (r *NotificationRepository) ListPreferences(ctx context.Context, userIDs []uint) ([]domain.NotificationPreference, error) {
+	var prefs []domain.NotificationPreference
+	if len(userIDs) == 0 {
+		return prefs, nil
+	}
+	err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&prefs).Error
+	return prefs, err
+}
+
+// SavePreferences inserts or replaces a user's preferences
+func (r *NotificationRepository) SavePreferences(ctx context.Context, prefs []domain.NotificationPreference) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"in_app", "email"}),
+	}).Create(&prefs).Error
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PriorityRepository struct {
+	db *gorm.DB
+}
+
+func NewPriorityRepository(db *gorm.DB) *PriorityRepository {
+	return &PriorityRepository{db: db}
+}
+
+// PriorityOverrideFilter narrows the period covered by the override report
+type PriorityOverrideFilter struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// PriorityOverrideRow is one group of the priority override report
+type PriorityOverrideRow struct {
+	Key       string `json:"key"`
+	Label     string `json:"label"`
+	Overrides int    `json:"overrides"`
+	Tickets   int    `json:"tickets"`
+}
+
+func (r *PriorityRepository) ListMatrix(ctx context.Context) ([]domain.PriorityMatrixEntry, error) {
+	var entries []domain.PriorityMatrixEntry
+	err := r.db.WithContext(ctx).Find(&entries).Error
+	return entries, err
+}
+
+// SaveMatrix inserts or replaces the given matrix cells
+func (r *PriorityRepository) SaveMatrix(ctx context.Context, entries []domain.PriorityMatrixEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "impact"}, {Name: "urgency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"priority"}),
+	}).Create(&entries).Error
+}
+
+func (r *PriorityRepository) CreateOverride(ctx context.Context, override *domain.PriorityOverride) error {
+	return r.db.WithContext(ctx).Create(override).Error
+}
+
+// Reporting
+
+// CountTickets returns the number of tickets created in the period
+func (r *PriorityRepository) CountTickets(ctx context.Context, filter PriorityOverrideFilter) (int, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&domain.Ticket{})
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	err := query.Count(&count).Error
+	return int(count), err
+}
+
+// SummarizeOverridesBy aggregates the overrides recorded in the period by the
+// agent who made them or by the change from computed to chosen priority
+func (r *PriorityRepository) SummarizeOverridesBy(ctx context.Context, groupBy string, filter PriorityOverrideFilter) ([]PriorityOverrideRow, error) {
+	query := r.filterOverrides(ctx, filter)
+
+	var key, label string
+	switch groupBy {
+	case "agent":
+		query = query.Joins("JOIN users agents ON agents.id = priority_overrides.overridden_by_id")
+		key = "CAST(priority_overrides.overridden_by_id AS TEXT)"
+		label = "MAX(agents.first_name || ' ' || agents.last_name)"
+	case "change":
+		key = "priority_overrides.computed_priority || ' -> ' || priority_overrides.priority"
+		label = key
+	default:
+		return nil, fmt.Errorf("unsupported priority override grouping: %s", groupBy)
+	}
+
+	var rows []PriorityOverrideRow
+	err := query.
+		Select(key + " AS key, " + label + " AS label, " +
+			"COUNT(*) AS overrides, COUNT(DISTINCT priority_overrides.ticket_id) AS tickets").
+		Group(key).
+		Order("overrides DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// CountOverriddenTickets returns how many distinct tickets were overridden in the period
+func (r *PriorityRepository) CountOverriddenTickets(ctx context.Context, filter PriorityOverrideFilter) (int, error) {
+	var count int64
+	err := r.filterOverrides(ctx, filter).
+		Distinct("priority_overrides.ticket_id").
+		Count(&count).Error
+	return int(count), err
+}
+
+// ListOverrides returns the overrides recorded in the period, newest first
+func (r *PriorityRepository) ListOverrides(ctx context.Context, filter PriorityOverrideFilter, limit int) ([]domain.PriorityOverride, error) {
+	var overrides []domain.PriorityOverride
+	err := r.filterOverrides(ctx, filter).
+		Preload("OverriddenBy").
+		Order("priority_overrides.created_at DESC, priority_overrides.id DESC").
+		Limit(limit).
+		Find(&overrides).Error
+	return overrides, err
+}
+
+func (r *PriorityRepository) filterOverrides(ctx context.Context, filter PriorityOverrideFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&domain.PriorityOverride{})
+	if filter.From != nil {
+		query = query.Where("priority_overrides.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("priority_overrides.created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// BackfillImpactUrgency sets impact and urgency on tickets of the given
+// priority that predate the priority matrix. A non-empty reason records the
+// priority as an override because no matrix cell yields it.
+func (r *PriorityRepository) BackfillImpactUrgency(ctx context.Context, priority domain.TicketPriority, impact domain.TicketImpact, urgency domain.TicketUrgency, reason string) (int, error) {
+	result := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Where("impact = '' AND priority = ?", priority).
+		UpdateColumns(map[string]interface{}{
+			"impact":                   impact,
+			"urgency":                  urgency,
+			"priority_override_reason": reason,
+		})
+	return int(result.RowsAffected), result.Error
+}
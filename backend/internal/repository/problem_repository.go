@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type ProblemRepository struct {
+	db *gorm.DB
+}
+
+func NewProblemRepository(db *gorm.DB) *ProblemRepository {
+	return &ProblemRepository{db: db}
+}
+
+func (r *ProblemRepository) Create(ctx context.Context, problem *domain.Problem) error {
+	return r.db.WithContext(ctx).Create(problem).Error
+}
+
+func (r *ProblemRepository) GetByID(ctx context.Context, id uint) (*domain.Problem, error) {
+	var problem domain.Problem
+	err := r.db.WithContext(ctx).
+		Preload("Owner").
+		Preload("CreatedBy").
+		First(&problem, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &problem, nil
+}
+
+// Update saves the problem if it is unchanged since it was read and bumps its version
+func (r *ProblemRepository) Update(ctx context.Context, problem *domain.Problem) error {
+	return updateVersioned(r.db.WithContext(ctx), problem, &problem.Version)
+}
+
+// Delete removes a problem and unlinks its tickets
+func (r *ProblemRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.Ticket{}).
+			Where("problem_id = ?", id).
+			UpdateColumns(map[string]interface{}{
+				"problem_id": nil,
+				"version":    gorm.Expr("version + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.Problem{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// List returns problems, newest first. An empty status returns problems in every state.
+func (r *ProblemRepository) List(ctx context.Context, status domain.ProblemStatus, limit, offset int) ([]domain.Problem, error) {
+	query := r.db.WithContext(ctx).Preload("Owner")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var problems []domain.Problem
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&problems).Error
+	return problems, err
+}
+
+// CountTickets returns the number of incident tickets linked to each problem
+func (r *ProblemRepository) CountTickets(ctx context.Context, problemIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(problemIDs))
+	if len(problemIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ProblemID uint
+		Count     int
+	}
+	err := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Select("problem_id, COUNT(*) AS count").
+		Where("problem_id IN ?", problemIDs).
+		Group("problem_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ProblemID] = row.Count
+	}
+	return counts, nil
+}
+
+// ListTickets returns the incident tickets linked to a problem, oldest first
+func (r *ProblemRepository) ListTickets(ctx context.Context, problemID uint) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Where("problem_id = ?", problemID).
+		Preload("Requester").
+		Preload("Assignee").
+		Order("created_at ASC, id ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
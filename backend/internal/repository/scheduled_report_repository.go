@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduledReportRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledReportRepository(db *gorm.DB) *ScheduledReportRepository {
+	return &ScheduledReportRepository{db: db}
+}
+
+func (r *ScheduledReportRepository) Create(ctx context.Context, report *domain.ScheduledReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+func (r *ScheduledReportRepository) GetByID(ctx context.Context, id uint) (*domain.ScheduledReport, error) {
+	var report domain.ScheduledReport
+	err := r.db.WithContext(ctx).First(&report, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *ScheduledReportRepository) Update(ctx context.Context, report *domain.ScheduledReport) error {
+	return r.db.WithContext(ctx).Save(report).Error
+}
+
+// Delete removes a scheduled report together with its run history
+func (r *ScheduledReportRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scheduled_report_id = ?", id).Delete(&domain.ReportRun{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.ScheduledReport{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *ScheduledReportRepository) List(ctx context.Context) ([]domain.ScheduledReport, error) {
+	var reports []domain.ScheduledReport
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&reports).Error
+	return reports, err
+}
+
+// ClaimDue returns the active reports whose next run is due and moves their
+// next run to the time returned by next, so that other workers skip them.
+// A report whose run is interrupted is not retried; it runs again on its
+// next scheduled time.
+func (r *ScheduledReportRepository) ClaimDue(ctx context.Context, now time.Time, limit int, next func(*domain.ScheduledReport) *time.Time) ([]domain.ScheduledReport, error) {
+	var claimed []domain.ScheduledReport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("is_active = ? AND next_run_at <= ?", true, now).
+			Order("next_run_at ASC, id ASC").
+			Limit(limit).
+			Find(&claimed).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range claimed {
+			claimed[i].NextRunAt = next(&claimed[i])
+			claimed[i].LastRunAt = &now
+			err := tx.Model(&domain.ScheduledReport{}).
+				Where("id = ?", claimed[i].ID).
+				Updates(map[string]interface{}{
+					"next_run_at": claimed[i].NextRunAt,
+					"last_run_at": now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// MarkRun records that a report was run at t
+func (r *ScheduledReportRepository) MarkRun(ctx context.Context, id uint, t time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.ScheduledReport{}).
+		Where("id = ?", id).
+		UpdateColumn("last_run_at", t).Error
+}
+
+func (r *ScheduledReportRepository) CreateRun(ctx context.Context, run *domain.ReportRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *ScheduledReportRepository) UpdateRun(ctx context.Context, run *domain.ReportRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// ListRuns returns the run history of a report, newest first
+func (r *ScheduledReportRepository) ListRuns(ctx context.Context, reportID uint, limit, offset int) ([]domain.ReportRun, error) {
+	var runs []domain.ReportRun
+	err := r.db.WithContext(ctx).
+		Where("scheduled_report_id = ?", reportID).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&runs).Error
+	return runs, err
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"helpdesk-backend/internal/domain"
-	"log"
+	"log/slog"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -18,7 +18,7 @@ func NewSeeder(db *gorm.DB) *Seeder {
 }
 
 func (s *Seeder) SeedInitialData() error {
-	log.Println("Starting database seeding...")
+	slog.Info("Starting database seeding")
 
 	// Seed SLA configurations first
 	if err := s.seedSLAs(); err != nil {
@@ -35,7 +35,7 @@ func (s *Seeder) SeedInitialData() error {
 		return err
 	}
 
-	log.Println("Database seeding completed successfully")
+	slog.Info("Database seeding completed successfully")
 	return nil
 }
 
@@ -44,7 +44,7 @@ func (s *Seeder) seedSLAs() error {
 	s.db.Model(&domain.SLA{}).Count(&count)
 
 	if count > 0 {
-		log.Println("SLAs already exist, skipping SLA seeding")
+		slog.Info("SLAs already exist, skipping SLA seeding")
 		return nil
 	}
 
@@ -81,7 +81,7 @@ func (s *Seeder) seedSLAs() error {
 		}
 	}
 
-	log.Println("SLAs seeded successfully")
+	slog.Info("SLAs seeded successfully")
 	return nil
 }
 
@@ -90,7 +90,7 @@ func (s *Seeder) seedUsers() error {
 	s.db.Model(&domain.User{}).Count(&count)
 
 	if count > 0 {
-		log.Println("Users already exist, skipping user seeding")
+		slog.Info("Users already exist, skipping user seeding")
 		return nil
 	}
 
@@ -135,7 +135,7 @@ func (s *Seeder) seedUsers() error {
 		}
 	}
 
-	log.Println("Users seeded successfully")
+	slog.Info("Users seeded successfully")
 	return nil
 }
 
@@ -144,7 +144,7 @@ func (s *Seeder) seedTickets() error {
 	s.db.Model(&domain.Ticket{}).Count(&count)
 
 	if count > 0 {
-		log.Println("Tickets already exist, skipping ticket seeding")
+		slog.Info("Tickets already exist, skipping ticket seeding")
 		return nil
 	}
 
@@ -192,7 +192,7 @@ func (s *Seeder) seedTickets() error {
 		}
 	}
 
-	log.Println("Sample tickets seeded successfully")
+	slog.Info("Sample tickets seeded successfully")
 	return nil
 }
 
@@ -0,0 +1,425 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"strings"
+	"time"
+)
+
+// wallClockLayout formats local wall-clock times for SQL timestamp parameters
+const wallClockLayout = "2006-01-02 15:04:05"
+
+// TicketReportFilter narrows the tickets aggregated by reports
+type TicketReportFilter struct {
+	Category   string
+	Priority   domain.TicketPriority
+	AssigneeID *uint
+	Department string // department of the requester
+}
+
+// conditions returns the SQL conditions on the tickets table for the filter
+func (f TicketReportFilter) conditions() (string, []interface{}) {
+	conds := []string{"tickets.deleted_at IS NULL"}
+	var args []interface{}
+	if f.Category != "" {
+		conds = append(conds, "tickets.category = ?")
+		args = append(args, f.Category)
+	}
+	if f.Priority != "" {
+		conds = append(conds, "tickets.priority = ?")
+		args = append(args, f.Priority)
+	}
+	if f.AssigneeID != nil {
+		conds = append(conds, "tickets.assignee_id = ?")
+		args = append(args, *f.AssigneeID)
+	}
+	if f.Department != "" {
+		conds = append(conds, "tickets.requester_id IN (SELECT id FROM users WHERE department = ?)")
+		args = append(args, f.Department)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// SeriesBucketing describes the buckets of a time series. Buckets are aligned
+// to the wall clock of a time zone, so a day is a local calendar day even
+// across daylight saving changes.
+type SeriesBucketing struct {
+	Unit string // hour, day, week or month; weeks start on Monday
+	Zone string // IANA time zone name
+
+	// Local wall-clock times of the first bucket and of the end of the series
+	// (exclusive), expressed in UTC
+	From time.Time
+	To   time.Time
+}
+
+func (b SeriesBucketing) from() string { return b.From.Format(wallClockLayout) }
+func (b SeriesBucketing) to() string   { return b.To.Format(wallClockLayout) }
+func (b SeriesBucketing) step() string { return "1 " + b.Unit }
+
+// TicketSeriesRow holds the ticket metrics of one bucket. Bucket is the local
+// wall-clock start of the bucket, expressed in UTC.
+type TicketSeriesRow struct {
+	Bucket      time.Time
+	Created     int
+	Resolved    int
+	Reopened    int
+	Backlog     int // tickets open at the end of the bucket
+	MedianHours *float64
+	P90Hours    *float64
+	SLATracked  int // resolved tickets that had an SLA
+	SLAMet      int // of those, the ones resolved before breaching it
+}
+
+// TicketSeries aggregates ticket volume, backlog and resolution times into
+// buckets. Tickets count as resolved when they were last resolved or closed,
+// so a reopened ticket is part of the backlog until it is resolved again.
+func (r *TicketRepository) TicketSeries(ctx context.Context, bucketing SeriesBucketing, filter TicketReportFilter) ([]TicketSeriesRow, error) {
+	conds, condArgs := filter.conditions()
+	db := r.db.WithContext(ctx)
+
+	// The backlog query yields every bucket, including empty ones
+	var rows []TicketSeriesRow
+	backlogArgs := []interface{}{bucketing.from(), bucketing.to(), bucketing.step(),
+		bucketing.step(), bucketing.to(), bucketing.Zone,
+		bucketing.step(), bucketing.to(), bucketing.Zone}
+	err := db.Raw(`
+		SELECT b.bucket, COUNT(tickets.id) AS backlog
+		FROM generate_series(?::timestamp, ?::timestamp - interval '1 microsecond', ?::interval) AS b(bucket)
+		LEFT JOIN tickets ON tickets.created_at < (LEAST(b.bucket + ?::interval, ?::timestamp) AT TIME ZONE ?)
+			AND (tickets.resolved_at IS NULL OR tickets.resolved_at >= (LEAST(b.bucket + ?::interval, ?::timestamp) AT TIME ZONE ?))
+			AND `+conds+`
+		GROUP BY b.bucket
+		ORDER BY b.bucket`, append(backlogArgs, condArgs...)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Buckets are matched by instant since scanned times may differ in location
+	index := make(map[int64]*TicketSeriesRow, len(rows))
+	for i := range rows {
+		index[rows[i].Bucket.Unix()] = &rows[i]
+	}
+	rangeArgs := []interface{}{bucketing.Unit, bucketing.Zone, bucketing.from(), bucketing.Zone, bucketing.to(), bucketing.Zone}
+
+	var created []struct {
+		Bucket time.Time
+		Count  int
+	}
+	err = db.Raw(`
+		SELECT date_trunc(?, tickets.created_at AT TIME ZONE ?) AS bucket, COUNT(*) AS count
+		FROM tickets
+		WHERE tickets.created_at >= (?::timestamp AT TIME ZONE ?) AND tickets.created_at < (?::timestamp AT TIME ZONE ?)
+			AND `+conds+`
+		GROUP BY 1`, append(rangeArgs, condArgs...)...).
+		Scan(&created).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range created {
+		if row, ok := index[c.Bucket.Unix()]; ok {
+			row.Created = c.Count
+		}
+	}
+
+	var resolved []TicketSeriesRow
+	err = db.Raw(`
+		SELECT date_trunc(?, tickets.resolved_at AT TIME ZONE ?) AS bucket,
+			COUNT(*) AS resolved,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM tickets.resolved_at - tickets.created_at)) / 3600 AS median_hours,
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM tickets.resolved_at - tickets.created_at)) / 3600 AS p90_hours,
+			COUNT(tickets.sla_breach_at) AS sla_tracked,
+			COUNT(*) FILTER (WHERE tickets.resolved_at <= tickets.sla_breach_at) AS sla_met
+		FROM tickets
+		WHERE tickets.resolved_at >= (?::timestamp AT TIME ZONE ?) AND tickets.resolved_at < (?::timestamp AT TIME ZONE ?)
+			AND tickets.status IN ?
+			AND `+conds+`
+		GROUP BY 1`, append(append(rangeArgs, resolvedStatuses), condArgs...)...).
+		Scan(&resolved).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range resolved {
+		if row, ok := index[res.Bucket.Unix()]; ok {
+			row.Resolved = res.Resolved
+			row.MedianHours = res.MedianHours
+			row.P90Hours = res.P90Hours
+			row.SLATracked = res.SLATracked
+			row.SLAMet = res.SLAMet
+		}
+	}
+
+	var reopened []struct {
+		Bucket time.Time
+		Count  int
+	}
+	err = db.Raw(`
+		SELECT date_trunc(?, ticket_status_changes.created_at AT TIME ZONE ?) AS bucket, COUNT(*) AS count
+		FROM ticket_status_changes
+		JOIN tickets ON tickets.id = ticket_status_changes.ticket_id
+		WHERE ticket_status_changes.created_at >= (?::timestamp AT TIME ZONE ?) AND ticket_status_changes.created_at < (?::timestamp AT TIME ZONE ?)
+			AND ticket_status_changes.from_status IN ? AND ticket_status_changes.to_status IN ?
+			AND `+conds+`
+		GROUP BY 1`, append(append(rangeArgs, resolvedStatuses, openStatuses), condArgs...)...).
+		Scan(&reopened).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range reopened {
+		if row, ok := index[c.Bucket.Unix()]; ok {
+			row.Reopened = c.Count
+		}
+	}
+
+	return rows, nil
+}
+
+var (
+	openStatuses     = []domain.TicketStatus{domain.OpenStatus, domain.InProgressStatus}
+	resolvedStatuses = []domain.TicketStatus{domain.ResolvedStatus, domain.ClosedStatus}
+)
+
+// AgentStatsRow holds the performance and workload of one agent. Tickets are
+// attributed to their current assignee: reassignments are not recorded, so a
+// ticket handed over to someone else counts only for them, even in Assigned.
+type AgentStatsRow struct {
+	AgentID   uint
+	FirstName string
+	LastName  string
+	Email     string
+	Role      domain.UserRole
+
+	Assigned     int // tickets created in the range and currently assigned to the agent
+	Resolved     int
+	Reopened     int
+	OpenWorkload int // open and in-progress tickets right now, whatever the range
+
+	FirstResponses           int // tickets created in the range that got a public staff reply
+	MedianFirstResponseHours *float64
+	AvgFirstResponseHours    *float64
+	MedianResolutionHours    *float64
+	AvgResolutionHours       *float64
+	SLATracked               int // resolved tickets that had an SLA
+	SLABreached              int // of those, the ones resolved after breaching it
+}
+
+// AgentStats aggregates ticket metrics per agent and admin for tickets
+// created or resolved in [from, to)
+func (r *TicketRepository) AgentStats(ctx context.Context, from, to time.Time) ([]AgentStatsRow, error) {
+	db := r.db.WithContext(ctx)
+	staffRoles := []domain.UserRole{domain.AgentRole, domain.AdminRole}
+
+	var rows []AgentStatsRow
+	err := db.Raw(`
+		SELECT id AS agent_id, first_name, last_name, email, role
+		FROM users
+		WHERE role IN ? AND deleted_at IS NULL
+		ORDER BY first_name, last_name, id`, staffRoles).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uint]*AgentStatsRow, len(rows))
+	for i := range rows {
+		index[rows[i].AgentID] = &rows[i]
+	}
+
+	type count struct {
+		AgentID uint
+		Count   int
+	}
+	counts := func(query string, args ...interface{}) ([]count, error) {
+		var result []count
+		err := db.Raw(query, args...).Scan(&result).Error
+		return result, err
+	}
+
+	assigned, err := counts(`
+		SELECT assignee_id AS agent_id, COUNT(*) AS count
+		FROM tickets
+		WHERE deleted_at IS NULL AND assignee_id IS NOT NULL AND created_at >= ? AND created_at < ?
+		GROUP BY assignee_id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range assigned {
+		if row, ok := index[c.AgentID]; ok {
+			row.Assigned = c.Count
+		}
+	}
+
+	workload, err := counts(`
+		SELECT assignee_id AS agent_id, COUNT(*) AS count
+		FROM tickets
+		WHERE deleted_at IS NULL AND assignee_id IS NOT NULL AND status IN ?
+		GROUP BY assignee_id`, openStatuses)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range workload {
+		if row, ok := index[c.AgentID]; ok {
+			row.OpenWorkload = c.Count
+		}
+	}
+
+	reopened, err := counts(`
+		SELECT tickets.assignee_id AS agent_id, COUNT(*) AS count
+		FROM ticket_status_changes
+		JOIN tickets ON tickets.id = ticket_status_changes.ticket_id
+		WHERE tickets.deleted_at IS NULL AND tickets.assignee_id IS NOT NULL
+			AND ticket_status_changes.created_at >= ? AND ticket_status_changes.created_at < ?
+			AND ticket_status_changes.from_status IN ? AND ticket_status_changes.to_status IN ?
+		GROUP BY tickets.assignee_id`, from, to, resolvedStatuses, openStatuses)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range reopened {
+		if row, ok := index[c.AgentID]; ok {
+			row.Reopened = c.Count
+		}
+	}
+
+	var resolved []AgentStatsRow
+	err = db.Raw(`
+		SELECT assignee_id AS agent_id,
+			COUNT(*) AS resolved,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600 AS median_resolution_hours,
+			AVG(EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600 AS avg_resolution_hours,
+			COUNT(sla_breach_at) AS sla_tracked,
+			COUNT(*) FILTER (WHERE resolved_at > sla_breach_at) AS sla_breached
+		FROM tickets
+		WHERE deleted_at IS NULL AND assignee_id IS NOT NULL AND status IN ?
+			AND resolved_at >= ? AND resolved_at < ?
+		GROUP BY assignee_id`, resolvedStatuses, from, to).
+		Scan(&resolved).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range resolved {
+		if row, ok := index[res.AgentID]; ok {
+			row.Resolved = res.Resolved
+			row.MedianResolutionHours = res.MedianResolutionHours
+			row.AvgResolutionHours = res.AvgResolutionHours
+			row.SLATracked = res.SLATracked
+			row.SLABreached = res.SLABreached
+		}
+	}
+
+	// The first response is the first public reply by any agent or admin that
+	// has not been deleted since
+	var responses []AgentStatsRow
+	err = db.Raw(`
+		SELECT tickets.assignee_id AS agent_id,
+			COUNT(*) AS first_responses,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM first_reply.at - tickets.created_at)) / 3600 AS median_first_response_hours,
+			AVG(EXTRACT(EPOCH FROM first_reply.at - tickets.created_at)) / 3600 AS avg_first_response_hours
+		FROM tickets
+		JOIN LATERAL (
+			SELECT MIN(comments.created_at) AS at
+			FROM comments
+			JOIN users authors ON authors.id = comments.author_id
+			WHERE comments.ticket_id = tickets.id AND comments.is_public AND comments.deleted_at IS NULL
+				AND authors.role IN ?
+		) first_reply ON first_reply.at IS NOT NULL
+		WHERE tickets.deleted_at IS NULL AND tickets.assignee_id IS NOT NULL
+			AND tickets.created_at >= ? AND tickets.created_at < ?
+		GROUP BY tickets.assignee_id`, staffRoles, from, to).
+		Scan(&responses).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range responses {
+		if row, ok := index[res.AgentID]; ok {
+			row.FirstResponses = res.FirstResponses
+			row.MedianFirstResponseHours = res.MedianFirstResponseHours
+			row.AvgFirstResponseHours = res.AvgFirstResponseHours
+		}
+	}
+
+	return rows, nil
+}
+
+// AgeBucket counts open tickets whose age is at least MinHours and, unless
+// MaxHours is zero, less than MaxHours
+type AgeBucket struct {
+	MinHours int
+	MaxHours int
+	Count    int
+}
+
+// OpenAgeHistogram counts open and in-progress tickets by age at now into
+// the buckets bounded by the given hour limits, which must be ascending
+func (r *TicketRepository) OpenAgeHistogram(ctx context.Context, now time.Time, limits []int) ([]AgeBucket, error) {
+	buckets := make([]AgeBucket, len(limits)+1)
+	selects := make([]string, len(buckets))
+	var args []interface{}
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].MinHours = limits[i-1]
+		}
+		cond := "created_at <= ?"
+		args = append(args, now.Add(-time.Duration(buckets[i].MinHours)*time.Hour))
+		if i < len(limits) {
+			buckets[i].MaxHours = limits[i]
+			cond += " AND created_at > ?"
+			args = append(args, now.Add(-time.Duration(limits[i])*time.Hour))
+		}
+		selects[i] = "COUNT(*) FILTER (WHERE " + cond + ")"
+	}
+
+	dest := make([]interface{}, len(buckets))
+	for i := range buckets {
+		dest[i] = &buckets[i].Count
+	}
+	err := r.db.WithContext(ctx).Raw(
+		"SELECT "+strings.Join(selects, ", ")+" FROM tickets WHERE deleted_at IS NULL AND status IN ?",
+		append(args, openStatuses)...).
+		Row().Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// CountSLADueBetween counts open and in-progress tickets whose SLA breaches
+// in [from, to)
+func (r *TicketRepository) CountSLADueBetween(ctx context.Context, from, to time.Time) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Where("status IN ? AND sla_breach_at >= ? AND sla_breach_at < ?", openStatuses, from, to).
+		Count(&count).Error
+	return int(count), err
+}
+
+// SLAComplianceRow counts the tickets of one priority resolved with an SLA
+type SLAComplianceRow struct {
+	Priority domain.TicketPriority
+	Resolved int
+	Met      int
+}
+
+// SLAComplianceByPriority counts, per priority, the tickets resolved since the
+// given time that had an SLA and how many of them met it
+func (r *TicketRepository) SLAComplianceByPriority(ctx context.Context, since time.Time) ([]SLAComplianceRow, error) {
+	var rows []SLAComplianceRow
+	err := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Select("priority, COUNT(*) AS resolved, COUNT(*) FILTER (WHERE resolved_at <= sla_breach_at) AS met").
+		Where("status IN ? AND resolved_at >= ? AND sla_breach_at IS NOT NULL", resolvedStatuses, since).
+		Group("priority").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ListOldestUnassigned returns the oldest open tickets nobody is assigned to
+func (r *TicketRepository) ListOldestUnassigned(ctx context.Context, limit int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Preload("Requester").
+		Where("status IN ? AND assignee_id IS NULL", openStatuses).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, err
+}
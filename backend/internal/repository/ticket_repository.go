@@ -12,25 +12,161 @@ type TicketRepository struct {
 	db *gorm.DB
 }
 
+// TicketFilter selects tickets by their attributes. Zero values are ignored.
+type TicketFilter struct {
+	Status      domain.TicketStatus   `json:"status"`
+	Priority    domain.TicketPriority `json:"priority"`
+	Category    string                `json:"category"`
+	AssigneeID  *uint                 `json:"assignee_id"`
+	Unassigned  bool                  `json:"unassigned"`
+	RequesterID *uint                 `json:"requester_id"`
+	Tag         string                `json:"tag"`
+	Search      string                `json:"search"`
+}
+
+// IsEmpty reports whether the filter has no criteria set
+func (f TicketFilter) IsEmpty() bool {
+	return f.Status == "" && f.Priority == "" && f.Category == "" && f.AssigneeID == nil &&
+		!f.Unassigned && f.RequesterID == nil && f.Tag == "" && f.Search == ""
+}
+
 func NewTicketRepository(db *gorm.DB) *TicketRepository {
 	return &TicketRepository{db: db}
 }
 
 func (r *TicketRepository) Create(ctx context.Context, ticket *domain.Ticket) error {
+	if ticket.Version == 0 {
+		ticket.Version = 1
+	}
 	return r.db.WithContext(ctx).Create(ticket).Error
 }
 
+// TicketKeyFunc builds a ticket key from a scope's sequence number
+type TicketKeyFunc func(seq int) string
+
+// CreateWithKey inserts the ticket with the next key of scope. The counter row
+// stays locked until the transaction commits, so concurrent creations queue up
+// and a failed insert gives its number back.
+func (r *TicketRepository) CreateWithKey(ctx context.Context, ticket *domain.Ticket, scope string, keyFn TicketKeyFunc) error {
+	if ticket.Version == 0 {
+		ticket.Version = 1
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextKeySequence(tx, scope)
+		if err != nil {
+			return err
+		}
+		key := keyFn(seq)
+		ticket.Key = &key
+		if err := tx.Create(ticket).Error; err != nil {
+			ticket.Key = nil
+			return translateUnique(err)
+		}
+		return nil
+	})
+}
+
+// AssignKey gives a ticket that has no key yet the next key of scope
+func (r *TicketRepository) AssignKey(ctx context.Context, id uint, scope string, keyFn TicketKeyFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seq, err := nextKeySequence(tx, scope)
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&domain.Ticket{}).
+			Where("id = ? AND key IS NULL", id).
+			UpdateColumn("key", keyFn(seq))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Someone else assigned it first; roll back so the number is not used up
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// nextKeySequence increments the counter of scope and returns the new value
+func nextKeySequence(tx *gorm.DB, scope string) (int, error) {
+	var seq int
+	err := tx.Raw(`INSERT INTO ticket_key_counters (scope, value) VALUES (?, 1)
+		ON CONFLICT (scope) DO UPDATE SET value = ticket_key_counters.value + 1
+		RETURNING value`, scope).Scan(&seq).Error
+	return seq, err
+}
+
+// ListWithoutKey returns tickets created before keys were introduced, in ID order
+func (r *TicketRepository) ListWithoutKey(ctx context.Context, afterID uint, limit int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("id > ? AND key IS NULL", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// GetIDByKey returns the ID of the ticket with the given key
+func (r *TicketRepository) GetIDByKey(ctx context.Context, key string) (uint, error) {
+	var ticket domain.Ticket
+	err := r.db.WithContext(ctx).Select("id").Where("key = ?", key).First(&ticket).Error
+	return ticket.ID, err
+}
+
 func (r *TicketRepository) GetByID(ctx context.Context, id uint) (*domain.Ticket, error) {
+	return r.getByID(ctx, id, true, false)
+}
+
+// GetByIDForViewer loads a ticket for display. Internal notes and deleted
+// comments are only preloaded for staff.
+func (r *TicketRepository) GetByIDForViewer(ctx context.Context, id uint, staff bool) (*domain.Ticket, error) {
+	return r.getByID(ctx, id, staff, staff)
+}
+
+// GetByIDShallow loads a ticket without any of its associations
+func (r *TicketRepository) GetByIDShallow(ctx context.Context, id uint) (*domain.Ticket, error) {
 	var ticket domain.Ticket
-	err := r.db.WithContext(ctx).Preload("Requester").Preload("Assignee").Preload("Comments.Author").First(&ticket, id).Error
+	err := r.db.WithContext(ctx).First(&ticket, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &ticket, nil
 }
 
+func (r *TicketRepository) getByID(ctx context.Context, id uint, includeInternal, includeDeleted bool) (*domain.Ticket, error) {
+	comments := func(db *gorm.DB) *gorm.DB {
+		if !includeInternal {
+			db = db.Where("is_public = ?", true)
+		}
+		if includeDeleted {
+			db = db.Unscoped().Preload("DeletedBy")
+		}
+		return db.Order("created_at ASC")
+	}
+
+	var ticket domain.Ticket
+	err := r.db.WithContext(ctx).
+		Preload("Requester").
+		Preload("Assignee").
+		Preload("Computer").
+		Preload("Tags").
+		Preload("Comments", comments).
+		Preload("Comments.Author").
+		First(&ticket, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// Update saves the ticket if it is unchanged since it was read and bumps its version.
+// It returns ErrVersionConflict when another update got there first. The SLA
+// warning and alert start are left alone, as the SLA monitor and alert intake
+// set them without bumping the version; use ClearSLAWarning to re-arm the warning.
 func (r *TicketRepository) Update(ctx context.Context, ticket *domain.Ticket) error {
-	return r.db.WithContext(ctx).Save(ticket).Error
+	return updateVersioned(r.db.WithContext(ctx), ticket, &ticket.Version, "sla_warned_at", "alert_starts_at")
 }
 
 func (r *TicketRepository) Delete(ctx context.Context, id uint) error {
@@ -67,6 +203,78 @@ func (r *TicketRepository) ListByAssignee(ctx context.Context, assigneeID uint,
 	return tickets, err
 }
 
+// FindIDs returns the IDs of tickets matching the filter, up to limit
+func (r *TicketRepository) FindIDs(ctx context.Context, filter TicketFilter, limit int) ([]uint, error) {
+	query := r.db.WithContext(ctx).Model(&domain.Ticket{})
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Priority != "" {
+		query = query.Where("priority = ?", filter.Priority)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.AssigneeID != nil {
+		query = query.Where("assignee_id = ?", *filter.AssigneeID)
+	}
+	if filter.Unassigned {
+		query = query.Where("assignee_id IS NULL")
+	}
+	if filter.RequesterID != nil {
+		query = query.Where("requester_id = ?", *filter.RequesterID)
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", r.db.Table("ticket_tags").
+			Select("ticket_tags.ticket_id").
+			Joins("JOIN tags ON tags.id = ticket_tags.tag_id").
+			Where("tags.name = ?", filter.Tag))
+	}
+	if filter.Search != "" {
+		query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	var ids []uint
+	err := query.Order("id ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// AddTags attaches the named tags to a ticket, creating tags that do not exist yet
+func (r *TicketRepository) AddTags(ctx context.Context, ticketID uint, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		tag := domain.Tag{Name: name}
+		if err := r.db.WithContext(ctx).Where(domain.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+
+		err := r.db.WithContext(ctx).Exec(
+			"INSERT INTO ticket_tags (ticket_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			ticketID, tag.ID,
+		).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveTags detaches the named tags from a ticket
+func (r *TicketRepository) RemoveTags(ctx context.Context, ticketID uint, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Exec(
+		"DELETE FROM ticket_tags WHERE ticket_id = ? AND tag_id IN (SELECT id FROM tags WHERE name IN ?)",
+		ticketID, names,
+	).Error
+}
+
 // Dashboard statistics methods
 func (r *TicketRepository) GetTotalCount(ctx context.Context) (int, error) {
 	var count int64
@@ -94,6 +302,28 @@ func (r *TicketRepository) GetSLABreachesCount(ctx context.Context) (int, error)
 	return int(count), err
 }
 
+// CountOpenByPriority returns the number of open and in-progress tickets per priority
+func (r *TicketRepository) CountOpenByPriority(ctx context.Context) (map[domain.TicketPriority]int, error) {
+	var rows []struct {
+		Priority domain.TicketPriority
+		Count    int
+	}
+	err := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Select("priority, COUNT(*) AS count").
+		Where("status IN ?", openStatuses).
+		Group("priority").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[domain.TicketPriority]int, len(rows))
+	for _, row := range rows {
+		counts[row.Priority] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *TicketRepository) GetResolvedTodayCount(ctx context.Context) (int, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&domain.Ticket{}).
@@ -179,3 +409,124 @@ func (r *TicketRepository) GetRecentTickets(ctx context.Context, limit int) ([]d
 		Find(&tickets).Error
 	return tickets, err
 }
+
+// ListResolved returns resolved and closed tickets, most recently resolved
+// first. A requesterID other than zero limits them to that requester's tickets.
+func (r *TicketRepository) ListResolved(ctx context.Context, requesterID uint, limit int) ([]domain.Ticket, error) {
+	query := r.db.WithContext(ctx).
+		Where("status IN ?", []domain.TicketStatus{domain.ResolvedStatus, domain.ClosedStatus})
+	if requesterID != 0 {
+		query = query.Where("requester_id = ?", requesterID)
+	}
+
+	var tickets []domain.Ticket
+	err := query.Order("resolved_at DESC NULLS LAST, id DESC").Limit(limit).Find(&tickets).Error
+	return tickets, err
+}
+
+// ListDuplicateCandidates returns open tickets created since the given time
+// that are not duplicates themselves, newest first
+func (r *TicketRepository) ListDuplicateCandidates(ctx context.Context, since time.Time, limit int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []domain.TicketStatus{domain.OpenStatus, domain.InProgressStatus}).
+		Where("created_at >= ? AND duplicate_of_id IS NULL", since).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// ListDuplicates returns the tickets marked as duplicates of a ticket
+func (r *TicketRepository) ListDuplicates(ctx context.Context, id uint) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Preload("Requester").
+		Where("duplicate_of_id = ?", id).
+		Order("created_at ASC, id ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// CreateStatusChange records a status transition of a ticket
+func (r *TicketRepository) CreateStatusChange(ctx context.Context, change *domain.TicketStatusChange) error {
+	return r.db.WithContext(ctx).Create(change).Error
+}
+
+// ListUnrendered returns up to limit tickets after afterID whose description
+// HTML has not been rendered yet or still embeds images by unsigned URLs.
+// Descriptions that render to nothing are stored as ” and not returned again.
+func (r *TicketRepository) ListUnrendered(ctx context.Context, afterID uint, limit int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Where("description_html IS NULL OR (description_html LIKE ? AND description_html NOT LIKE ?)", unsignedImagePattern, signedImagePattern).
+		Order("id ASC").
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// SetDescriptionHTML stores rendered HTML without touching the version or timestamps.
+// Nothing is stored if the record was saved since version was read, as that
+// save rendered its own HTML.
+func (r *TicketRepository) SetDescriptionHTML(ctx context.Context, id uint, version int, html string) error {
+	return r.db.WithContext(ctx).Model(&domain.Ticket{}).Where("id = ? AND version = ?", id, version).UpdateColumn("description_html", html).Error
+}
+
+// ListSLAAtRisk returns unresolved tickets breaching their SLA between after
+// and before that have not been warned about yet
+func (r *TicketRepository) ListSLAAtRisk(ctx context.Context, after, before time.Time, limit int) ([]domain.Ticket, error) {
+	var tickets []domain.Ticket
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []domain.TicketStatus{domain.OpenStatus, domain.InProgressStatus}).
+		Where("sla_breach_at > ? AND sla_breach_at <= ? AND sla_warned_at IS NULL", after, before).
+		Order("sla_breach_at ASC").
+		Limit(limit).
+		Find(&tickets).Error
+	return tickets, err
+}
+
+// MarkSLAWarned records that an SLA warning was raised for a ticket. It
+// reports false if the ticket had been warned about already, such as by
+// another server, so that only one of them raises the warning.
+func (r *TicketRepository) MarkSLAWarned(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.Ticket{}).
+		Where("id = ? AND sla_warned_at IS NULL", id).
+		UpdateColumn("sla_warned_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ClearSLAWarning re-arms the SLA warning of a ticket whose deadline moved
+func (r *TicketRepository) ClearSLAWarning(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Ticket{}).Where("id = ?", id).UpdateColumn("sla_warned_at", nil).Error
+}
+
+// EnsureAlertIndex creates the index that allows one ticket per alert
+// fingerprint that is not closed, so that intake on several servers cannot
+// open duplicates
+func (r *TicketRepository) EnsureAlertIndex(ctx context.Context) error {
+	return r.db.WithContext(ctx).Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_tickets_open_alert_fingerprint
+		ON tickets (alert_fingerprint)
+		WHERE alert_fingerprint IS NOT NULL AND status <> 'closed' AND deleted_at IS NULL`).Error
+}
+
+// SetAlertStartsAt records the start of the alert firing a ticket tracks
+func (r *TicketRepository) SetAlertStartsAt(ctx context.Context, id uint, startsAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.Ticket{}).Where("id = ?", id).UpdateColumn("alert_starts_at", startsAt).Error
+}
+
+// FindOpenByAlertFingerprint returns the most recent ticket opened for an
+// alert that is not closed yet. Resolved tickets are included so that a
+// re-firing alert can reopen them.
+func (r *TicketRepository) FindOpenByAlertFingerprint(ctx context.Context, fingerprint string) (*domain.Ticket, error) {
+	var ticket domain.Ticket
+	err := r.db.WithContext(ctx).
+		Where("alert_fingerprint = ? AND status <> ?", fingerprint, domain.ClosedStatus).
+		Order("created_at DESC, id DESC").
+		First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// TxManager runs units of work inside a database transaction
+type TxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// Tx exposes repositories bound to a single transaction
+type Tx struct {
+	db         *gorm.DB
+	Tickets    *TicketRepository
+	Comments   *CommentRepository
+	Priorities *PriorityRepository
+	Changes    *ChangeRepository
+	BulkJobs   *BulkJobRepository
+
+	savepoints int
+}
+
+// Run executes fn in a transaction. The transaction is committed when fn
+// returns nil and rolled back otherwise.
+func (m *TxManager) Run(ctx context.Context, fn func(tx *Tx) error) error {
+	return m.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return fn(&Tx{
+			db:         db,
+			Tickets:    NewTicketRepository(db),
+			Comments:   NewCommentRepository(db),
+			Priorities: NewPriorityRepository(db),
+			Changes:    NewChangeRepository(db),
+			BulkJobs:   NewBulkJobRepository(db),
+		})
+	})
+}
+
+// Savepoint runs fn inside a savepoint so that a failure only rolls back
+// the work done by fn while keeping the rest of the transaction intact.
+func (tx *Tx) Savepoint(fn func() error) error {
+	tx.savepoints++
+	name := fmt.Sprintf("sp_%d", tx.savepoints)
+
+	if err := tx.db.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if rbErr := tx.db.RollbackTo(name).Error; rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	return nil
+}
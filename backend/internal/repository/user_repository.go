@@ -50,3 +50,34 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]domain.
 	err := r.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&users).Error
 	return users, err
 }
+
+// FindByHandles returns active users whose email local part or first name
+// matches one of the given lowercase handles
+func (r *UserRepository) FindByHandles(ctx context.Context, handles []string) ([]domain.User, error) {
+	var users []domain.User
+	if len(handles) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("is_active = ?", true).
+		Where("LOWER(SPLIT_PART(email, '@', 1)) IN ? OR LOWER(first_name) IN ?", handles, handles).
+		Find(&users).Error
+	return users, err
+}
+
+// ListActiveByIDs returns the active users among the given IDs
+func (r *UserRepository) ListActiveByIDs(ctx context.Context, ids []uint) ([]domain.User, error) {
+	var users []domain.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ? AND is_active = ?", ids, true).Find(&users).Error
+	return users, err
+}
+
+// ListActiveByRoles returns the active users having one of the given roles
+func (r *UserRepository) ListActiveByRoles(ctx context.Context, roles ...domain.UserRole) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).Where("role IN ? AND is_active = ?", roles, true).Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when a record was modified by someone else
+// between being read and being written back.
+var ErrVersionConflict = errors.New("record was modified by another request")
+
+// updateVersioned writes every column of model except those in omit, but only
+// if the stored row still has the version the caller read. On success the
+// version is bumped. Columns maintained outside of the versioned updates, such
+// as counters, belong in omit so that saving a stale copy cannot undo them.
+func updateVersioned(db *gorm.DB, model interface{}, version *int, omit ...string) error {
+	expected := *version
+	*version = expected + 1
+
+	result := db.Model(model).
+		Where("version = ?", expected).
+		Select("*").
+		Omit(append([]string{clause.Associations, "created_at"}, omit...)...).
+		Updates(model)
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = expected
+		return ErrVersionConflict
+	}
+	return nil
+}
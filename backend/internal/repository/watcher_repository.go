@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WatcherRepository struct {
+	db *gorm.DB
+}
+
+func NewWatcherRepository(db *gorm.DB) *WatcherRepository {
+	return &WatcherRepository{db: db}
+}
+
+// Add subscribes users to a ticket, ignoring existing subscriptions
+func (r *WatcherRepository) Add(ctx context.Context, ticketID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	watchers := make([]domain.TicketWatcher, 0, len(userIDs))
+	for _, userID := range userIDs {
+		watchers = append(watchers, domain.TicketWatcher{TicketID: ticketID, UserID: userID})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&watchers).Error
+}
+
+// ListByTicket returns the users watching a ticket
+func (r *WatcherRepository) ListByTicket(ctx context.Context, ticketID uint) ([]domain.TicketWatcher, error) {
+	var watchers []domain.TicketWatcher
+	err := r.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&watchers).Error
+	return watchers, err
+}
+
+// UserIDsByTicket returns the IDs of the users watching a ticket
+func (r *WatcherRepository) UserIDsByTicket(ctx context.Context, ticketID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&domain.TicketWatcher{}).
+		Where("ticket_id = ?", ticketID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id uint) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// Delete removes a webhook together with its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&domain.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepository) ListActive(ctx context.Context) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	err := r.db.WithContext(ctx).Where("is_active = ?", true).Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *WebhookRepository) GetDelivery(ctx context.Context, webhookID, id uint) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID).First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+// CountDeliveriesByStatus returns the number of deliveries in the given state
+func (r *WebhookRepository) CountDeliveriesByStatus(ctx context.Context, status domain.WebhookDeliveryStatus) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).Where("status = ?", status).Count(&count).Error
+	return int(count), err
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first. An empty
+// status returns deliveries in every state.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uint, status domain.WebhookDeliveryStatus, limit, offset int) ([]domain.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []domain.WebhookDelivery
+	err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDue returns pending deliveries whose next attempt is due and pushes
+// their next attempt back by lease, so that other workers skip them and a
+// crashed worker's deliveries are picked up again once the lease expires.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	var claimed []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.WebhookDeliveryPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(limit).
+			Find(&claimed).Error
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(claimed))
+		for _, delivery := range claimed {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&domain.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return claimed, err
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WorkLogRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkLogRepository(db *gorm.DB) *WorkLogRepository {
+	return &WorkLogRepository{db: db}
+}
+
+// TimeTotals holds the aggregated work log minutes for a single ticket
+type TimeTotals struct {
+	TicketID        uint
+	TotalMinutes    int
+	BillableMinutes int
+}
+
+// TimeReportFilter narrows the work logs included in a time report
+type TimeReportFilter struct {
+	From     *time.Time
+	To       *time.Time
+	AgentID  *uint
+	Billable *bool
+}
+
+// TimeReportRow is one group of a time report
+type TimeReportRow struct {
+	Key             string `json:"key"`
+	Label           string `json:"label"`
+	TotalMinutes    int    `json:"total_minutes"`
+	BillableMinutes int    `json:"billable_minutes"`
+	Entries         int    `json:"entries"`
+	Tickets         int    `json:"tickets"`
+}
+
+func (r *WorkLogRepository) Create(ctx context.Context, workLog *domain.WorkLog) error {
+	return r.db.WithContext(ctx).Create(workLog).Error
+}
+
+func (r *WorkLogRepository) GetByID(ctx context.Context, id uint) (*domain.WorkLog, error) {
+	var workLog domain.WorkLog
+	err := r.db.WithContext(ctx).Preload("Agent").First(&workLog, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &workLog, nil
+}
+
+func (r *WorkLogRepository) Update(ctx context.Context, workLog *domain.WorkLog) error {
+	return r.db.WithContext(ctx).Save(workLog).Error
+}
+
+func (r *WorkLogRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.WorkLog{}, id).Error
+}
+
+func (r *WorkLogRepository) ListByTicket(ctx context.Context, ticketID uint) ([]domain.WorkLog, error) {
+	var workLogs []domain.WorkLog
+	err := r.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Preload("Agent").
+		Order("work_date ASC, created_at ASC").
+		Find(&workLogs).Error
+	return workLogs, err
+}
+
+// TotalsByTicket returns the logged and billable minutes for each of the given tickets
+func (r *WorkLogRepository) TotalsByTicket(ctx context.Context, ticketIDs []uint) (map[uint]TimeTotals, error) {
+	totals := make(map[uint]TimeTotals)
+	if len(ticketIDs) == 0 {
+		return totals, nil
+	}
+
+	var rows []TimeTotals
+	err := r.db.WithContext(ctx).Model(&domain.WorkLog{}).
+		Select("ticket_id, COALESCE(SUM(duration_minutes), 0) AS total_minutes, "+
+			"COALESCE(SUM(CASE WHEN billable THEN duration_minutes ELSE 0 END), 0) AS billable_minutes").
+		Where("ticket_id IN ?", ticketIDs).
+		Group("ticket_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		totals[row.TicketID] = row
+	}
+	return totals, nil
+}
+
+// Timer methods
+
+func (r *WorkLogRepository) GetTimer(ctx context.Context, ticketID, agentID uint) (*domain.WorkTimer, error) {
+	var timer domain.WorkTimer
+	err := r.db.WithContext(ctx).Where("ticket_id = ? AND agent_id = ?", ticketID, agentID).First(&timer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &timer, nil
+}
+
+// CreateTimer returns ErrDuplicate when the agent already has a timer running
+// on the ticket
+func (r *WorkLogRepository) CreateTimer(ctx context.Context, timer *domain.WorkTimer) error {
+	return translateUnique(r.db.WithContext(ctx).Create(timer).Error)
+}
+
+func (r *WorkLogRepository) ListTimersByAgent(ctx context.Context, agentID uint) ([]domain.WorkTimer, error) {
+	var timers []domain.WorkTimer
+	err := r.db.WithContext(ctx).Where("agent_id = ?", agentID).Order("started_at ASC").Find(&timers).Error
+	return timers, err
+}
+
+// StopTimer converts a running timer into a work log in a single transaction.
+// The timer is deleted first so that of two concurrent stops only one logs
+// the time; the other gets gorm.ErrRecordNotFound.
+func (r *WorkLogRepository) StopTimer(ctx context.Context, timer *domain.WorkTimer, workLog *domain.WorkLog) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&domain.WorkTimer{}, timer.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(workLog).Error
+	})
+}
+
+// Reporting
+
+// SummarizeBy aggregates work logs by agent, category, department or computer.
+// Departments are those of the ticket requester, which is what chargeback uses.
+func (r *WorkLogRepository) SummarizeBy(ctx context.Context, groupBy string, filter TimeReportFilter) ([]TimeReportRow, error) {
+	query := r.db.WithContext(ctx).Model(&domain.WorkLog{}).
+		Joins("JOIN tickets ON tickets.id = work_logs.ticket_id")
+
+	var key, label string
+	switch groupBy {
+	case "agent":
+		query = query.Joins("JOIN users agents ON agents.id = work_logs.agent_id")
+		key = "CAST(work_logs.agent_id AS TEXT)"
+		label = "MAX(agents.first_name || ' ' || agents.last_name)"
+	case "category":
+		key = "COALESCE(NULLIF(tickets.category, ''), 'uncategorized')"
+		label = key
+	case "department":
+		query = query.Joins("JOIN users requesters ON requesters.id = tickets.requester_id")
+		key = "COALESCE(NULLIF(requesters.department, ''), 'unknown')"
+		label = key
+	case "computer":
+		query = query.Joins("LEFT JOIN computers ON computers.id = tickets.computer_id")
+		key = "COALESCE(CAST(tickets.computer_id AS TEXT), 'none')"
+		label = "COALESCE(MAX(computers.hostname), 'No computer')"
+	default:
+		return nil, fmt.Errorf("unsupported time report grouping: %s", groupBy)
+	}
+
+	if filter.From != nil {
+		query = query.Where("work_logs.work_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("work_logs.work_date <= ?", *filter.To)
+	}
+	if filter.AgentID != nil {
+		query = query.Where("work_logs.agent_id = ?", *filter.AgentID)
+	}
+	if filter.Billable != nil {
+		query = query.Where("work_logs.billable = ?", *filter.Billable)
+	}
+
+	var rows []TimeReportRow
+	err := query.
+		Select(key + " AS key, " + label + " AS label, " +
+			"SUM(work_logs.duration_minutes) AS total_minutes, " +
+			"SUM(CASE WHEN work_logs.billable THEN work_logs.duration_minutes ELSE 0 END) AS billable_minutes, " +
+			"COUNT(*) AS entries, COUNT(DISTINCT work_logs.ticket_id) AS tickets").
+		Group(key).
+		Order("total_minutes DESC").
+		Scan(&rows).Error
+	return rows, err
+}
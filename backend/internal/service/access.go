@@ -0,0 +1,20 @@
+package service
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+)
+
+// ticketForViewer loads a ticket without associations and checks that the
+// viewer may see it: staff see every ticket, end users only their own
+func ticketForViewer(ctx context.Context, ticketRepo *repository.TicketRepository, ticketID uint, viewer domain.Viewer) (*domain.Ticket, error) {
+	ticket, err := ticketRepo.GetByIDShallow(ctx, ticketID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if !viewer.IsStaff() && ticket.RequesterID != viewer.UserID {
+		return nil, ErrForbidden
+	}
+	return ticket, nil
+}
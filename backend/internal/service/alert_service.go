@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AlertStatus is the state reported by the monitoring system
+type AlertStatus string
+
+const (
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// Alert is a monitoring alert in the format-independent form the intake works with
+type Alert struct {
+	Fingerprint string
+	Status      AlertStatus
+	Severity    string
+	Title       string
+	Description string
+	Source      string
+	URL         string
+	Labels      map[string]string
+	StartsAt    time.Time
+	EndsAt      time.Time
+}
+
+// AlertResult reports what the intake did with one alert
+type AlertResult struct {
+	Fingerprint string `json:"fingerprint"`
+	TicketID    uint   `json:"ticket_id,omitempty"`
+	Action      string `json:"action"` // created, commented, reopened, resolved or ignored
+	Error       string `json:"error,omitempty"`
+}
+
+// ErrAlertIntakeDisabled is returned when no requester is configured for alert tickets
+var ErrAlertIntakeDisabled = errors.New("alert intake is not configured")
+
+// AlertService opens one ticket per alert fingerprint. A new firing of the
+// alert is added to its ticket as a comment and the ticket is resolved when
+// the alert resolves.
+type AlertService struct {
+	ticketRepo     *repository.TicketRepository
+	userRepo       *repository.UserRepository
+	ticketService  *TicketService
+	commentService *CommentService
+	requesterEmail string
+	category       string
+
+	// Alerts with the same fingerprint are processed one at a time. Across
+	// servers, a unique index keeps concurrent notifications from opening
+	// duplicate tickets.
+	locks [64]sync.Mutex
+}
+
+// NewAlertService creates the service. Alert tickets are filed by the user
+// with requesterEmail, which also authors the follow-up comments.
+func NewAlertService(ticketRepo *repository.TicketRepository, userRepo *repository.UserRepository, ticketService *TicketService, commentService *CommentService, requesterEmail, category string) *AlertService {
+	return &AlertService{
+		ticketRepo:     ticketRepo,
+		userRepo:       userRepo,
+		ticketService:  ticketService,
+		commentService: commentService,
+		requesterEmail: requesterEmail,
+		category:       category,
+	}
+}
+
+// EnsureIndex creates the index that keeps each alert fingerprint to one
+// ticket that is not closed
+func (s *AlertService) EnsureIndex(ctx context.Context) error {
+	return s.ticketRepo.EnsureAlertIndex(ctx)
+}
+
+// Ingest processes a batch of alerts. A failure on one alert is reported in
+// its result and does not stop the others.
+func (s *AlertService) Ingest(ctx context.Context, alerts []Alert) ([]AlertResult, error) {
+	if s.requesterEmail == "" {
+		return nil, ErrAlertIntakeDisabled
+	}
+	requester, err := s.userRepo.GetByEmail(ctx, s.requesterEmail)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: requester %s does not exist", ErrAlertIntakeDisabled, s.requesterEmail)
+		}
+		return nil, err
+	}
+	viewer := domain.Viewer{UserID: requester.ID, Role: requester.Role}
+
+	results := make([]AlertResult, 0, len(alerts))
+	for _, alert := range alerts {
+		if alert.Fingerprint == "" {
+			alert.Fingerprint = alertFingerprint(alert)
+		}
+
+		result, err := s.ingestOne(ctx, alert, viewer)
+		if err != nil {
+			result = AlertResult{Fingerprint: alert.Fingerprint, Action: "ignored", Error: err.Error()}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *AlertService) ingestOne(ctx context.Context, alert Alert, viewer domain.Viewer) (AlertResult, error) {
+	h := fnv.New32a()
+	h.Write([]byte(alert.Fingerprint))
+	lock := &s.locks[h.Sum32()%uint32(len(s.locks))]
+	lock.Lock()
+	defer lock.Unlock()
+
+	result := AlertResult{Fingerprint: alert.Fingerprint}
+	receivedAt := time.Now()
+
+	ticket, err := s.ticketRepo.FindOpenByAlertFingerprint(ctx, alert.Fingerprint)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, err
+	}
+
+	if alert.Status == AlertResolved {
+		if ticket == nil || ticket.Status == domain.ResolvedStatus {
+			result.Action = "ignored"
+			if ticket != nil {
+				result.TicketID = ticket.ID
+			}
+			return result, nil
+		}
+
+		result.TicketID = ticket.ID
+		if err := s.comment(ctx, ticket.ID, fmt.Sprintf("Alert resolved at %s.", alertTime(alert.EndsAt)), viewer); err != nil {
+			return result, err
+		}
+		if err := s.setStatus(ctx, ticket, domain.ResolvedStatus, viewer.UserID); err != nil {
+			return result, err
+		}
+		result.Action = "resolved"
+		return result, nil
+	}
+
+	if ticket == nil {
+		created, err := s.open(ctx, alert, viewer)
+		if err == nil {
+			result.TicketID = created.ID
+			result.Action = "created"
+			return result, nil
+		}
+		if !errors.Is(err, repository.ErrDuplicate) {
+			return result, err
+		}
+
+		// Another server opened the ticket first, so this is a repeat
+		if ticket, err = s.ticketRepo.FindOpenByAlertFingerprint(ctx, alert.Fingerprint); err != nil {
+			return result, err
+		}
+	}
+
+	result.TicketID = ticket.ID
+	startsAt := alertStart(alert)
+	content := fmt.Sprintf("Alert fired again at %s (severity: %s).", alertTime(receivedAt), alertSeverity(alert.Severity))
+	if ticket.Status == domain.ResolvedStatus {
+		if err := s.comment(ctx, ticket.ID, content+" Reopening the ticket.", viewer); err != nil {
+			return result, err
+		}
+		if err := s.setStatus(ctx, ticket, domain.OpenStatus, viewer.UserID); err != nil {
+			return result, err
+		}
+		if err := s.ticketRepo.SetAlertStartsAt(ctx, ticket.ID, startsAt); err != nil {
+			return result, err
+		}
+		result.Action = "reopened"
+		return result, nil
+	}
+
+	// Alertmanager repeats unchanged notifications of a firing alert; only a
+	// new firing is worth a comment
+	if sameTime(ticket.AlertStartsAt, startsAt) {
+		result.Action = "ignored"
+		return result, nil
+	}
+	if err := s.comment(ctx, ticket.ID, content, viewer); err != nil {
+		return result, err
+	}
+	if err := s.ticketRepo.SetAlertStartsAt(ctx, ticket.ID, startsAt); err != nil {
+		return result, err
+	}
+	result.Action = "commented"
+	return result, nil
+}
+
+// open files the ticket for a newly firing alert. It returns
+// repository.ErrDuplicate when the alert already has a ticket that is not closed.
+func (s *AlertService) open(ctx context.Context, alert Alert, viewer domain.Viewer) (*domain.Ticket, error) {
+	fingerprint := alert.Fingerprint
+	ticket := &domain.Ticket{
+		Title:            alert.Title,
+		Description:      alertDescription(alert),
+		Status:           domain.OpenStatus,
+		Category:         s.category,
+		RequesterID:      viewer.UserID,
+		AlertFingerprint: &fingerprint,
+		AlertStartsAt:    alertStart(alert),
+
+		// The severity decides the priority; the matrix only applies
+		// when it happens to agree
+		Priority:               SeverityToPriority(alert.Severity),
+		PriorityOverrideReason: "Alert severity: " + alertSeverity(alert.Severity),
+	}
+	if err := s.ticketService.CreateTicket(ctx, ticket, viewer.UserID); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+func (s *AlertService) comment(ctx context.Context, ticketID uint, content string, viewer domain.Viewer) error {
+	return s.commentService.CreateComment(ctx, &domain.Comment{
+		TicketID: ticketID,
+		Content:  content,
+		IsPublic: true,
+	}, viewer)
+}
+
+// setStatus moves the ticket to status, reloading it once if an agent
+// changed it in the meantime
+func (s *AlertService) setStatus(ctx context.Context, ticket *domain.Ticket, status domain.TicketStatus, actorID uint) error {
+	for attempt := 0; ; attempt++ {
+		ticket.Status = status
+		if status == domain.OpenStatus {
+			ticket.ResolvedAt = nil
+		}
+
+		err := s.ticketService.UpdateTicket(ctx, ticket, actorID)
+		if !errors.Is(err, repository.ErrVersionConflict) || attempt > 0 {
+			return err
+		}
+
+		current, getErr := s.ticketRepo.GetByIDShallow(ctx, ticket.ID)
+		if getErr != nil {
+			return getErr
+		}
+		*ticket = *current
+	}
+}
+
+// SeverityToPriority maps the severity names used by common monitoring
+// systems to a ticket priority. Unknown severities map to medium.
+func SeverityToPriority(severity string) domain.TicketPriority {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical", "crit", "fatal", "emergency", "page", "p1", "disaster":
+		return domain.CriticalPriority
+	case "error", "err", "high", "major", "p2":
+		return domain.HighPriority
+	case "info", "informational", "low", "minor", "none", "p4", "p5":
+		return domain.LowPriority
+	default:
+		return domain.MediumPriority
+	}
+}
+
+// alertFingerprint derives a stable fingerprint for alerts that do not carry one
+func alertFingerprint(alert Alert) string {
+	keys := make([]string, 0, len(alert.Labels))
+	for key := range alert.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	if len(keys) == 0 {
+		fmt.Fprintf(h, "%s\x00%s", alert.Source, alert.Title)
+	}
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", key, alert.Labels[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// alertDescription renders the alert details as the Markdown ticket description
+func alertDescription(alert Alert) string {
+	var b strings.Builder
+	if alert.Description != "" {
+		b.WriteString(alert.Description)
+		b.WriteString("\n\n")
+	}
+
+	fmt.Fprintf(&b, "- **Severity:** %s\n", alertSeverity(alert.Severity))
+	if alert.Source != "" {
+		fmt.Fprintf(&b, "- **Source:** %s\n", alert.Source)
+	}
+	fmt.Fprintf(&b, "- **Started:** %s\n", alertTime(alert.StartsAt))
+	fmt.Fprintf(&b, "- **Fingerprint:** `%s`\n", alert.Fingerprint)
+	if alert.URL != "" {
+		fmt.Fprintf(&b, "- **Link:** <%s>\n", alert.URL)
+	}
+
+	if len(alert.Labels) > 0 {
+		keys := make([]string, 0, len(alert.Labels))
+		for key := range alert.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("\n**Labels**\n\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "- `%s`: `%s`\n", key, alert.Labels[key])
+		}
+	}
+	return b.String()
+}
+
+func alertSeverity(severity string) string {
+	if severity == "" {
+		return "unknown"
+	}
+	return severity
+}
+
+// alertStart returns when the alert started firing, or nil if it did not say
+func alertStart(alert Alert) *time.Time {
+	if alert.StartsAt.IsZero() {
+		return nil
+	}
+	startsAt := alert.StartsAt.UTC()
+	return &startsAt
+}
+
+func alertTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidArticle is returned when a knowledge base article fails validation
+var ErrInvalidArticle = errors.New("invalid article")
+
+// ArticleService manages the knowledge base. Staff see every article; end
+// users only see published public ones.
+type ArticleService struct {
+	articleRepo *repository.ArticleRepository
+	ticketRepo  *repository.TicketRepository
+}
+
+func NewArticleService(articleRepo *repository.ArticleRepository, ticketRepo *repository.TicketRepository) *ArticleService {
+	return &ArticleService{
+		articleRepo: articleRepo,
+		ticketRepo:  ticketRepo,
+	}
+}
+
+// EnsureSearchIndex creates the full-text search index if it is missing
+func (s *ArticleService) EnsureSearchIndex(ctx context.Context) error {
+	return s.articleRepo.EnsureSearchIndex(ctx)
+}
+
+func (s *ArticleService) CreateArticle(ctx context.Context, article *domain.Article, authorID uint) error {
+	if article.Status == "" {
+		article.Status = domain.ArticleDraft
+	}
+	if article.Visibility == "" {
+		article.Visibility = domain.PublicArticle
+	}
+	if err := s.prepare(ctx, article); err != nil {
+		return err
+	}
+
+	article.AuthorID = authorID
+	return s.articleRepo.Create(ctx, article)
+}
+
+// GetArticle returns an article if the viewer may read it
+func (s *ArticleService) GetArticle(ctx context.Context, id uint, viewer domain.Viewer) (*domain.Article, error) {
+	article, err := s.articleRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !canReadArticle(article, viewer) {
+		return nil, ErrNotFound
+	}
+	return article, nil
+}
+
+// ViewArticle returns an article and counts the view. Only views of
+// published articles are counted.
+func (s *ArticleService) ViewArticle(ctx context.Context, id uint, viewer domain.Viewer) (*domain.Article, error) {
+	article, err := s.GetArticle(ctx, id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if article.Status == domain.ArticlePublished {
+		if err := s.articleRepo.IncrementViews(ctx, id); err != nil {
+			logging.FromContext(ctx).Warn("Failed to count article view", "article_id", id, "error", err)
+		} else {
+			article.ViewCount++
+		}
+	}
+	return article, nil
+}
+
+// ListArticles returns the articles the viewer may read that match the filter
+func (s *ArticleService) ListArticles(ctx context.Context, filter repository.ArticleFilter, viewer domain.Viewer, limit, offset int) ([]domain.Article, error) {
+	return s.articleRepo.List(ctx, articleFilterFor(filter, viewer), limit, offset)
+}
+
+// Categories returns the categories of the articles the viewer may read
+func (s *ArticleService) Categories(ctx context.Context, viewer domain.Viewer) ([]repository.ArticleCategory, error) {
+	return s.articleRepo.Categories(ctx, articleFilterFor(repository.ArticleFilter{}, viewer))
+}
+
+// UpdateArticle saves an edited article as a new version
+func (s *ArticleService) UpdateArticle(ctx context.Context, article *domain.Article, editorID uint) error {
+	if err := s.prepare(ctx, article); err != nil {
+		return err
+	}
+
+	article.UpdatedByID = &editorID
+	article.UpdatedBy = nil
+	return s.articleRepo.Update(ctx, article, editorID)
+}
+
+func (s *ArticleService) DeleteArticle(ctx context.Context, id uint) error {
+	err := s.articleRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListRevisions returns the version history of an article, oldest first
+func (s *ArticleService) ListRevisions(ctx context.Context, id uint, viewer domain.Viewer) ([]domain.ArticleRevision, error) {
+	if _, err := s.GetArticle(ctx, id, viewer); err != nil {
+		return nil, err
+	}
+	return s.articleRepo.ListRevisions(ctx, id)
+}
+
+// ListTickets returns the tickets an article was linked to
+func (s *ArticleService) ListTickets(ctx context.Context, id uint, viewer domain.Viewer) ([]domain.Ticket, error) {
+	if _, err := s.GetArticle(ctx, id, viewer); err != nil {
+		return nil, err
+	}
+	return s.articleRepo.ListTickets(ctx, id)
+}
+
+// ListTicketArticles returns the articles linked to a ticket that the viewer may read
+func (s *ArticleService) ListTicketArticles(ctx context.Context, ticketID uint, viewer domain.Viewer) ([]domain.Article, error) {
+	if _, err := ticketForViewer(ctx, s.ticketRepo, ticketID, viewer); err != nil {
+		return nil, err
+	}
+	return s.articleRepo.ListByTicket(ctx, ticketID, !viewer.IsStaff())
+}
+
+// LinkTicket records that an article solved a ticket
+func (s *ArticleService) LinkTicket(ctx context.Context, articleID, ticketID uint, viewer domain.Viewer) error {
+	if _, err := s.GetArticle(ctx, articleID, viewer); err != nil {
+		return err
+	}
+	if _, err := ticketForViewer(ctx, s.ticketRepo, ticketID, viewer); err != nil {
+		return err
+	}
+	return s.articleRepo.LinkTicket(ctx, &domain.ArticleTicketLink{
+		ArticleID:  articleID,
+		TicketID:   ticketID,
+		LinkedByID: viewer.UserID,
+	})
+}
+
+func (s *ArticleService) UnlinkTicket(ctx context.Context, articleID, ticketID uint) error {
+	err := s.articleRepo.UnlinkTicket(ctx, articleID, ticketID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// prepare validates an article and renders its body
+func (s *ArticleService) prepare(ctx context.Context, article *domain.Article) error {
+	article.Title = strings.TrimSpace(article.Title)
+	article.Category = strings.TrimSpace(article.Category)
+	if article.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidArticle)
+	}
+	if !article.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidArticle, article.Status)
+	}
+	if !article.Visibility.IsValid() {
+		return fmt.Errorf("%w: unknown visibility %q", ErrInvalidArticle, article.Visibility)
+	}
+	if article.Status == domain.ArticlePublished && strings.TrimSpace(article.Body) == "" {
+		return fmt.Errorf("%w: a published article needs a body", ErrInvalidArticle)
+	}
+
+	html, err := renderMarkdown(ctx, nil, 0, article.Body)
+	if err != nil {
+		return err
+	}
+	article.BodyHTML = html
+
+	if article.Status == domain.ArticlePublished && article.PublishedAt == nil {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+	return nil
+}
+
+// canReadArticle reports whether the viewer may read an article. Drafts and
+// archived articles are only shown to staff.
+func canReadArticle(article *domain.Article, viewer domain.Viewer) bool {
+	if viewer.IsStaff() {
+		return true
+	}
+	return article.Status == domain.ArticlePublished && article.Visibility.VisibleTo(viewer.Role)
+}
+
+// articleFilterFor restricts a filter to the articles the viewer may read
+func articleFilterFor(filter repository.ArticleFilter, viewer domain.Viewer) repository.ArticleFilter {
+	if !viewer.IsStaff() {
+		filter.Status = domain.ArticlePublished
+		filter.Visibility = domain.PublicArticle
+	}
+	return filter
+}
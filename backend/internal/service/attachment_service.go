@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrAttachmentTooLarge = errors.New("file exceeds the maximum upload size")
+	ErrAttachmentType     = errors.New("file type is not allowed")
+)
+
+type AttachmentService struct {
+	attachmentRepo *repository.AttachmentRepository
+	ticketRepo     *repository.TicketRepository
+	commentRepo    *repository.CommentRepository
+	uploadDir      string
+	maxSize        int64
+	allowedTypes   map[string]bool
+	imageKey       []byte
+}
+
+// NewAttachmentService creates the service. Files are stored below uploadDir;
+// allowedTypes lists the permitted file extensions without the leading dot.
+// Links to inline images are signed with a key derived from secret.
+func NewAttachmentService(attachmentRepo *repository.AttachmentRepository, ticketRepo *repository.TicketRepository, commentRepo *repository.CommentRepository, uploadDir string, maxSize int64, allowedTypes []string, secret string) *AttachmentService {
+	allowed := make(map[string]bool, len(allowedTypes))
+	for _, ext := range allowedTypes {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			allowed[ext] = true
+		}
+	}
+
+	return &AttachmentService{
+		attachmentRepo: attachmentRepo,
+		ticketRepo:     ticketRepo,
+		commentRepo:    commentRepo,
+		uploadDir:      uploadDir,
+		maxSize:        maxSize,
+		allowedTypes:   allowed,
+		imageKey:       deriveKey(secret, "attachment-images"),
+	}
+}
+
+// Upload stores a file on a ticket the viewer can access. An image is shown
+// right away wherever the ticket or its comments already embed it.
+func (s *AttachmentService) Upload(ctx context.Context, ticketID uint, fileName string, content io.Reader, viewer domain.Viewer) (*domain.Attachment, error) {
+	ticket, err := ticketForViewer(ctx, s.ticketRepo, ticketID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName = filepath.Base(strings.ReplaceAll(fileName, `\`, "/"))
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if !s.allowedTypes[ext] {
+		return nil, ErrAttachmentType
+	}
+
+	dir := filepath.Join(s.uploadDir, "tickets", fmt.Sprint(ticketID))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, name+"."+ext)
+
+	size, contentType, err := s.writeFile(path, content)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	attachment := &domain.Attachment{
+		FileName:     fileName,
+		ContentType:  contentType,
+		Size:         size,
+		StoragePath:  path,
+		TicketID:     ticketID,
+		UploadedByID: viewer.UserID,
+	}
+	if err := s.attachmentRepo.Create(ctx, attachment); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if attachment.IsImage() {
+		// The upload is saved, so a failure only leaves the image as a link
+		if err := s.renderReferences(ctx, ticket, attachment); err != nil {
+			logging.FromContext(ctx).Warn("Failed to render inline image", "attachment_id", attachment.ID, "error", err)
+		}
+	}
+	return attachment, nil
+}
+
+// renderReferences re-renders the ticket description and comments that may
+// embed an image, since images are usually uploaded after the text that
+// refers to them
+func (s *AttachmentService) renderReferences(ctx context.Context, ticket *domain.Ticket, image *domain.Attachment) error {
+	if refersTo(ticket.Description, image) {
+		html, err := renderMarkdown(ctx, s, ticket.ID, ticket.Description)
+		if err != nil {
+			return err
+		}
+		if err := s.ticketRepo.SetDescriptionHTML(ctx, ticket.ID, ticket.Version, html); err != nil {
+			return err
+		}
+	}
+
+	comments, err := s.commentRepo.ListByTicket(ctx, ticket.ID, true, false)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if !refersTo(comment.Content, image) {
+			continue
+		}
+		html, err := renderMarkdown(ctx, s, ticket.ID, comment.Content)
+		if err != nil {
+			return err
+		}
+		if err := s.commentRepo.SetContentHTML(ctx, comment.ID, comment.Version, html); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refersTo reports whether Markdown may embed an attachment, by file name or
+// as "attachment:<id>". Extra matches only cost a render.
+func refersTo(source string, attachment *domain.Attachment) bool {
+	return strings.Contains(strings.ToLower(source), strings.ToLower(attachment.FileName)) ||
+		strings.Contains(source, fmt.Sprintf("attachment:%d", attachment.ID))
+}
+
+// GetAttachment returns an attachment of a ticket the viewer can access
+func (s *AttachmentService) GetAttachment(ctx context.Context, id uint, viewer domain.Viewer) (*domain.Attachment, error) {
+	attachment, err := s.attachmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if _, err := ticketForViewer(ctx, s.ticketRepo, attachment.TicketID, viewer); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// GetSignedImage returns an image attachment for a link produced by
+// ImageURL. Browsers load inline images without the Authorization header, so
+// the signature stands in for it: whoever can read the rendered HTML may load
+// its images.
+func (s *AttachmentService) GetSignedImage(ctx context.Context, id uint, signature string) (*domain.Attachment, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.imageSignature(id))) {
+		return nil, ErrNotFound
+	}
+	attachment, err := s.attachmentRepo.GetByID(ctx, id)
+	if err != nil || !attachment.IsImage() {
+		return nil, ErrNotFound
+	}
+	return attachment, nil
+}
+
+// ImageURL returns the signed link under which an image attachment is embedded
+// in rendered Markdown
+func (s *AttachmentService) ImageURL(id uint) string {
+	return fmt.Sprintf("/api/v1/attachments/%d/image?signature=%s", id, s.imageSignature(id))
+}
+
+func (s *AttachmentService) imageSignature(id uint) string {
+	mac := hmac.New(sha256.New, s.imageKey)
+	fmt.Fprintf(mac, "attachment:%d", id)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ListAttachments returns the attachments of a ticket the viewer can access
+func (s *AttachmentService) ListAttachments(ctx context.Context, ticketID uint, viewer domain.Viewer) ([]domain.Attachment, error) {
+	if _, err := ticketForViewer(ctx, s.ticketRepo, ticketID, viewer); err != nil {
+		return nil, err
+	}
+	return s.attachmentRepo.ListByTicket(ctx, ticketID)
+}
+
+// writeFile copies content to path, enforcing the size limit and sniffing the content type
+func (s *AttachmentService) writeFile(path string, content io.Reader) (int64, string, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, "", err
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	if _, err := file.Write(head); err != nil {
+		return 0, "", err
+	}
+	// Read one byte past the limit to detect oversized files
+	rest, err := io.Copy(file, io.LimitReader(content, s.maxSize-int64(n)+1))
+	if err != nil {
+		return 0, "", err
+	}
+
+	size := int64(n) + rest
+	if size > s.maxSize {
+		return 0, "", ErrAttachmentTooLarge
+	}
+	return size, contentType, nil
+}
+
+// deriveKey derives a key for one purpose from a shared secret, so that a
+// signature made for one purpose is never valid for another
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
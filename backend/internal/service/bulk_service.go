@@ -0,0 +1,500 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"strings"
+	"time"
+)
+
+const (
+	// bulkSyncLimit is the largest ticket set applied inline in the request
+	bulkSyncLimit = 100
+	// bulkMaxTickets caps how many tickets a single bulk operation may touch
+	bulkMaxTickets = 5000
+	// bulkChunkSize is how many tickets a background job commits per transaction
+	bulkChunkSize = 100
+)
+
+// bulkOverrideReason explains priorities set by a bulk update that differ
+// from the one computed from impact and urgency
+const bulkOverrideReason = "Set by bulk update"
+
+var errBulkRolledBack = errors.New("bulk operation rolled back")
+
+// BulkRequest describes a bulk ticket operation
+type BulkRequest struct {
+	TicketIDs []uint
+	Filter    *repository.TicketFilter
+	Changes   domain.BulkTicketChanges
+	Atomic    bool // roll back every change when any ticket fails
+	Async     bool // force a background job regardless of size
+}
+
+// BulkResult is the outcome of a bulk operation applied inline
+type BulkResult struct {
+	Total      int                    `json:"total"`
+	Succeeded  int                    `json:"succeeded"`
+	Failed     int                    `json:"failed"`
+	RolledBack bool                   `json:"rolled_back"`
+	Results    []domain.BulkJobResult `json:"results"`
+
+	jobID uint // the background job the results belong to, if any
+}
+
+func (r *BulkResult) add(ticketID uint, err error) {
+	result := domain.BulkJobResult{JobID: r.jobID, TicketID: ticketID, Success: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// BulkService applies changes to many tickets at once, either inline or as a background job
+type BulkService struct {
+	txManager  *repository.TxManager
+	ticketRepo *repository.TicketRepository
+	userRepo   *repository.UserRepository
+	jobRepo    *repository.BulkJobRepository
+	priorities *PriorityService
+	broker     *events.Broker
+	wake       chan struct{}
+}
+
+// NewBulkService creates a new bulk service
+func NewBulkService(txManager *repository.TxManager, ticketRepo *repository.TicketRepository, userRepo *repository.UserRepository, jobRepo *repository.BulkJobRepository, priorities *PriorityService, broker *events.Broker) *BulkService {
+	return &BulkService{
+		txManager:  txManager,
+		ticketRepo: ticketRepo,
+		userRepo:   userRepo,
+		jobRepo:    jobRepo,
+		priorities: priorities,
+		broker:     broker,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Submit validates a bulk request and either applies it inline, returning
+// per-ticket results, or queues it as a background job.
+func (s *BulkService) Submit(ctx context.Context, req BulkRequest, actorID uint) (*BulkResult, *domain.BulkJob, error) {
+	if err := s.validateChanges(ctx, &req.Changes); err != nil {
+		return nil, nil, err
+	}
+
+	ids, err := s.resolveTicketIDs(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Async || len(ids) > bulkSyncLimit {
+		if req.Atomic {
+			return nil, nil, fmt.Errorf("atomic bulk operations are limited to %d tickets", bulkSyncLimit)
+		}
+		job, err := s.enqueue(ctx, ids, req.Changes, actorID)
+		return nil, job, err
+	}
+
+	result, err := s.applyInline(ctx, 0, ids, req.Changes, actorID, req.Atomic)
+	return result, nil, err
+}
+
+// GetJob returns a background bulk job
+func (s *BulkService) GetJob(ctx context.Context, id uint) (*domain.BulkJob, error) {
+	return s.jobRepo.GetByID(ctx, id)
+}
+
+// ListJobResults returns the per-ticket outcomes recorded for a job
+func (s *BulkService) ListJobResults(ctx context.Context, jobID uint, limit, offset int) ([]domain.BulkJobResult, error) {
+	return s.jobRepo.ListResults(ctx, jobID, limit, offset)
+}
+
+// QueueDepth returns the number of bulk jobs waiting to run
+func (s *BulkService) QueueDepth(ctx context.Context) (int, error) {
+	return s.jobRepo.CountByStatus(ctx, domain.BulkJobQueued)
+}
+
+// Start runs the background worker until ctx is cancelled
+func (s *BulkService) Start(ctx context.Context) {
+	// Jobs left running by a previous process resume where they stopped
+	if err := s.jobRepo.RequeueRunning(ctx); err != nil {
+		logging.FromContext(ctx).Warn("Failed to requeue interrupted bulk jobs", "error", err)
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		s.drainQueue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BulkService) drainQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := s.jobRepo.ClaimNext(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to claim bulk job", "error", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+		s.runJob(ctx, job)
+	}
+}
+
+func (s *BulkService) validateChanges(ctx context.Context, changes *domain.BulkTicketChanges) error {
+	changes.AddTags = normalizeTags(changes.AddTags)
+	changes.RemoveTags = normalizeTags(changes.RemoveTags)
+
+	if changes.Status == nil && changes.Priority == nil && changes.AssigneeID == nil && !changes.Unassign &&
+		len(changes.AddTags) == 0 && len(changes.RemoveTags) == 0 && changes.Comment == nil {
+		return errors.New("no changes specified")
+	}
+
+	if changes.Status != nil && !changes.Status.IsValid() {
+		return fmt.Errorf("invalid status: %s", *changes.Status)
+	}
+	if changes.Priority != nil && !changes.Priority.IsValid() {
+		return fmt.Errorf("invalid priority: %s", *changes.Priority)
+	}
+	if changes.Unassign && changes.AssigneeID != nil {
+		return errors.New("assignee_id and unassign cannot be combined")
+	}
+	if changes.AssigneeID != nil {
+		assignee, err := s.userRepo.GetByID(ctx, *changes.AssigneeID)
+		if err != nil {
+			return errors.New("invalid assignee ID")
+		}
+		if assignee.Role != domain.AgentRole && assignee.Role != domain.AdminRole {
+			return errors.New("tickets can only be assigned to agents or admins")
+		}
+	}
+	if changes.Comment != nil && strings.TrimSpace(changes.Comment.Content) == "" {
+		return errors.New("comment content cannot be empty")
+	}
+	return nil
+}
+
+func (s *BulkService) resolveTicketIDs(ctx context.Context, req BulkRequest) ([]uint, error) {
+	if len(req.TicketIDs) > 0 && req.Filter != nil {
+		return nil, errors.New("specify either ticket_ids or filter, not both")
+	}
+
+	if len(req.TicketIDs) > 0 {
+		if len(req.TicketIDs) > bulkMaxTickets {
+			return nil, fmt.Errorf("a bulk operation is limited to %d tickets", bulkMaxTickets)
+		}
+		return uniqueIDs(req.TicketIDs), nil
+	}
+
+	if req.Filter == nil || req.Filter.IsEmpty() {
+		return nil, errors.New("ticket_ids or a non-empty filter is required")
+	}
+
+	ids, err := s.ticketRepo.FindIDs(ctx, *req.Filter, bulkMaxTickets+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > bulkMaxTickets {
+		return nil, fmt.Errorf("filter matches more than %d tickets", bulkMaxTickets)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("filter matches no tickets")
+	}
+	return ids, nil
+}
+
+// applyInline runs the operation in a single transaction. Each ticket is
+// applied in its own savepoint so one failure does not undo the others,
+// unless atomic is set, in which case any failure rolls back everything.
+// When jobID is set the results are saved to the job in the same
+// transaction, so that a resumed job never applies a ticket twice.
+func (s *BulkService) applyInline(ctx context.Context, jobID uint, ids []uint, changes domain.BulkTicketChanges, actorID uint, atomic bool) (*BulkResult, error) {
+	result := &BulkResult{Total: len(ids), jobID: jobID}
+
+	matrix, err := s.priorities.Matrix(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []events.Event
+	err = s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		for _, id := range ids {
+			result.add(id, tx.Savepoint(func() error {
+				ticketEvents, err := applyBulkChanges(ctx, tx, matrix, id, changes, actorID)
+				if err != nil {
+					return err
+				}
+				pending = append(pending, ticketEvents...)
+				return nil
+			}))
+		}
+		if atomic && result.Failed > 0 {
+			return errBulkRolledBack
+		}
+		if jobID != 0 {
+			return tx.BulkJobs.SaveResults(ctx, result.Results)
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBulkRolledBack) {
+		result.RolledBack = true
+		for i := range result.Results {
+			if result.Results[i].Success {
+				result.Results[i].Success = false
+				result.Results[i].Error = "rolled back because another ticket failed"
+			}
+		}
+		result.Failed, result.Succeeded = result.Total, 0
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range pending {
+		publish(s.broker, event)
+	}
+	return result, nil
+}
+
+func (s *BulkService) enqueue(ctx context.Context, ids []uint, changes domain.BulkTicketChanges, actorID uint) (*domain.BulkJob, error) {
+	encodedChanges, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	encodedIDs, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &domain.BulkJob{
+		Status:        domain.BulkJobQueued,
+		Changes:       string(encodedChanges),
+		TicketIDs:     string(encodedIDs),
+		Total:         len(ids),
+		RequestedByID: actorID,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// Wake the worker without blocking if it is already busy
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// runJob processes a claimed job chunk by chunk, skipping tickets already
+// handled so that interrupted jobs can resume.
+func (s *BulkService) runJob(ctx context.Context, job *domain.BulkJob) {
+	var changes domain.BulkTicketChanges
+	var ids []uint
+	if err := json.Unmarshal([]byte(job.Changes), &changes); err != nil {
+		s.failJob(ctx, job, fmt.Errorf("invalid job changes: %w", err))
+		return
+	}
+	if err := json.Unmarshal([]byte(job.TicketIDs), &ids); err != nil {
+		s.failJob(ctx, job, fmt.Errorf("invalid job ticket IDs: %w", err))
+		return
+	}
+
+	processed, err := s.jobRepo.ProcessedTicketIDs(ctx, job.ID)
+	if err != nil {
+		s.failJob(ctx, job, err)
+		return
+	}
+
+	if job.StartedAt == nil {
+		now := time.Now()
+		job.StartedAt = &now
+	}
+
+	pending := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !processed[id] {
+			pending = append(pending, id)
+		}
+	}
+
+	for start := 0; start < len(pending); start += bulkChunkSize {
+		if ctx.Err() != nil {
+			// Shutting down; the job stays running and is requeued on next start
+			return
+		}
+
+		end := min(start+bulkChunkSize, len(pending))
+		if _, err := s.applyInline(ctx, job.ID, pending[start:end], changes, job.RequestedByID, false); err != nil {
+			// Nothing in the chunk was applied, so record every ticket as failed
+			result := &BulkResult{jobID: job.ID}
+			for _, id := range pending[start:end] {
+				result.add(id, err)
+			}
+			if err := s.jobRepo.SaveResults(ctx, result.Results); err != nil {
+				s.failJob(ctx, job, err)
+				return
+			}
+		}
+
+		if err := s.refreshProgress(ctx, job); err != nil {
+			logging.FromContext(ctx).Warn("Failed to update progress of bulk job", "job_id", job.ID, "error", err)
+		}
+	}
+
+	now := time.Now()
+	job.Status = domain.BulkJobCompleted
+	job.FinishedAt = &now
+	if err := s.refreshProgress(ctx, job); err != nil {
+		logging.FromContext(ctx).Warn("Failed to complete bulk job", "job_id", job.ID, "error", err)
+	}
+}
+
+func (s *BulkService) refreshProgress(ctx context.Context, job *domain.BulkJob) error {
+	succeeded, failed, err := s.jobRepo.CountResults(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	job.Succeeded = succeeded
+	job.Failed = failed
+	job.Processed = succeeded + failed
+	return s.jobRepo.Update(ctx, job)
+}
+
+func (s *BulkService) failJob(ctx context.Context, job *domain.BulkJob, cause error) {
+	now := time.Now()
+	job.Status = domain.BulkJobFailed
+	job.Error = cause.Error()
+	job.FinishedAt = &now
+	if err := s.jobRepo.Update(ctx, job); err != nil {
+		logging.FromContext(ctx).Warn("Failed to mark bulk job as failed", "job_id", job.ID, "error", err)
+	}
+}
+
+// applyBulkChanges applies the changes to one ticket within the transaction.
+// It returns the events to publish once the transaction commits.
+func applyBulkChanges(ctx context.Context, tx *repository.Tx, matrix PriorityMatrix, ticketID uint, changes domain.BulkTicketChanges, actorID uint) ([]events.Event, error) {
+	ticket, err := tx.Tickets.GetByID(ctx, ticketID)
+	if err != nil {
+		return nil, errors.New("ticket not found")
+	}
+	previous := *ticket
+
+	if changes.Status != nil {
+		ticket.Status = *changes.Status
+	}
+	if changes.Priority != nil && *changes.Priority != ticket.Priority {
+		ticket.Priority = *changes.Priority
+		ticket.PriorityOverrideReason = bulkOverrideReason
+	}
+	if changes.Unassign {
+		ticket.AssigneeID = nil
+		ticket.Assignee = nil
+	}
+	if changes.AssigneeID != nil {
+		ticket.AssigneeID = changes.AssigneeID
+		ticket.Assignee = nil
+		if ticket.Status == domain.OpenStatus {
+			ticket.Status = domain.InProgressStatus
+		}
+	}
+
+	override, err := applyPriority(matrix, ticket, &previous, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyTicketRules(ticket)
+	applySLADeadline(&previous, ticket)
+	if err := tx.Tickets.Update(ctx, ticket); err != nil {
+		return nil, err
+	}
+	if err := rearmSLAWarning(ctx, tx, &previous, ticket); err != nil {
+		return nil, err
+	}
+	if err := recordOverride(ctx, tx, ticket, override); err != nil {
+		return nil, err
+	}
+	if change := statusChange(&previous, ticket, actorID); change != nil {
+		if err := tx.Tickets.CreateStatusChange(ctx, change); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Tickets.AddTags(ctx, ticket.ID, changes.AddTags); err != nil {
+		return nil, err
+	}
+	if err := tx.Tickets.RemoveTags(ctx, ticket.ID, changes.RemoveTags); err != nil {
+		return nil, err
+	}
+
+	eventType := events.TicketUpdated
+	if changes.AssigneeID != nil {
+		eventType = events.TicketAssigned
+	}
+	pending := []events.Event{events.NewTicketEvent(eventType, ticket, actorID)}
+
+	if changes.Comment != nil {
+		html, err := renderMarkdown(ctx, nil, 0, changes.Comment.Content)
+		if err != nil {
+			return nil, err
+		}
+		comment := &domain.Comment{
+			Content:     changes.Comment.Content,
+			ContentHTML: html,
+			IsPublic:    changes.Comment.IsPublic,
+			TicketID:    ticket.ID,
+			AuthorID:    actorID,
+		}
+		if err := tx.Comments.Create(ctx, comment); err != nil {
+			return nil, err
+		}
+		pending = append(pending, events.NewCommentEvent(comment, ticket))
+	}
+	return pending, nil
+}
+
+// normalizeTags lowercases, trims and de-duplicates tag names
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidChange is returned when a change request fails validation or
+// cannot move to the requested status
+var ErrInvalidChange = errors.New("invalid change request")
+
+// conflictingChangeStatuses are the statuses whose planned windows can still
+// collide with another change
+var conflictingChangeStatuses = []domain.ChangeStatus{
+	domain.ChangePendingApproval,
+	domain.ChangeApproved,
+	domain.ChangeInProgress,
+}
+
+// ChangeConflict warns that another change works on the same computers in an
+// overlapping window
+type ChangeConflict struct {
+	ChangeID     uint                `json:"change_id"`
+	Title        string              `json:"title"`
+	Status       domain.ChangeStatus `json:"status"`
+	PlannedStart time.Time           `json:"planned_start"`
+	PlannedEnd   time.Time           `json:"planned_end"`
+	Computers    []ConflictComputer  `json:"computers"`
+}
+
+// ConflictComputer is a computer touched by both changes of a conflict
+type ConflictComputer struct {
+	ID       uint   `json:"id"`
+	Hostname string `json:"hostname"`
+}
+
+// ChangeService manages change requests, their CAB approvals and the
+// computers they affect
+type ChangeService struct {
+	txManager  *repository.TxManager
+	changeRepo *repository.ChangeRepository
+	userRepo   *repository.UserRepository
+}
+
+func NewChangeService(txManager *repository.TxManager, changeRepo *repository.ChangeRepository, userRepo *repository.UserRepository) *ChangeService {
+	return &ChangeService{
+		txManager:  txManager,
+		changeRepo: changeRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// CreateChange saves a new change request as a draft and returns the changes
+// it conflicts with
+func (s *ChangeService) CreateChange(ctx context.Context, change *domain.ChangeRequest, computerIDs []uint, actorID uint) ([]ChangeConflict, error) {
+	if change.Type == "" {
+		change.Type = domain.NormalChange
+	}
+	if change.Risk == "" {
+		change.Risk = domain.MediumRisk
+	}
+	change.Status = domain.ChangeDraft
+	change.RequestedByID = actorID
+
+	if err := s.prepare(ctx, change, &computerIDs); err != nil {
+		return nil, err
+	}
+	if err := s.changeRepo.Create(ctx, change); err != nil {
+		return nil, err
+	}
+	return s.conflictsFor(ctx, change)
+}
+
+// GetChange returns a change request with the changes it conflicts with
+func (s *ChangeService) GetChange(ctx context.Context, id uint) (*domain.ChangeRequest, []ChangeConflict, error) {
+	change, err := s.changeRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	conflicts, err := s.conflictsFor(ctx, change)
+	if err != nil {
+		return nil, nil, err
+	}
+	return change, conflicts, nil
+}
+
+func (s *ChangeService) ListChanges(ctx context.Context, filter repository.ChangeFilter, limit, offset int) ([]domain.ChangeRequest, error) {
+	return s.changeRepo.List(ctx, filter, limit, offset)
+}
+
+// UpdateChange saves edits to a change request. Only drafts and rejected
+// changes can be edited; a rejected change becomes a draft again. A nil
+// computerIDs keeps the linked computers.
+func (s *ChangeService) UpdateChange(ctx context.Context, change *domain.ChangeRequest, computerIDs *[]uint) ([]ChangeConflict, error) {
+	if change.Status != domain.ChangeDraft && change.Status != domain.ChangeRejected {
+		return nil, fmt.Errorf("%w: only draft or rejected changes can be edited", ErrInvalidChange)
+	}
+	change.Status = domain.ChangeDraft
+
+	if err := s.prepare(ctx, change, computerIDs); err != nil {
+		return nil, err
+	}
+	if err := s.changeRepo.Update(ctx, change); err != nil {
+		return nil, err
+	}
+	return s.conflictsFor(ctx, change)
+}
+
+func (s *ChangeService) DeleteChange(ctx context.Context, id uint) error {
+	err := s.changeRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// SubmitChange sends a draft or rejected change for approval. Standard
+// changes are pre-approved; normal and emergency changes need CAB approvers,
+// who decide normal changes one after another in the given order.
+func (s *ChangeService) SubmitChange(ctx context.Context, change *domain.ChangeRequest, approverIDs []uint) error {
+	if change.Status != domain.ChangeDraft && change.Status != domain.ChangeRejected {
+		return fmt.Errorf("%w: only draft or rejected changes can be submitted", ErrInvalidChange)
+	}
+	if strings.TrimSpace(change.ImplementationPlan) == "" {
+		return fmt.Errorf("%w: an implementation plan is required", ErrInvalidChange)
+	}
+	if change.Type != domain.StandardChange && strings.TrimSpace(change.RollbackPlan) == "" {
+		return fmt.Errorf("%w: a rollback plan is required", ErrInvalidChange)
+	}
+
+	now := time.Now()
+	change.SubmittedAt = &now
+
+	if change.Type == domain.StandardChange {
+		change.Status = domain.ChangeApproved
+		change.ApprovedAt = &now
+		return s.changeRepo.Update(ctx, change)
+	}
+
+	approverIDs = uniqueIDs(approverIDs)
+	if len(approverIDs) == 0 {
+		return fmt.Errorf("%w: %s changes need at least one CAB approver", ErrInvalidChange, change.Type)
+	}
+	for _, approverID := range approverIDs {
+		if approverID == change.RequestedByID {
+			return fmt.Errorf("%w: the requester cannot approve their own change", ErrInvalidChange)
+		}
+		approver, err := s.userRepo.GetByID(ctx, approverID)
+		if err != nil || (approver.Role != domain.AgentRole && approver.Role != domain.AdminRole) {
+			return fmt.Errorf("%w: approver %d must be an agent or admin", ErrInvalidChange, approverID)
+		}
+	}
+
+	change.Status = domain.ChangePendingApproval
+	change.ApprovalRound++
+	change.ApprovedAt = nil
+
+	approvals := make([]domain.ChangeApproval, 0, len(approverIDs))
+	for i, approverID := range approverIDs {
+		approvals = append(approvals, domain.ChangeApproval{
+			ChangeRequestID: change.ID,
+			Round:           change.ApprovalRound,
+			Step:            i + 1,
+			ApproverID:      approverID,
+			Status:          domain.ApprovalPending,
+		})
+	}
+
+	return s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		if err := tx.Changes.Update(ctx, change); err != nil {
+			return err
+		}
+		return tx.Changes.CreateApprovals(ctx, approvals)
+	})
+}
+
+// DecideChange records the acting approver's decision. A rejection rejects
+// the change; it is approved once every step of a normal change, or any
+// member of the emergency CAB, has approved.
+func (s *ChangeService) DecideChange(ctx context.Context, changeID uint, approve bool, comment string, viewer domain.Viewer) (*domain.ChangeRequest, error) {
+	err := s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		change, err := tx.Changes.GetByID(ctx, changeID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if change.Status != domain.ChangePendingApproval {
+			return fmt.Errorf("%w: the change is not awaiting approval", ErrInvalidChange)
+		}
+
+		approval, remaining := nextApproval(change, viewer.UserID)
+		if approval == nil {
+			return ErrForbidden
+		}
+
+		now := time.Now()
+		approval.Comment = strings.TrimSpace(comment)
+		approval.DecidedAt = &now
+		approval.Status = domain.ApprovalRejected
+		if approve {
+			approval.Status = domain.ApprovalApproved
+		}
+		if err := tx.Changes.UpdateApproval(ctx, approval); err != nil {
+			return err
+		}
+
+		switch {
+		case !approve:
+			change.Status = domain.ChangeRejected
+		case change.Type == domain.EmergencyChange || remaining == 0:
+			change.Status = domain.ChangeApproved
+			change.ApprovedAt = &now
+		default:
+			// Later steps of a normal change still have to decide
+			return nil
+		}
+
+		if err := tx.Changes.SkipPendingApprovals(ctx, change.ID); err != nil {
+			return err
+		}
+		return tx.Changes.Update(ctx, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	change, _, err := s.GetChange(ctx, changeID)
+	return change, err
+}
+
+// TransitionChange moves an approved change through implementation or
+// cancels a change that has not started
+func (s *ChangeService) TransitionChange(ctx context.Context, change *domain.ChangeRequest, status domain.ChangeStatus) error {
+	now := time.Now()
+	switch {
+	case status == domain.ChangeInProgress && change.Status == domain.ChangeApproved:
+		change.StartedAt = &now
+	case (status == domain.ChangeCompleted || status == domain.ChangeRolledBack) && change.Status == domain.ChangeInProgress:
+		change.FinishedAt = &now
+	case status == domain.ChangeCancelled && change.Status != domain.ChangeInProgress &&
+		change.Status != domain.ChangeCancelled && change.Status != domain.ChangeCompleted && change.Status != domain.ChangeRolledBack:
+		change.FinishedAt = &now
+	default:
+		return fmt.Errorf("%w: cannot move a %s change to %s", ErrInvalidChange, change.Status, status)
+	}
+
+	change.Status = status
+	return s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		if status == domain.ChangeCancelled {
+			if err := tx.Changes.SkipPendingApprovals(ctx, change.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Changes.Update(ctx, change)
+	})
+}
+
+// CheckConflicts returns the active changes that touch any of the computers
+// in an overlapping window, leaving out the change with excludeID
+func (s *ChangeService) CheckConflicts(ctx context.Context, computerIDs []uint, start, end time.Time, excludeID uint) ([]ChangeConflict, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: planned end must be after planned start", ErrInvalidChange)
+	}
+
+	overlapping, err := s.changeRepo.FindOverlapping(ctx, computerIDs, start, end, conflictingChangeStatuses, excludeID)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[uint]bool, len(computerIDs))
+	for _, id := range computerIDs {
+		wanted[id] = true
+	}
+
+	conflicts := make([]ChangeConflict, 0, len(overlapping))
+	for _, other := range overlapping {
+		conflict := ChangeConflict{
+			ChangeID:     other.ID,
+			Title:        other.Title,
+			Status:       other.Status,
+			PlannedStart: other.PlannedStart,
+			PlannedEnd:   other.PlannedEnd,
+		}
+		for _, computer := range other.Computers {
+			if wanted[computer.ID] {
+				conflict.Computers = append(conflict.Computers, ConflictComputer{ID: computer.ID, Hostname: computer.Hostname})
+			}
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
+// conflictsFor checks a change against the others unless it is finished
+func (s *ChangeService) conflictsFor(ctx context.Context, change *domain.ChangeRequest) ([]ChangeConflict, error) {
+	switch change.Status {
+	case domain.ChangeCompleted, domain.ChangeRolledBack, domain.ChangeCancelled, domain.ChangeRejected:
+		return []ChangeConflict{}, nil
+	}
+
+	ids := make([]uint, 0, len(change.Computers))
+	for _, computer := range change.Computers {
+		ids = append(ids, computer.ID)
+	}
+	return s.CheckConflicts(ctx, ids, change.PlannedStart, change.PlannedEnd, change.ID)
+}
+
+// prepare validates the change and loads the computers it is linked to.
+// A nil computerIDs keeps the computers already on the change.
+func (s *ChangeService) prepare(ctx context.Context, change *domain.ChangeRequest, computerIDs *[]uint) error {
+	change.Title = strings.TrimSpace(change.Title)
+	if change.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidChange)
+	}
+	if !change.Type.IsValid() {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidChange, change.Type)
+	}
+	if !change.Risk.IsValid() {
+		return fmt.Errorf("%w: unknown risk %q", ErrInvalidChange, change.Risk)
+	}
+	if change.Type == domain.StandardChange && change.Risk == domain.HighRisk {
+		return fmt.Errorf("%w: high risk changes cannot be standard changes", ErrInvalidChange)
+	}
+	if change.PlannedStart.IsZero() || change.PlannedEnd.IsZero() {
+		return fmt.Errorf("%w: planned start and end are required", ErrInvalidChange)
+	}
+	if !change.PlannedEnd.After(change.PlannedStart) {
+		return fmt.Errorf("%w: planned end must be after planned start", ErrInvalidChange)
+	}
+
+	if change.ImplementerID != nil {
+		implementer, err := s.userRepo.GetByID(ctx, *change.ImplementerID)
+		if err != nil || (implementer.Role != domain.AgentRole && implementer.Role != domain.AdminRole) {
+			return fmt.Errorf("%w: the implementer must be an agent or admin", ErrInvalidChange)
+		}
+	}
+
+	if computerIDs == nil {
+		return nil
+	}
+	ids := uniqueIDs(*computerIDs)
+	computers, err := s.changeRepo.FindComputers(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(computers) != len(ids) {
+		return fmt.Errorf("%w: unknown computer", ErrInvalidChange)
+	}
+	change.Computers = computers
+	return nil
+}
+
+// nextApproval returns the pending approval of the current round that
+// approverID may decide, and how many other approvals of the round are still
+// pending. Normal changes are decided step by step; emergency changes in any order.
+func nextApproval(change *domain.ChangeRequest, approverID uint) (*domain.ChangeApproval, int) {
+	var next *domain.ChangeApproval
+	pending := 0
+	for i := range change.Approvals {
+		approval := &change.Approvals[i]
+		if approval.Round != change.ApprovalRound || approval.Status != domain.ApprovalPending {
+			continue
+		}
+		pending++
+
+		if next != nil {
+			continue
+		}
+		if approval.ApproverID == approverID {
+			next = approval
+		} else if change.Type == domain.NormalChange {
+			// An earlier step is still waiting on someone else
+			return nil, 0
+		}
+	}
+	if next == nil {
+		return nil, 0
+	}
+	return next, pending - 1
+}
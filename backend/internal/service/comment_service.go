@@ -3,35 +3,208 @@ package service
 import (
 	"context"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
 	"helpdesk-backend/internal/repository"
+	"time"
 )
 
 type CommentService struct {
-	commentRepo *repository.CommentRepository
+	commentRepo       *repository.CommentRepository
+	ticketRepo        *repository.TicketRepository
+	attachmentService *AttachmentService
+	mentionService    *MentionService
+	broker            *events.Broker
 }
 
-func NewCommentService(commentRepo *repository.CommentRepository) *CommentService {
+func NewCommentService(commentRepo *repository.CommentRepository, ticketRepo *repository.TicketRepository, attachmentService *AttachmentService, mentionService *MentionService, broker *events.Broker) *CommentService {
 	return &CommentService{
-		commentRepo: commentRepo,
+		commentRepo:       commentRepo,
+		ticketRepo:        ticketRepo,
+		attachmentService: attachmentService,
+		mentionService:    mentionService,
+		broker:            broker,
 	}
 }
 
-func (s *CommentService) CreateComment(ctx context.Context, comment *domain.Comment) error {
-	return s.commentRepo.Create(ctx, comment)
+// CreateComment adds a comment authored by the viewer. End users may only
+// comment on their own tickets and can never write internal notes.
+func (s *CommentService) CreateComment(ctx context.Context, comment *domain.Comment, viewer domain.Viewer) error {
+	ticket, err := s.checkTicketAccess(ctx, comment.TicketID, viewer)
+	if err != nil {
+		return err
+	}
+
+	comment.AuthorID = viewer.UserID
+	if !viewer.IsStaff() {
+		comment.IsPublic = true
+	}
+
+	if comment.ContentHTML, err = renderMarkdown(ctx, s.attachmentService, ticket.ID, comment.Content); err != nil {
+		return err
+	}
+
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		return err
+	}
+
+	// Mentions go first so that the comment event can tell notifications
+	// which users already hear about it through a mention
+	mentions := s.processMentions(ctx, comment, ticket)
+	publish(s.broker, events.NewCommentEvent(comment, ticket, mentions...))
+	return nil
 }
 
 func (s *CommentService) GetCommentByID(ctx context.Context, id uint) (*domain.Comment, error) {
 	return s.commentRepo.GetByID(ctx, id)
 }
 
-func (s *CommentService) UpdateComment(ctx context.Context, comment *domain.Comment) error {
-	return s.commentRepo.Update(ctx, comment)
+// GetCommentForViewer returns a comment, hiding internal notes and other
+// users' tickets from end users
+func (s *CommentService) GetCommentForViewer(ctx context.Context, id uint, viewer domain.Viewer) (*domain.Comment, error) {
+	comment, err := s.commentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if !viewer.IsStaff() && !comment.IsPublic {
+		return nil, ErrNotFound
+	}
+	if _, err := s.checkTicketAccess(ctx, comment.TicketID, viewer); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// UpdateComment saves an edited comment. Only the author or an admin may
+// edit, and end users cannot turn a comment into an internal note.
+func (s *CommentService) UpdateComment(ctx context.Context, comment *domain.Comment, viewer domain.Viewer) error {
+	if !canModifyComment(comment, viewer) {
+		return ErrForbidden
+	}
+	if !viewer.IsStaff() && !comment.IsPublic {
+		return ErrForbidden
+	}
+
+	html, err := renderMarkdown(ctx, s.attachmentService, comment.TicketID, comment.Content)
+	if err != nil {
+		return err
+	}
+	comment.ContentHTML = html
+
+	now := time.Now()
+	editorID := viewer.UserID
+	comment.EditedAt = &now
+	comment.EditedByID = &editorID
+
+	if err := s.commentRepo.Update(ctx, comment); err != nil {
+		return err
+	}
+
+	// Only users newly mentioned by the edit are notified
+	if ticket, err := s.ticketRepo.GetByIDShallow(ctx, comment.TicketID); err == nil {
+		s.processMentions(ctx, comment, ticket)
+	}
+	return nil
+}
+
+// DeleteComment removes a comment. Only the author or an admin may delete.
+func (s *CommentService) DeleteComment(ctx context.Context, id uint, viewer domain.Viewer) error {
+	comment, err := s.GetCommentForViewer(ctx, id, viewer)
+	if err != nil {
+		return err
+	}
+	if !canModifyComment(comment, viewer) {
+		return ErrForbidden
+	}
+
+	return s.commentRepo.Delete(ctx, id, viewer.UserID)
+}
+
+// ListCommentsForViewer returns a ticket's comments. End users do not see
+// internal notes or deleted comments; staff see deleted comments as tombstones.
+func (s *CommentService) ListCommentsForViewer(ctx context.Context, ticketID uint, viewer domain.Viewer) ([]domain.Comment, error) {
+	if _, err := s.checkTicketAccess(ctx, ticketID, viewer); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.ListByTicket(ctx, ticketID, viewer.IsStaff(), viewer.IsStaff())
+	if err != nil {
+		return nil, err
+	}
+	redactDeletedComments(comments)
+	return comments, nil
 }
 
-func (s *CommentService) DeleteComment(ctx context.Context, id uint) error {
-	return s.commentRepo.Delete(ctx, id)
+// ListRevisions returns every revision of a comment, oldest first. Staff may
+// read the history of deleted comments; end users only see revisions made
+// while the comment was public.
+func (s *CommentService) ListRevisions(ctx context.Context, id uint, viewer domain.Viewer) ([]domain.CommentRevision, error) {
+	if viewer.IsStaff() {
+		comment, err := s.commentRepo.GetByIDWithDeleted(ctx, id)
+		if err != nil {
+			return nil, ErrNotFound
+		}
+		if _, err := s.checkTicketAccess(ctx, comment.TicketID, viewer); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.GetCommentForViewer(ctx, id, viewer); err != nil {
+		return nil, err
+	}
+
+	return s.commentRepo.ListRevisions(ctx, id, viewer.IsStaff())
+}
+
+// RenderMissingHTML renders the HTML of comments stored before Markdown
+// rendering was introduced
+func (s *CommentService) RenderMissingHTML(ctx context.Context) error {
+	var lastID uint
+	for {
+		comments, err := s.commentRepo.ListUnrendered(ctx, lastID, renderBatchSize)
+		if err != nil || len(comments) == 0 {
+			return err
+		}
+		for _, comment := range comments {
+			lastID = comment.ID
+			html, err := renderMarkdown(ctx, s.attachmentService, comment.TicketID, comment.Content)
+			if err != nil {
+				return err
+			}
+			if err := s.commentRepo.SetContentHTML(ctx, comment.ID, comment.Version, html); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkTicketAccess ensures the ticket exists and, for end users, that they requested it
+func (s *CommentService) checkTicketAccess(ctx context.Context, ticketID uint, viewer domain.Viewer) (*domain.Ticket, error) {
+	return ticketForViewer(ctx, s.ticketRepo, ticketID, viewer)
+}
+
+// processMentions records and notifies mentions and returns the new ones. The
+// comment is already saved, so failures are logged rather than returned.
+func (s *CommentService) processMentions(ctx context.Context, comment *domain.Comment, ticket *domain.Ticket) []domain.Mention {
+	if s.mentionService == nil {
+		return nil
+	}
+	mentions, err := s.mentionService.ProcessComment(ctx, comment, ticket)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to process mentions", "comment_id", comment.ID, "error", err)
+	}
+	return mentions
+}
+
+// redactDeletedComments turns deleted comments into tombstones that only
+// record who deleted them and when. The content stays in the revision history.
+func redactDeletedComments(comments []domain.Comment) {
+	for i := range comments {
+		if comments[i].DeletedAt.Valid {
+			comments[i].Content = ""
+			comments[i].ContentHTML = ""
+		}
+	}
 }
 
-func (s *CommentService) ListCommentsByTicket(ctx context.Context, ticketID uint) ([]domain.Comment, error) {
-	return s.commentRepo.ListByTicket(ctx, ticketID)
+func canModifyComment(comment *domain.Comment, viewer domain.Viewer) bool {
+	return viewer.Role == domain.AdminRole || comment.AuthorID == viewer.UserID
 }
@@ -62,13 +62,18 @@ func (s *ComputerService) GetComputersByOS() (map[string][]domain.Computer, erro
 	return s.computerRepo.GetByOS()
 }
 
-// UpdateComputer updates a computer
+// UpdateComputer updates a computer. A non-zero updates.Version must match
+// the stored version, otherwise repository.ErrVersionConflict is returned.
 func (s *ComputerService) UpdateComputer(id uint, updates *domain.Computer) error {
 	computer, err := s.computerRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
 
+	if updates.Version != 0 && updates.Version != computer.Version {
+		return repository.ErrVersionConflict
+	}
+
 	// Validate assignee if being updated
 	if updates.AssigneeID != nil && *updates.AssigneeID != 0 {
 		_, err := s.userRepo.GetByID(context.Background(), *updates.AssigneeID)
@@ -140,6 +145,25 @@ func (s *ComputerService) UpdateComputer(id uint, updates *domain.Computer) erro
 	return s.computerRepo.Update(computer)
 }
 
+// SaveComputer persists a fully edited computer, such as one modified by a
+// merge patch, after validating its assignee and hostname
+func (s *ComputerService) SaveComputer(computer *domain.Computer) error {
+	if computer.AssigneeID != nil {
+		_, err := s.userRepo.GetByID(context.Background(), *computer.AssigneeID)
+		if err != nil {
+			return errors.New("invalid assignee ID")
+		}
+	}
+
+	existingComputer, _ := s.computerRepo.GetByHostname(computer.Hostname)
+	if existingComputer != nil && existingComputer.ID != computer.ID {
+		return errors.New("computer with this hostname already exists")
+	}
+
+	computer.Assignee = nil
+	return s.computerRepo.Update(computer)
+}
+
 // DeleteComputer deletes a computer
 func (s *ComputerService) DeleteComputer(id uint) error {
 	_, err := s.computerRepo.GetByID(id)
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/markdown"
+	"strconv"
+	"strings"
+)
+
+// renderBatchSize is how many records are re-rendered per query when backfilling HTML
+const renderBatchSize = 200
+
+// renderMarkdown renders ticket or comment Markdown to sanitized HTML. Inline
+// images may reference an image attachment of the ticket either as
+// "attachment:<id>" or by its file name, and link to it with a signed URL.
+func renderMarkdown(ctx context.Context, attachmentService *AttachmentService, ticketID uint, source string) (string, error) {
+	if strings.TrimSpace(source) == "" {
+		return "", nil
+	}
+
+	var resolve markdown.ImageResolver
+	if ticketID != 0 && attachmentService != nil {
+		attachments, err := attachmentService.attachmentRepo.ListByTicket(ctx, ticketID)
+		if err != nil {
+			return "", err
+		}
+		resolve = imageResolver(attachments, attachmentService.ImageURL)
+	}
+
+	return markdown.Render(source, resolve)
+}
+
+// imageResolver maps image destinations to the ticket's image attachments
+func imageResolver(attachments []domain.Attachment, attachmentURL func(id uint) string) markdown.ImageResolver {
+	return func(destination string) (string, bool) {
+		if ref, ok := strings.CutPrefix(destination, "attachment:"); ok {
+			id, err := strconv.ParseUint(ref, 10, 32)
+			if err != nil {
+				return "", false
+			}
+			for _, a := range attachments {
+				if a.ID == uint(id) && a.IsImage() {
+					return attachmentURL(a.ID), true
+				}
+			}
+			return "", false
+		}
+
+		// Later uploads win when several attachments share a file name
+		for i := len(attachments) - 1; i >= 0; i-- {
+			if a := attachments[i]; a.IsImage() && strings.EqualFold(a.FileName, destination) {
+				return attachmentURL(a.ID), true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/textsim"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidDuplicate is returned when a ticket cannot be marked as a
+// duplicate of the given ticket
+var ErrInvalidDuplicate = errors.New("invalid duplicate")
+
+const (
+	// New tickets are compared against open tickets created this recently
+	duplicateWindow     = 7 * 24 * time.Hour
+	duplicateCandidates = 500
+
+	// A ticket is flagged when its confidence reaches duplicateThreshold. Text
+	// similarity makes up most of the confidence; a shared category and the
+	// same requester add the rest.
+	duplicateThreshold      = 0.6
+	duplicateTextWeight     = 0.8
+	duplicateCategoryBonus  = 0.1
+	duplicateRequesterBonus = 0.1
+	duplicateMaxChainHops   = 10
+)
+
+// flagPossibleDuplicate compares a ticket about to be created with recent
+// open tickets and flags the most likely original. Detection is advisory, so
+// failures are only logged.
+func (s *TicketService) flagPossibleDuplicate(ctx context.Context, ticket *domain.Ticket) {
+	candidates, err := s.ticketRepo.ListDuplicateCandidates(ctx, time.Now().Add(-duplicateWindow), duplicateCandidates)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load duplicate candidates", "error", err)
+		return
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	documents := make([]textsim.Document, len(candidates))
+	for i, candidate := range candidates {
+		documents[i] = textsim.Document{Title: candidate.Title, Body: candidate.Description}
+	}
+	matches := textsim.NewIndex(documents).Query(textsim.Document{Title: ticket.Title, Body: ticket.Description}, 0, 0)
+
+	var best *domain.Ticket
+	var bestScore float64
+	for _, match := range matches {
+		candidate := &candidates[match.Index]
+		score := duplicateTextWeight * match.Score
+		if ticket.Category != "" && strings.EqualFold(candidate.Category, ticket.Category) {
+			score += duplicateCategoryBonus
+		}
+		if candidate.RequesterID == ticket.RequesterID {
+			score += duplicateRequesterBonus
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if best == nil || bestScore < duplicateThreshold {
+		return
+	}
+
+	confidence := math.Round(math.Min(bestScore, 1)*100) / 100
+	ticket.PossibleDuplicateOfID = &best.ID
+	ticket.DuplicateConfidence = &confidence
+}
+
+// MarkDuplicate marks a ticket as a duplicate of another and closes it. If
+// the original is itself a duplicate, the ticket is pointed at the ticket
+// that one duplicates instead.
+func (s *TicketService) MarkDuplicate(ctx context.Context, id, originalID, actorID uint) (*domain.Ticket, error) {
+	rootID, err := s.duplicateRoot(ctx, originalID)
+	if err != nil {
+		return nil, err
+	}
+	if rootID == id {
+		return nil, fmt.Errorf("%w: a ticket cannot be a duplicate of itself", ErrInvalidDuplicate)
+	}
+
+	ticket, err := s.ModifyTicket(ctx, id, actorID, func(ticket *domain.Ticket) {
+		ticket.DuplicateOfID = &rootID
+		ticket.PossibleDuplicateOfID = nil
+		ticket.DuplicateConfidence = nil
+		if ticket.Status != domain.ResolvedStatus {
+			ticket.Status = domain.ClosedStatus
+		}
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return ticket, err
+}
+
+// ClearDuplicate dismisses a duplicate flag or unmarks a duplicate. The
+// ticket keeps its status; reopen it separately if needed.
+func (s *TicketService) ClearDuplicate(ctx context.Context, id, actorID uint) (*domain.Ticket, error) {
+	ticket, err := s.ModifyTicket(ctx, id, actorID, func(ticket *domain.Ticket) {
+		ticket.DuplicateOfID = nil
+		ticket.PossibleDuplicateOfID = nil
+		ticket.DuplicateConfidence = nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return ticket, err
+}
+
+// ListDuplicates returns the tickets marked as duplicates of a ticket
+func (s *TicketService) ListDuplicates(ctx context.Context, id uint) ([]domain.Ticket, error) {
+	if _, err := s.ticketRepo.GetByIDShallow(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.ticketRepo.ListDuplicates(ctx, id)
+}
+
+// duplicateRoot follows duplicate marks from a ticket to the original one
+func (s *TicketService) duplicateRoot(ctx context.Context, id uint) (uint, error) {
+	for hop := 0; hop < duplicateMaxChainHops; hop++ {
+		ticket, err := s.ticketRepo.GetByIDShallow(ctx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, ErrNotFound
+			}
+			return 0, err
+		}
+		if ticket.DuplicateOfID == nil {
+			return id, nil
+		}
+		id = *ticket.DuplicateOfID
+	}
+	return 0, fmt.Errorf("%w: duplicate chain is too long", ErrInvalidDuplicate)
+}
@@ -0,0 +1,10 @@
+package service
+
+import "errors"
+
+var (
+	// ErrForbidden is returned when the acting user may not perform an operation
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound is returned when a record does not exist or is hidden from the acting user
+	ErrNotFound = errors.New("not found")
+)
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches @handle when it is not part of a word or an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+type MentionService struct {
+	mentionRepo *repository.MentionRepository
+	watcherRepo *repository.WatcherRepository
+	userRepo    *repository.UserRepository
+	broker      *events.Broker
+}
+
+// NewMentionService creates the service. Mentioned users are notified through
+// mention.created events published on broker.
+func NewMentionService(mentionRepo *repository.MentionRepository, watcherRepo *repository.WatcherRepository, userRepo *repository.UserRepository, broker *events.Broker) *MentionService {
+	return &MentionService{
+		mentionRepo: mentionRepo,
+		watcherRepo: watcherRepo,
+		userRepo:    userRepo,
+		broker:      broker,
+	}
+}
+
+// ProcessComment resolves the @mentions in a comment, records the ones that
+// are new, adds the mentioned users as ticket watchers and notifies them.
+//
+// Mentions resolve against the email local part (@jane for jane@example.com)
+// or, failing that, a unique first name. Internal notes can only mention
+// agents and admins; public comments may also mention the ticket requester.
+func (s *MentionService) ProcessComment(ctx context.Context, comment *domain.Comment, ticket *domain.Ticket) ([]domain.Mention, error) {
+	handles := parseMentions(comment.Content)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	candidates, err := s.userRepo.FindByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.mentionRepo.MentionedUserIDs(ctx, comment.ID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uint]bool, len(existing)+1)
+	for _, id := range existing {
+		seen[id] = true
+	}
+	seen[comment.AuthorID] = true
+
+	var mentions []domain.Mention
+	var userIDs []uint
+	for _, handle := range handles {
+		user := resolveHandle(handle, candidates)
+		if user == nil || seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+
+		isStaff := user.Role == domain.AdminRole || user.Role == domain.AgentRole
+		if !isStaff && (!comment.IsPublic || user.ID != ticket.RequesterID) {
+			continue
+		}
+
+		mentions = append(mentions, domain.Mention{
+			CommentID:       comment.ID,
+			TicketID:        ticket.ID,
+			MentionedUserID: user.ID,
+			MentionedByID:   comment.AuthorID,
+		})
+		userIDs = append(userIDs, user.ID)
+	}
+	if len(mentions) == 0 {
+		return nil, nil
+	}
+
+	if err := s.mentionRepo.CreateMany(ctx, mentions); err != nil {
+		return nil, err
+	}
+
+	if err := s.watcherRepo.Add(ctx, ticket.ID, userIDs); err != nil {
+		logging.FromContext(ctx).Warn("Failed to add mentioned users as watchers", "ticket_id", ticket.ID, "error", err)
+	}
+
+	for _, mention := range mentions {
+		publish(s.broker, events.NewMentionEvent(mention, comment, ticket))
+	}
+
+	return mentions, nil
+}
+
+// ListMentionsOfUser returns the viewer's mentions feed, newest first
+func (s *MentionService) ListMentionsOfUser(ctx context.Context, viewer domain.Viewer, limit, offset int) ([]domain.Mention, error) {
+	return s.mentionRepo.ListForUser(ctx, viewer.UserID, viewer.IsStaff(), limit, offset)
+}
+
+// ListWatchers returns the users watching a ticket
+func (s *MentionService) ListWatchers(ctx context.Context, ticketID uint) ([]domain.TicketWatcher, error) {
+	return s.watcherRepo.ListByTicket(ctx, ticketID)
+}
+
+// parseMentions returns the distinct lowercase handles mentioned in content, in order
+func parseMentions(content string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		// Drop sentence punctuation such as the period in "thanks @jane."
+		handle := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// resolveHandle picks the user a handle refers to, or nil when there is no
+// unambiguous match
+func resolveHandle(handle string, candidates []domain.User) *domain.User {
+	var byEmail, byName []*domain.User
+	for i := range candidates {
+		user := &candidates[i]
+		if local, _, _ := strings.Cut(strings.ToLower(user.Email), "@"); local == handle {
+			byEmail = append(byEmail, user)
+		}
+		if strings.ToLower(user.FirstName) == handle {
+			byName = append(byName, user)
+		}
+	}
+
+	if len(byEmail) == 1 {
+		return byEmail[0]
+	}
+	if len(byEmail) == 0 && len(byName) == 1 {
+		return byName[0]
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/metrics"
+	"helpdesk-backend/internal/repository"
+	"sync"
+	"time"
+)
+
+// metricsCacheTTL keeps frequent scrapes from querying the database every time
+const metricsCacheTTL = 30 * time.Second
+
+// metricsPriorities are reported even when no open ticket has them, so that
+// every series exists from the first scrape
+var metricsPriorities = []domain.TicketPriority{domain.CriticalPriority, domain.HighPriority, domain.MediumPriority, domain.LowPriority}
+
+type operationalCounts struct {
+	openByPriority map[domain.TicketPriority]int
+	slaBreached    int
+	bulkQueued     int
+	bulkRunning    int
+	webhookPending int
+	webhookDead    int
+}
+
+// MetricsCollector reports the ticket backlog and the depth of the background
+// job queues as Prometheus gauges
+type MetricsCollector struct {
+	ticketRepo  *repository.TicketRepository
+	bulkJobRepo *repository.BulkJobRepository
+	webhookRepo *repository.WebhookRepository
+
+	mu        sync.Mutex
+	counts    *operationalCounts
+	countedAt time.Time
+}
+
+func NewMetricsCollector(ticketRepo *repository.TicketRepository, bulkJobRepo *repository.BulkJobRepository, webhookRepo *repository.WebhookRepository) *MetricsCollector {
+	return &MetricsCollector{
+		ticketRepo:  ticketRepo,
+		bulkJobRepo: bulkJobRepo,
+		webhookRepo: webhookRepo,
+	}
+}
+
+// Collect writes the gauges, counted at most every 30 seconds
+func (c *MetricsCollector) Collect(ctx context.Context, w *metrics.Writer) error {
+	counts, err := c.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	open := make([]metrics.Sample, 0, len(metricsPriorities))
+	for _, priority := range metricsPriorities {
+		open = append(open, metrics.NewSample(float64(counts.openByPriority[priority]), "priority", string(priority)))
+	}
+	w.Gauge("helpdesk_tickets_open", "Open and in-progress tickets by priority.", open...)
+	w.Gauge("helpdesk_tickets_sla_breached", "Unresolved tickets past their SLA breach time.",
+		metrics.NewSample(float64(counts.slaBreached)))
+	w.Gauge("helpdesk_job_queue_depth", "Background jobs waiting or in progress, by queue and status.",
+		metrics.NewSample(float64(counts.bulkQueued), "queue", "bulk", "status", string(domain.BulkJobQueued)),
+		metrics.NewSample(float64(counts.bulkRunning), "queue", "bulk", "status", string(domain.BulkJobRunning)),
+		metrics.NewSample(float64(counts.webhookPending), "queue", "webhook", "status", string(domain.WebhookDeliveryPending)))
+	w.Gauge("helpdesk_webhook_deliveries_dead", "Webhook deliveries that ran out of retries.",
+		metrics.NewSample(float64(counts.webhookDead)))
+	return nil
+}
+
+func (c *MetricsCollector) load(ctx context.Context) (*operationalCounts, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts != nil && time.Since(c.countedAt) < metricsCacheTTL {
+		return c.counts, nil
+	}
+
+	counts := &operationalCounts{}
+	var err error
+	if counts.openByPriority, err = c.ticketRepo.CountOpenByPriority(ctx); err != nil {
+		return nil, err
+	}
+	if counts.slaBreached, err = c.ticketRepo.GetSLABreachesCount(ctx); err != nil {
+		return nil, err
+	}
+	if counts.bulkQueued, err = c.bulkJobRepo.CountByStatus(ctx, domain.BulkJobQueued); err != nil {
+		return nil, err
+	}
+	if counts.bulkRunning, err = c.bulkJobRepo.CountByStatus(ctx, domain.BulkJobRunning); err != nil {
+		return nil, err
+	}
+	if counts.webhookPending, err = c.webhookRepo.CountDeliveriesByStatus(ctx, domain.WebhookDeliveryPending); err != nil {
+		return nil, err
+	}
+	if counts.webhookDead, err = c.webhookRepo.CountDeliveriesByStatus(ctx, domain.WebhookDeliveryDead); err != nil {
+		return nil, err
+	}
+
+	c.counts = counts
+	c.countedAt = time.Now()
+	return counts, nil
+}
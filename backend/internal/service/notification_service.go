@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/mail"
+	"helpdesk-backend/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationTypes are the event types users are notified about, in the
+// order preferences are listed
+var NotificationTypes = []events.Type{
+	events.TicketCreated,
+	events.TicketUpdated,
+	events.TicketAssigned,
+	events.CommentCreated,
+	events.MentionCreated,
+	events.SLAWarning,
+}
+
+// defaultPreference is used for event types a user has not configured. Every
+// event shows up in the app; only events addressed to the user personally are
+// emailed.
+func defaultPreference(eventType events.Type) domain.NotificationPreference {
+	switch eventType {
+	case events.TicketAssigned, events.MentionCreated, events.SLAWarning:
+		return domain.NotificationPreference{EventType: string(eventType), InApp: true, Email: true}
+	default:
+		return domain.NotificationPreference{EventType: string(eventType), InApp: true, Email: false}
+	}
+}
+
+func isNotificationType(eventType string) bool {
+	for _, t := range NotificationTypes {
+		if string(t) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownEventType is returned when a preference names an event type users cannot be notified about
+var ErrUnknownEventType = errors.New("unknown notification event type")
+
+// NotificationService turns published events into in-app notifications and
+// emails according to each recipient's preferences
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	ticketRepo       *repository.TicketRepository
+	userRepo         *repository.UserRepository
+	watcherRepo      *repository.WatcherRepository
+	broker           *events.Broker
+	mailer           *mail.Mailer
+	ticketURL        string
+}
+
+// NewNotificationService creates the service. frontendURL is used to link to
+// the ticket from notification emails.
+func NewNotificationService(notificationRepo *repository.NotificationRepository, ticketRepo *repository.TicketRepository, userRepo *repository.UserRepository, watcherRepo *repository.WatcherRepository, broker *events.Broker, mailer *mail.Mailer, frontendURL string) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		ticketRepo:       ticketRepo,
+		userRepo:         userRepo,
+		watcherRepo:      watcherRepo,
+		broker:           broker,
+		mailer:           mailer,
+		ticketURL:        strings.TrimRight(frontendURL, "/") + "/tickets/",
+	}
+}
+
+// Start delivers notifications for published events until ctx is cancelled.
+// When the broker drops the subscription for falling behind, it resubscribes
+// and catches up from the history.
+func (s *NotificationService) Start(ctx context.Context) {
+	var lastID uint64
+	for {
+		sub, replay, complete := s.broker.Subscribe(lastID)
+		if !complete {
+			logging.FromContext(ctx).Warn("Some events were no longer available; their notifications were skipped", "after_event_id", lastID)
+		}
+		for _, event := range replay {
+			s.handle(ctx, event)
+			lastID = event.ID
+		}
+
+		closed := false
+		for !closed {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					closed = true
+					continue
+				}
+				s.handle(ctx, event)
+				lastID = event.ID
+			}
+		}
+	}
+}
+
+// handle notifies the recipients of one event. Failures are only logged so
+// that one bad event does not stop delivery.
+func (s *NotificationService) handle(ctx context.Context, event events.Event) {
+	if err := s.deliver(ctx, event); err != nil && ctx.Err() == nil {
+		logging.FromContext(ctx).Warn("Failed to deliver notifications", "event_id", event.ID, "event_type", event.Type, "error", err)
+	}
+}
+
+func (s *NotificationService) deliver(ctx context.Context, event events.Event) error {
+	if !isNotificationType(string(event.Type)) {
+		return nil
+	}
+
+	recipients, err := s.recipients(ctx, event)
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	title, body := describeEvent(event)
+
+	prefs, err := s.preferencesFor(ctx, recipients, event.Type)
+	if err != nil {
+		return err
+	}
+
+	var notifications []domain.Notification
+	var emails []domain.User
+	for _, user := range recipients {
+		pref := prefs[user.ID]
+		if pref.InApp {
+			notification := domain.Notification{
+				UserID:    user.ID,
+				EventType: string(event.Type),
+				Title:     title,
+				Body:      body,
+				CreatedAt: event.OccurredAt,
+			}
+			if event.TicketID != 0 {
+				ticketID := event.TicketID
+				notification.TicketID = &ticketID
+			}
+			if event.ActorID != 0 {
+				actorID := event.ActorID
+				notification.ActorID = &actorID
+			}
+			notifications = append(notifications, notification)
+		}
+		if pref.Email && user.Email != "" {
+			emails = append(emails, user)
+		}
+	}
+
+	if err := s.notificationRepo.CreateMany(ctx, notifications); err != nil {
+		return err
+	}
+
+	if len(emails) > 0 && s.mailer.Enabled() {
+		go s.sendEmails(ctx, event, title, body, emails)
+	}
+	return nil
+}
+
+// recipients returns the active users to notify about an event. The user who
+// caused the event is never notified, and internal events only reach staff.
+func (s *NotificationService) recipients(ctx context.Context, event events.Event) ([]domain.User, error) {
+	var users []domain.User
+	var err error
+
+	switch event.Type {
+	case events.TicketCreated:
+		users, err = s.userRepo.ListActiveByRoles(ctx, domain.AgentRole, domain.AdminRole)
+
+	case events.TicketAssigned:
+		data, _ := event.Data.(events.TicketData)
+		ids := []uint{data.RequesterID}
+		if data.AssigneeID != nil {
+			ids = append(ids, *data.AssigneeID)
+		}
+		users, err = s.userRepo.ListActiveByIDs(ctx, ids)
+
+	case events.TicketUpdated:
+		ids, idsErr := s.ticketParticipants(ctx, event.TicketID)
+		if idsErr != nil {
+			return nil, idsErr
+		}
+		users, err = s.userRepo.ListActiveByIDs(ctx, ids)
+
+	case events.CommentCreated:
+		ids, idsErr := s.ticketParticipants(ctx, event.TicketID)
+		if idsErr != nil {
+			return nil, idsErr
+		}
+		// Mentioned users get the mention instead of a second notification
+		data, _ := event.Data.(events.CommentData)
+		users, err = s.userRepo.ListActiveByIDs(ctx, withoutIDs(ids, data.MentionedUserIDs))
+
+	case events.MentionCreated:
+		data, _ := event.Data.(events.MentionData)
+		users, err = s.userRepo.ListActiveByIDs(ctx, []uint{data.MentionedUserID})
+
+	case events.SLAWarning:
+		data, _ := event.Data.(events.TicketData)
+		if data.AssigneeID != nil {
+			users, err = s.userRepo.ListActiveByIDs(ctx, []uint{*data.AssigneeID})
+		}
+		if err == nil && len(users) == 0 {
+			// Nobody owns the ticket, so escalate to the admins
+			users, err = s.userRepo.ListActiveByRoles(ctx, domain.AdminRole)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := users[:0]
+	for _, user := range users {
+		if user.ID == event.ActorID {
+			continue
+		}
+		if !event.VisibleTo(domain.Viewer{UserID: user.ID, Role: user.Role}) {
+			continue
+		}
+		recipients = append(recipients, user)
+	}
+	return recipients, nil
+}
+
+// withoutIDs returns the IDs in ids that are not in exclude
+func withoutIDs(ids, exclude []uint) []uint {
+	if len(exclude) == 0 {
+		return ids
+	}
+	excluded := make(map[uint]bool, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+	kept := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !excluded[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// ticketParticipants returns the requester, assignee and watchers of a ticket
+func (s *NotificationService) ticketParticipants(ctx context.Context, ticketID uint) ([]uint, error) {
+	ticket, err := s.ticketRepo.GetByIDShallow(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ids, err := s.watcherRepo.UserIDsByTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, ticket.RequesterID)
+	if ticket.AssigneeID != nil {
+		ids = append(ids, *ticket.AssigneeID)
+	}
+	return ids, nil
+}
+
+// preferencesFor returns each recipient's preference for an event type
+func (s *NotificationService) preferencesFor(ctx context.Context, users []domain.User, eventType events.Type) (map[uint]domain.NotificationPreference, error) {
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	stored, err := s.notificationRepo.ListPreferences(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make(map[uint]domain.NotificationPreference, len(users))
+	for _, id := range ids {
+		prefs[id] = defaultPreference(eventType)
+	}
+	for _, pref := range stored {
+		if pref.EventType == string(eventType) {
+			prefs[pref.UserID] = pref
+		}
+	}
+	return prefs, nil
+}
+
+// describeEvent returns the notification title and body for an event
+func describeEvent(event events.Event) (title, body string) {
+	switch data := event.Data.(type) {
+	case events.TicketData:
+		label := ticketLabel(data.ID, data.Key)
+		switch event.Type {
+		case events.TicketCreated:
+			return fmt.Sprintf("New ticket %s: %s", label, data.Title), fmt.Sprintf("Priority %s, category %s", data.Priority, data.Category)
+		case events.TicketAssigned:
+			return fmt.Sprintf("Ticket %s was assigned: %s", label, data.Title), ""
+		case events.SLAWarning:
+			body := ""
+			if data.SLABreachAt != nil {
+				body = fmt.Sprintf("The SLA will be breached at %s", data.SLABreachAt.UTC().Format(time.RFC1123))
+			}
+			return fmt.Sprintf("Ticket %s is about to breach its SLA: %s", label, data.Title), body
+		default:
+			return fmt.Sprintf("Ticket %s was updated: %s", label, data.Title), fmt.Sprintf("Status %s, priority %s", data.Status, data.Priority)
+		}
+	case events.CommentData:
+		label := ticketLabel(data.TicketID, data.TicketKey)
+		if !data.IsPublic {
+			return fmt.Sprintf("New internal note on ticket %s", label), ""
+		}
+		return fmt.Sprintf("New comment on ticket %s", label), ""
+	case events.MentionData:
+		return fmt.Sprintf("You were mentioned on ticket %s: %s", ticketLabel(data.TicketID, data.TicketKey), data.TicketTitle), data.Content
+	}
+	return string(event.Type), ""
+}
+
+// emailThread returns the subject and thread ID of notification emails about
+// the event's ticket. Both stay the same for every email about a ticket so
+// that mail clients group them, and replies can be matched by the key in the subject.
+func emailThread(event events.Event, fallback string) (subject, threadID string) {
+	var id uint
+	var key, title string
+	switch data := event.Data.(type) {
+	case events.TicketData:
+		id, key, title = data.ID, data.Key, data.Title
+	case events.CommentData:
+		id, key, title = data.TicketID, data.TicketKey, data.TicketTitle
+	case events.MentionData:
+		id, key, title = data.TicketID, data.TicketKey, data.TicketTitle
+	default:
+		return fallback, ""
+	}
+
+	label := ticketLabel(id, key)
+	return fmt.Sprintf("[%s] %s", label, title), "ticket-" + strings.TrimPrefix(label, "#")
+}
+
+// ticketLabel is how tickets are referred to in notifications: by key, or by
+// ID for tickets that do not have a key yet
+func ticketLabel(id uint, key string) string {
+	if key != "" {
+		return key
+	}
+	return fmt.Sprintf("#%d", id)
+}
+
+// sendEmails emails a notification. It runs in the background, so failures are only logged.
+func (s *NotificationService) sendEmails(ctx context.Context, event events.Event, title, body string, users []domain.User) {
+	subject, threadID := emailThread(event, title)
+
+	for _, user := range users {
+		text := fmt.Sprintf("Hi %s,\n\n%s\n", user.FirstName, title)
+		if body != "" {
+			text += "\n" + body + "\n"
+		}
+		if event.TicketID != 0 {
+			text += fmt.Sprintf("\nView the ticket: %s%d\n", s.ticketURL, event.TicketID)
+		}
+
+		msg := mail.Message{
+			To:       []string{user.Email},
+			Subject:  subject,
+			Body:     text,
+			ThreadID: threadID,
+		}
+		if err := s.mailer.Send(msg); err != nil {
+			logging.FromContext(ctx).Warn("Failed to send notification email", "recipient_id", user.ID, "error", err)
+		}
+	}
+}
+
+// ListNotifications returns the viewer's notifications, newest first
+func (s *NotificationService) ListNotifications(ctx context.Context, viewer domain.Viewer, unreadOnly bool, limit, offset int) ([]domain.Notification, error) {
+	return s.notificationRepo.ListForUser(ctx, viewer.UserID, unreadOnly, limit, offset)
+}
+
+// CountUnread returns how many notifications the viewer has not read
+func (s *NotificationService) CountUnread(ctx context.Context, viewer domain.Viewer) (int64, error) {
+	return s.notificationRepo.CountUnread(ctx, viewer.UserID)
+}
+
+// MarkRead marks one of the viewer's notifications as read
+func (s *NotificationService) MarkRead(ctx context.Context, id uint, viewer domain.Viewer) error {
+	err := s.notificationRepo.MarkRead(ctx, viewer.UserID, id, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// MarkAllRead marks all of the viewer's notifications as read and returns how many changed
+func (s *NotificationService) MarkAllRead(ctx context.Context, viewer domain.Viewer) (int64, error) {
+	return s.notificationRepo.MarkAllRead(ctx, viewer.UserID, time.Now())
+}
+
+// GetPreferences returns the viewer's preference for every notification type
+func (s *NotificationService) GetPreferences(ctx context.Context, viewer domain.Viewer) ([]domain.NotificationPreference, error) {
+	stored, err := s.notificationRepo.ListPreferences(ctx, []uint{viewer.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	byType := make(map[string]domain.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		byType[pref.EventType] = pref
+	}
+
+	prefs := make([]domain.NotificationPreference, 0, len(NotificationTypes))
+	for _, eventType := range NotificationTypes {
+		pref, ok := byType[string(eventType)]
+		if !ok {
+			pref = defaultPreference(eventType)
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences stores the given preferences for the viewer. Event types
+// that are not included keep their current setting.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, prefs []domain.NotificationPreference, viewer domain.Viewer) ([]domain.NotificationPreference, error) {
+	// A type listed twice keeps its last setting
+	byType := make(map[string]int, len(prefs))
+	var unique []domain.NotificationPreference
+	for _, pref := range prefs {
+		if !isNotificationType(pref.EventType) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, pref.EventType)
+		}
+		pref.ID = 0
+		pref.UserID = viewer.UserID
+		if i, ok := byType[pref.EventType]; ok {
+			unique[i] = pref
+			continue
+		}
+		byType[pref.EventType] = len(unique)
+		unique = append(unique, pref)
+	}
+
+	if err := s.notificationRepo.SavePreferences(ctx, unique); err != nil {
+		return nil, err
+	}
+	return s.GetPreferences(ctx, viewer)
+}
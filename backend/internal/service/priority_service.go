@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"strings"
+)
+
+// priorityReportRecent is how many individual overrides the report lists
+const priorityReportRecent = 50
+
+var (
+	// ErrPriorityOverrideReason is returned when a priority other than the
+	// computed one is set without saying why
+	ErrPriorityOverrideReason = errors.New("a reason is required to override the priority computed from impact and urgency")
+	// ErrInvalidTicketPriority is returned when a ticket names an unknown
+	// impact, urgency or priority
+	ErrInvalidTicketPriority = errors.New("invalid ticket priority")
+	// ErrInvalidPriorityMatrix is returned when a matrix update names an unknown level
+	ErrInvalidPriorityMatrix = errors.New("invalid priority matrix")
+)
+
+var (
+	ticketImpacts   = []domain.TicketImpact{domain.HighImpact, domain.MediumImpact, domain.LowImpact}
+	ticketUrgencies = []domain.TicketUrgency{domain.HighUrgency, domain.MediumUrgency, domain.LowUrgency}
+)
+
+// PriorityMatrix maps impact and urgency to a ticket priority
+type PriorityMatrix map[domain.TicketImpact]map[domain.TicketUrgency]domain.TicketPriority
+
+// defaultPriorityMatrix is the usual ITIL matrix, used for cells the admins
+// have not configured
+var defaultPriorityMatrix = PriorityMatrix{
+	domain.HighImpact: {
+		domain.HighUrgency:   domain.CriticalPriority,
+		domain.MediumUrgency: domain.HighPriority,
+		domain.LowUrgency:    domain.MediumPriority,
+	},
+	domain.MediumImpact: {
+		domain.HighUrgency:   domain.HighPriority,
+		domain.MediumUrgency: domain.MediumPriority,
+		domain.LowUrgency:    domain.LowPriority,
+	},
+	domain.LowImpact: {
+		domain.HighUrgency:   domain.MediumPriority,
+		domain.MediumUrgency: domain.LowPriority,
+		domain.LowUrgency:    domain.LowPriority,
+	},
+}
+
+// Lookup returns the priority for an impact and urgency
+func (m PriorityMatrix) Lookup(impact domain.TicketImpact, urgency domain.TicketUrgency) domain.TicketPriority {
+	if priority, ok := m[impact][urgency]; ok {
+		return priority
+	}
+	return defaultPriorityMatrix[impact][urgency]
+}
+
+// Entries lists the matrix cells from the highest impact and urgency down
+func (m PriorityMatrix) Entries() []domain.PriorityMatrixEntry {
+	entries := make([]domain.PriorityMatrixEntry, 0, len(ticketImpacts)*len(ticketUrgencies))
+	for _, impact := range ticketImpacts {
+		for _, urgency := range ticketUrgencies {
+			entries = append(entries, domain.PriorityMatrixEntry{
+				Impact:   impact,
+				Urgency:  urgency,
+				Priority: m.Lookup(impact, urgency),
+			})
+		}
+	}
+	return entries
+}
+
+// PriorityOverrideReport summarizes how often agents overrode the computed priority
+type PriorityOverrideReport struct {
+	Tickets           int                              `json:"tickets"`
+	OverriddenTickets int                              `json:"overridden_tickets"`
+	OverrideRate      float64                          `json:"override_rate"`
+	Overrides         int                              `json:"overrides"`
+	ByAgent           []repository.PriorityOverrideRow `json:"by_agent"`
+	ByChange          []repository.PriorityOverrideRow `json:"by_change"`
+	Recent            []domain.PriorityOverride        `json:"recent"`
+}
+
+// PriorityService computes ticket priorities from the admin-configured
+// impact and urgency matrix and reports on manual overrides
+type PriorityService struct {
+	priorityRepo *repository.PriorityRepository
+}
+
+func NewPriorityService(priorityRepo *repository.PriorityRepository) *PriorityService {
+	return &PriorityService{priorityRepo: priorityRepo}
+}
+
+// Matrix returns the configured matrix, falling back to the defaults for
+// cells that were never saved
+func (s *PriorityService) Matrix(ctx context.Context) (PriorityMatrix, error) {
+	entries, err := s.priorityRepo.ListMatrix(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make(PriorityMatrix, len(ticketImpacts))
+	for _, entry := range entries {
+		if matrix[entry.Impact] == nil {
+			matrix[entry.Impact] = make(map[domain.TicketUrgency]domain.TicketPriority, len(ticketUrgencies))
+		}
+		matrix[entry.Impact][entry.Urgency] = entry.Priority
+	}
+	return matrix, nil
+}
+
+// Compute returns the priority for an impact and urgency
+func (s *PriorityService) Compute(ctx context.Context, impact domain.TicketImpact, urgency domain.TicketUrgency) (domain.TicketPriority, error) {
+	matrix, err := s.Matrix(ctx)
+	if err != nil {
+		return "", err
+	}
+	return matrix.Lookup(impact, urgency), nil
+}
+
+// GetMatrix returns all nine cells of the matrix
+func (s *PriorityService) GetMatrix(ctx context.Context) ([]domain.PriorityMatrixEntry, error) {
+	matrix, err := s.Matrix(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matrix.Entries(), nil
+}
+
+// UpdateMatrix saves the given cells and returns the resulting matrix. Cells
+// that are not given keep their current priority. Existing tickets keep their
+// priority until their impact or urgency changes.
+func (s *PriorityService) UpdateMatrix(ctx context.Context, entries []domain.PriorityMatrixEntry) ([]domain.PriorityMatrixEntry, error) {
+	for _, entry := range entries {
+		if !entry.Impact.IsValid() {
+			return nil, fmt.Errorf("%w: unknown impact %q", ErrInvalidPriorityMatrix, entry.Impact)
+		}
+		if !entry.Urgency.IsValid() {
+			return nil, fmt.Errorf("%w: unknown urgency %q", ErrInvalidPriorityMatrix, entry.Urgency)
+		}
+		if !entry.Priority.IsValid() {
+			return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidPriorityMatrix, entry.Priority)
+		}
+	}
+
+	if err := s.priorityRepo.SaveMatrix(ctx, entries); err != nil {
+		return nil, err
+	}
+	return s.GetMatrix(ctx)
+}
+
+// OverrideReport reports the overrides recorded in the period against the
+// tickets created in it
+func (s *PriorityService) OverrideReport(ctx context.Context, filter repository.PriorityOverrideFilter) (*PriorityOverrideReport, error) {
+	report := &PriorityOverrideReport{}
+
+	var err error
+	if report.Tickets, err = s.priorityRepo.CountTickets(ctx, filter); err != nil {
+		return nil, err
+	}
+	if report.OverriddenTickets, err = s.priorityRepo.CountOverriddenTickets(ctx, filter); err != nil {
+		return nil, err
+	}
+	if report.ByAgent, err = s.priorityRepo.SummarizeOverridesBy(ctx, "agent", filter); err != nil {
+		return nil, err
+	}
+	if report.ByChange, err = s.priorityRepo.SummarizeOverridesBy(ctx, "change", filter); err != nil {
+		return nil, err
+	}
+	if report.Recent, err = s.priorityRepo.ListOverrides(ctx, filter, priorityReportRecent); err != nil {
+		return nil, err
+	}
+
+	for _, row := range report.ByChange {
+		report.Overrides += row.Overrides
+	}
+	if report.Tickets > 0 {
+		report.OverrideRate = float64(report.OverriddenTickets) / float64(report.Tickets)
+	}
+	return report, nil
+}
+
+// BackfillImpactUrgency gives tickets created before the priority matrix an
+// impact and urgency that the matrix maps to their current priority. Tickets
+// whose priority no cell yields are kept as overrides.
+func (s *PriorityService) BackfillImpactUrgency(ctx context.Context) error {
+	matrix, err := s.Matrix(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, priority := range []domain.TicketPriority{domain.CriticalPriority, domain.HighPriority, domain.MediumPriority, domain.LowPriority} {
+		impact, urgency, ok := matrix.cellFor(priority)
+		reason := ""
+		if !ok {
+			impact, urgency = domain.MediumImpact, domain.MediumUrgency
+			reason = "Set before impact and urgency were recorded"
+		}
+
+		count, err := s.priorityRepo.BackfillImpactUrgency(ctx, priority, impact, urgency, reason)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			logging.FromContext(ctx).Info("Backfilled ticket impact and urgency", "priority", priority, "impact", impact, "urgency", urgency, "tickets", count)
+		}
+	}
+	return nil
+}
+
+// backfillCells lists the matrix cells from the middle outwards, so that
+// backfilled tickets do not overstate their impact or urgency
+var backfillCells = [][2]string{
+	{"medium", "medium"},
+	{"medium", "high"}, {"high", "medium"}, {"medium", "low"}, {"low", "medium"},
+	{"high", "high"}, {"high", "low"}, {"low", "high"}, {"low", "low"},
+}
+
+// cellFor returns the first cell of backfillCells that yields priority
+func (m PriorityMatrix) cellFor(priority domain.TicketPriority) (domain.TicketImpact, domain.TicketUrgency, bool) {
+	for _, cell := range backfillCells {
+		impact, urgency := domain.TicketImpact(cell[0]), domain.TicketUrgency(cell[1])
+		if m.Lookup(impact, urgency) == priority {
+			return impact, urgency, true
+		}
+	}
+	return "", "", false
+}
+
+// applyPriority derives the ticket's priority from its impact and urgency.
+// A priority that differs from the computed one is kept as an override when
+// it carries a reason; a new or changed override is returned so it can be
+// recorded. previous is the ticket as stored, or nil for a new ticket.
+func applyPriority(matrix PriorityMatrix, ticket, previous *domain.Ticket, actorID uint) (*domain.PriorityOverride, error) {
+	if ticket.Impact == "" {
+		ticket.Impact = domain.MediumImpact
+	}
+	if ticket.Urgency == "" {
+		ticket.Urgency = domain.MediumUrgency
+	}
+	if !ticket.Impact.IsValid() {
+		return nil, fmt.Errorf("%w: unknown impact %q", ErrInvalidTicketPriority, ticket.Impact)
+	}
+	if !ticket.Urgency.IsValid() {
+		return nil, fmt.Errorf("%w: unknown urgency %q", ErrInvalidTicketPriority, ticket.Urgency)
+	}
+
+	if ticket.Priority != "" && !ticket.Priority.IsValid() {
+		return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidTicketPriority, ticket.Priority)
+	}
+
+	computed := matrix.Lookup(ticket.Impact, ticket.Urgency)
+	reason := strings.TrimSpace(ticket.PriorityOverrideReason)
+	changed := ticket.Priority != "" && (previous == nil || ticket.Priority != previous.Priority)
+
+	// Without a reason an unchanged priority simply follows impact and urgency
+	if ticket.Priority == "" || ticket.Priority == computed || (reason == "" && !changed) {
+		ticket.Priority = computed
+		ticket.PriorityOverrideReason = ""
+		return nil, nil
+	}
+	if reason == "" {
+		return nil, ErrPriorityOverrideReason
+	}
+
+	ticket.PriorityOverrideReason = reason
+	if previous != nil && previous.Priority == ticket.Priority && previous.PriorityOverrideReason == reason {
+		return nil, nil
+	}
+	return &domain.PriorityOverride{
+		TicketID:         ticket.ID,
+		ComputedPriority: computed,
+		Priority:         ticket.Priority,
+		Reason:           reason,
+		OverriddenByID:   actorID,
+	}, nil
+}
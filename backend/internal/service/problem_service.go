@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidProblem is returned when a problem fails validation
+var ErrInvalidProblem = errors.New("invalid problem")
+
+// ProblemTicketResult reports what resolving a problem did to one linked incident
+type ProblemTicketResult struct {
+	TicketID uint   `json:"ticket_id"`
+	Action   string `json:"action"` // resolved, skipped or failed
+	Error    string `json:"error,omitempty"`
+}
+
+// ProblemResolution is the outcome of resolving a problem
+type ProblemResolution struct {
+	Problem *domain.Problem       `json:"problem"`
+	Tickets []ProblemTicketResult `json:"tickets"`
+}
+
+// ProblemService tracks the root causes behind incident tickets. Resolving a
+// problem resolves its open incidents and tells their requesters why.
+type ProblemService struct {
+	problemRepo    *repository.ProblemRepository
+	ticketRepo     *repository.TicketRepository
+	userRepo       *repository.UserRepository
+	ticketService  *TicketService
+	commentService *CommentService
+}
+
+func NewProblemService(problemRepo *repository.ProblemRepository, ticketRepo *repository.TicketRepository, userRepo *repository.UserRepository, ticketService *TicketService, commentService *CommentService) *ProblemService {
+	return &ProblemService{
+		problemRepo:    problemRepo,
+		ticketRepo:     ticketRepo,
+		userRepo:       userRepo,
+		ticketService:  ticketService,
+		commentService: commentService,
+	}
+}
+
+// CreateProblem records a new problem. Problems are resolved through
+// ResolveProblem, so they cannot be created resolved.
+func (s *ProblemService) CreateProblem(ctx context.Context, problem *domain.Problem, actorID uint) error {
+	if problem.Status == "" {
+		problem.Status = domain.ProblemOpen
+	}
+	if problem.Priority == "" {
+		problem.Priority = domain.MediumPriority
+	}
+	if err := validateProblem(problem); err != nil {
+		return err
+	}
+	if problem.Status == domain.ProblemResolved {
+		return fmt.Errorf("%w: resolve the problem once it is created", ErrInvalidProblem)
+	}
+	if err := s.checkOwner(ctx, problem); err != nil {
+		return err
+	}
+
+	problem.CreatedByID = actorID
+	applyProblemRules(problem)
+	return s.problemRepo.Create(ctx, problem)
+}
+
+func (s *ProblemService) GetProblem(ctx context.Context, id uint) (*domain.Problem, error) {
+	problem, err := s.problemRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	problems := []domain.Problem{*problem}
+	if err := s.attachTicketCounts(ctx, problems); err != nil {
+		return nil, err
+	}
+	return &problems[0], nil
+}
+
+// ListProblems returns problems, optionally only those in one status
+func (s *ProblemService) ListProblems(ctx context.Context, status domain.ProblemStatus, limit, offset int) ([]domain.Problem, error) {
+	problems, err := s.problemRepo.List(ctx, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachTicketCounts(ctx, problems); err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// UpdateProblem saves changes to a problem. Moving it to resolved must go
+// through ResolveProblem so that its incidents are resolved too.
+func (s *ProblemService) UpdateProblem(ctx context.Context, problem *domain.Problem, previousStatus domain.ProblemStatus) error {
+	if err := validateProblem(problem); err != nil {
+		return err
+	}
+	if problem.Status == domain.ProblemResolved && previousStatus != domain.ProblemResolved {
+		return fmt.Errorf("%w: use the resolve action to resolve a problem", ErrInvalidProblem)
+	}
+	if err := s.checkOwner(ctx, problem); err != nil {
+		return err
+	}
+	if problem.Status != domain.ProblemResolved && problem.Status != domain.ProblemClosed {
+		problem.ResolvedAt = nil
+	}
+
+	applyProblemRules(problem)
+	return s.problemRepo.Update(ctx, problem)
+}
+
+func (s *ProblemService) DeleteProblem(ctx context.Context, id uint) error {
+	err := s.problemRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListTickets returns the incidents linked to a problem
+func (s *ProblemService) ListTickets(ctx context.Context, problemID uint) ([]domain.Ticket, error) {
+	if _, err := s.GetProblem(ctx, problemID); err != nil {
+		return nil, err
+	}
+	return s.problemRepo.ListTickets(ctx, problemID)
+}
+
+// LinkTickets links incident tickets to a problem, moving them from any
+// problem they were linked to before
+func (s *ProblemService) LinkTickets(ctx context.Context, problemID uint, ticketIDs []uint, actorID uint) error {
+	if _, err := s.GetProblem(ctx, problemID); err != nil {
+		return err
+	}
+
+	for _, ticketID := range uniqueIDs(ticketIDs) {
+		_, err := s.ticketService.ModifyTicket(ctx, ticketID, actorID, func(ticket *domain.Ticket) {
+			ticket.ProblemID = &problemID
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("ticket %d: %w", ticketID, err)
+		}
+	}
+	return nil
+}
+
+// UnlinkTicket removes an incident from a problem. It returns ErrNotFound if
+// the ticket is not linked to the problem.
+func (s *ProblemService) UnlinkTicket(ctx context.Context, problemID, ticketID, actorID uint) error {
+	ticket, err := s.ticketRepo.GetByIDShallow(ctx, ticketID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if ticket.ProblemID == nil || *ticket.ProblemID != problemID {
+		return ErrNotFound
+	}
+
+	_, err = s.ticketService.ModifyTicket(ctx, ticketID, actorID, func(ticket *domain.Ticket) {
+		ticket.ProblemID = nil
+	})
+	return err
+}
+
+// ResolveProblem marks the problem resolved and resolves every linked
+// incident that is still open, adding the workaround and resolution to each
+// as a public comment. A failure on one incident does not stop the others.
+func (s *ProblemService) ResolveProblem(ctx context.Context, problem *domain.Problem, resolution string, viewer domain.Viewer) (*ProblemResolution, error) {
+	if resolution = strings.TrimSpace(resolution); resolution != "" {
+		problem.Resolution = resolution
+	}
+	if strings.TrimSpace(problem.Resolution) == "" {
+		return nil, fmt.Errorf("%w: a resolution is required", ErrInvalidProblem)
+	}
+
+	problem.Status = domain.ProblemResolved
+	applyProblemRules(problem)
+	if err := s.problemRepo.Update(ctx, problem); err != nil {
+		return nil, err
+	}
+
+	tickets, err := s.problemRepo.ListTickets(ctx, problem.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProblemResolution{Problem: problem, Tickets: make([]ProblemTicketResult, 0, len(tickets))}
+	content := problemResolutionComment(problem)
+	for _, ticket := range tickets {
+		if ticket.Status == domain.ResolvedStatus || ticket.Status == domain.ClosedStatus {
+			result.Tickets = append(result.Tickets, ProblemTicketResult{TicketID: ticket.ID, Action: "skipped"})
+			continue
+		}
+
+		err := s.resolveIncident(ctx, ticket.ID, content, viewer)
+		if err != nil {
+			result.Tickets = append(result.Tickets, ProblemTicketResult{TicketID: ticket.ID, Action: "failed", Error: err.Error()})
+			continue
+		}
+		result.Tickets = append(result.Tickets, ProblemTicketResult{TicketID: ticket.ID, Action: "resolved"})
+	}
+
+	problem.TicketCount = len(tickets)
+	return result, nil
+}
+
+func (s *ProblemService) resolveIncident(ctx context.Context, ticketID uint, content string, viewer domain.Viewer) error {
+	err := s.commentService.CreateComment(ctx, &domain.Comment{
+		TicketID: ticketID,
+		Content:  content,
+		IsPublic: true,
+	}, viewer)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.ticketService.ModifyTicket(ctx, ticketID, viewer.UserID, func(ticket *domain.Ticket) {
+		if ticket.Status != domain.ClosedStatus {
+			ticket.Status = domain.ResolvedStatus
+		}
+	})
+	return err
+}
+
+func (s *ProblemService) attachTicketCounts(ctx context.Context, problems []domain.Problem) error {
+	ids := make([]uint, 0, len(problems))
+	for _, problem := range problems {
+		ids = append(ids, problem.ID)
+	}
+
+	counts, err := s.problemRepo.CountTickets(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range problems {
+		problems[i].TicketCount = counts[problems[i].ID]
+	}
+	return nil
+}
+
+// checkOwner makes sure problems are owned by agents or admins
+func (s *ProblemService) checkOwner(ctx context.Context, problem *domain.Problem) error {
+	if problem.OwnerID == nil {
+		return nil
+	}
+	owner, err := s.userRepo.GetByID(ctx, *problem.OwnerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: owner not found", ErrInvalidProblem)
+		}
+		return err
+	}
+	if owner.Role != domain.AgentRole && owner.Role != domain.AdminRole {
+		return fmt.Errorf("%w: problems can only be owned by agents or admins", ErrInvalidProblem)
+	}
+	return nil
+}
+
+func validateProblem(problem *domain.Problem) error {
+	problem.Title = strings.TrimSpace(problem.Title)
+	if problem.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidProblem)
+	}
+	if !problem.Status.IsValid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidProblem, problem.Status)
+	}
+	if !problem.Priority.IsValid() {
+		return fmt.Errorf("%w: unknown priority %q", ErrInvalidProblem, problem.Priority)
+	}
+	// A known error is one whose root cause is understood and can be worked around
+	if problem.Status == domain.ProblemKnownError &&
+		(strings.TrimSpace(problem.RootCause) == "" || strings.TrimSpace(problem.Workaround) == "") {
+		return fmt.Errorf("%w: a known error needs a root cause and a workaround", ErrInvalidProblem)
+	}
+	return nil
+}
+
+// applyProblemRules keeps the timestamps derived from the problem status consistent
+func applyProblemRules(problem *domain.Problem) {
+	now := time.Now()
+	if problem.Status == domain.ProblemKnownError && problem.KnownErrorAt == nil {
+		problem.KnownErrorAt = &now
+	}
+	if problem.Status == domain.ProblemResolved && problem.ResolvedAt == nil {
+		problem.ResolvedAt = &now
+	}
+}
+
+// problemResolutionComment renders the Markdown comment added to incidents
+// when their problem is resolved
+func problemResolutionComment(problem *domain.Problem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This incident was caused by problem #%d, **%s**, which has been resolved.\n", problem.ID, problem.Title)
+	if workaround := strings.TrimSpace(problem.Workaround); workaround != "" {
+		fmt.Fprintf(&b, "\n**Workaround**\n\n%s\n", workaround)
+	}
+	fmt.Fprintf(&b, "\n**Resolution**\n\n%s\n", strings.TrimSpace(problem.Resolution))
+	return b.String()
+}
@@ -0,0 +1,10 @@
+package service
+
+import "helpdesk-backend/internal/events"
+
+// publish sends an event to the broker, if the service was given one
+func publish(broker *events.Broker, event events.Event) {
+	if broker != nil {
+		broker.Publish(event)
+	}
+}
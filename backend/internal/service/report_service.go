@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalidReport is returned when report parameters are out of range
+var ErrInvalidReport = errors.New("invalid report")
+
+// maxSeriesBuckets keeps hourly series over long ranges from overloading the database
+const maxSeriesBuckets = 1000
+
+// SeriesInterval is the width of a time series bucket
+type SeriesInterval string
+
+const (
+	HourInterval  SeriesInterval = "hour"
+	DayInterval   SeriesInterval = "day"
+	WeekInterval  SeriesInterval = "week"
+	MonthInterval SeriesInterval = "month"
+)
+
+// IsValid reports whether i is a known series interval
+func (i SeriesInterval) IsValid() bool {
+	switch i {
+	case HourInterval, DayInterval, WeekInterval, MonthInterval:
+		return true
+	}
+	return false
+}
+
+// TicketSeriesQuery selects the tickets and buckets of a ticket series.
+// From and To are calendar dates in Location, both inclusive.
+type TicketSeriesQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval SeriesInterval
+	Location *time.Location
+	Filter   repository.TicketReportFilter
+}
+
+// TicketSeriesBucket holds the ticket metrics of one bucket. Resolution times
+// are in hours and, like SLA compliance, nil when nothing was resolved.
+type TicketSeriesBucket struct {
+	Start                 time.Time `json:"start"`
+	Created               int       `json:"created"`
+	Resolved              int       `json:"resolved"`
+	Reopened              int       `json:"reopened"`
+	Backlog               int       `json:"backlog"`
+	MedianResolutionHours *float64  `json:"median_resolution_hours"`
+	P90ResolutionHours    *float64  `json:"p90_resolution_hours"`
+	SLACompliance         *float64  `json:"sla_compliance"` // percentage of resolved tickets that met their SLA
+}
+
+// TicketSeriesReport is a bucketed time series of ticket metrics
+type TicketSeriesReport struct {
+	Interval SeriesInterval       `json:"interval"`
+	TimeZone string               `json:"time_zone"`
+	From     string               `json:"from"`
+	To       string               `json:"to"`
+	Buckets  []TicketSeriesBucket `json:"buckets"`
+}
+
+// ReportService builds reports from aggregate ticket queries
+type ReportService struct {
+	ticketRepo *repository.TicketRepository
+}
+
+func NewReportService(ticketRepo *repository.TicketRepository) *ReportService {
+	return &ReportService{ticketRepo: ticketRepo}
+}
+
+// TicketSeries returns ticket volume, backlog and resolution metrics in
+// buckets aligned to the local time of query.Location
+func (s *ReportService) TicketSeries(ctx context.Context, query TicketSeriesQuery) (*TicketSeriesReport, error) {
+	if !query.Interval.IsValid() {
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrInvalidReport, query.Interval)
+	}
+	if query.To.Before(query.From) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidReport)
+	}
+	if query.Location == nil {
+		query.Location = time.UTC
+	}
+
+	// Buckets are computed on wall-clock times, which are kept in UTC so
+	// that daylight saving changes do not shift them
+	from := truncateWallClock(wallClock(query.From), query.Interval)
+	to := wallClock(query.To).AddDate(0, 0, 1)
+	buckets := 0
+	for t := from; t.Before(to); t = nextBucket(t, query.Interval) {
+		if buckets++; buckets > maxSeriesBuckets {
+			return nil, fmt.Errorf("%w: more than %d buckets, use a wider interval or a shorter range", ErrInvalidReport, maxSeriesBuckets)
+		}
+	}
+
+	rows, err := s.ticketRepo.TicketSeries(ctx, repository.SeriesBucketing{
+		Unit: string(query.Interval),
+		Zone: query.Location.String(),
+		From: from,
+		To:   to,
+	}, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TicketSeriesReport{
+		Interval: query.Interval,
+		TimeZone: query.Location.String(),
+		From:     query.From.Format("2006-01-02"),
+		To:       query.To.Format("2006-01-02"),
+		Buckets:  make([]TicketSeriesBucket, 0, len(rows)),
+	}
+	for _, row := range rows {
+		bucket := TicketSeriesBucket{
+			Start:                 localTime(row.Bucket, query.Location),
+			Created:               row.Created,
+			Resolved:              row.Resolved,
+			Reopened:              row.Reopened,
+			Backlog:               row.Backlog,
+			MedianResolutionHours: roundHours(row.MedianHours),
+			P90ResolutionHours:    roundHours(row.P90Hours),
+		}
+		if row.SLATracked > 0 {
+			compliance := math.Round(float64(row.SLAMet)/float64(row.SLATracked)*1000) / 10
+			bucket.SLACompliance = &compliance
+		}
+		report.Buckets = append(report.Buckets, bucket)
+	}
+	return report, nil
+}
+
+// wallClock returns the wall-clock time of t expressed in UTC
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// localTime places a wall-clock time expressed in UTC in loc
+func localTime(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// truncateWallClock returns the start of the bucket containing t, matching
+// PostgreSQL's date_trunc
+func truncateWallClock(t time.Time, interval SeriesInterval) time.Time {
+	switch interval {
+	case HourInterval:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.UTC)
+	case WeekInterval:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -int((t.Weekday()+6)%7))
+	case MonthInterval:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextBucket(t time.Time, interval SeriesInterval) time.Time {
+	switch interval {
+	case HourInterval:
+		return t.Add(time.Hour)
+	case WeekInterval:
+		return t.AddDate(0, 0, 7)
+	case MonthInterval:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func roundHours(hours *float64) *float64 {
+	if hours == nil {
+		return nil
+	}
+	rounded := math.Round(*hours*10) / 10
+	return &rounded
+}
+
+// AgentPerformance is the performance and workload of one agent. Times are in
+// hours and nil when there is nothing to measure.
+type AgentPerformance struct {
+	AgentID uint            `json:"agent_id"`
+	Name    string          `json:"name"`
+	Email   string          `json:"email"`
+	Role    domain.UserRole `json:"role"`
+
+	Assigned     int `json:"assigned"` // created in the range and assigned to the agent now
+	Resolved     int `json:"resolved"`
+	Reopened     int `json:"reopened"`
+	OpenWorkload int `json:"open_workload"`
+
+	MedianFirstResponseHours *float64 `json:"median_first_response_hours"`
+	AvgFirstResponseHours    *float64 `json:"avg_first_response_hours"`
+	MedianResolutionHours    *float64 `json:"median_resolution_hours"`
+	AvgResolutionHours       *float64 `json:"avg_resolution_hours"`
+	SLABreachRate            *float64 `json:"sla_breach_rate"` // percentage of resolved tickets resolved after their SLA breach time
+}
+
+// AgentPerformanceReport covers the agents and admins over a date range
+type AgentPerformanceReport struct {
+	From   string             `json:"from"`
+	To     string             `json:"to"`
+	Agents []AgentPerformance `json:"agents"`
+}
+
+// AgentPerformance reports per agent on the tickets created or resolved
+// between the from and to dates, both inclusive
+func (s *ReportService) AgentPerformance(ctx context.Context, from, to time.Time) (*AgentPerformanceReport, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrInvalidReport)
+	}
+
+	rows, err := s.ticketRepo.AgentStats(ctx, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AgentPerformanceReport{
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Agents: make([]AgentPerformance, 0, len(rows)),
+	}
+	for _, row := range rows {
+		agent := AgentPerformance{
+			AgentID:                  row.AgentID,
+			Name:                     strings.TrimSpace(row.FirstName + " " + row.LastName),
+			Email:                    row.Email,
+			Role:                     row.Role,
+			Assigned:                 row.Assigned,
+			Resolved:                 row.Resolved,
+			Reopened:                 row.Reopened,
+			OpenWorkload:             row.OpenWorkload,
+			MedianFirstResponseHours: roundHours(row.MedianFirstResponseHours),
+			AvgFirstResponseHours:    roundHours(row.AvgFirstResponseHours),
+			MedianResolutionHours:    roundHours(row.MedianResolutionHours),
+			AvgResolutionHours:       roundHours(row.AvgResolutionHours),
+		}
+		if row.SLATracked > 0 {
+			rate := math.Round(float64(row.SLABreached)/float64(row.SLATracked)*1000) / 10
+			agent.SLABreachRate = &rate
+		}
+		report.Agents = append(report.Agents, agent)
+	}
+	return report, nil
+}
+
+// backlogAgeLimits are the upper bounds in hours of the backlog age buckets:
+// one day, three days, a week, two weeks and a month
+var backlogAgeLimits = []int{24, 72, 168, 336, 720}
+
+// oldestUnassignedLimit is how many unassigned tickets the backlog widget lists
+const oldestUnassignedLimit = 10
+
+// BacklogAgeBucket counts open tickets within an age range. MaxHours is nil
+// for the last, open-ended bucket.
+type BacklogAgeBucket struct {
+	MinHours int  `json:"min_hours"`
+	MaxHours *int `json:"max_hours"`
+	Count    int  `json:"count"`
+}
+
+// SLARisk counts open tickets that breached or are about to breach their SLA.
+// Tickets due within the next hour are also counted in NextDay.
+type SLARisk struct {
+	Breached int `json:"breached"`
+	NextHour int `json:"next_hour"`
+	NextDay  int `json:"next_day"`
+}
+
+// SLAComplianceWindow reports how many tickets resolved in a window met their SLA
+type SLAComplianceWindow struct {
+	Resolved   int      `json:"resolved"`
+	Met        int      `json:"met"`
+	Percentage *float64 `json:"percentage"` // nil when nothing was resolved
+}
+
+// PrioritySLACompliance reports SLA compliance of one priority
+type PrioritySLACompliance struct {
+	Priority   domain.TicketPriority `json:"priority"`
+	Last7Days  SLAComplianceWindow   `json:"last_7_days"`
+	Last30Days SLAComplianceWindow   `json:"last_30_days"`
+}
+
+// BacklogWidgets are the dashboard widgets on the age and SLA risk of the backlog
+type BacklogWidgets struct {
+	AgeHistogram     []BacklogAgeBucket      `json:"age_histogram"`
+	SLARisk          SLARisk                 `json:"sla_risk"`
+	SLACompliance    []PrioritySLACompliance `json:"sla_compliance"`
+	OldestUnassigned []domain.Ticket         `json:"oldest_unassigned"`
+}
+
+// Backlog returns how old the open tickets are, how many are at risk of
+// breaching their SLA, recent SLA compliance per priority and the oldest
+// tickets still waiting for an assignee
+func (s *ReportService) Backlog(ctx context.Context) (*BacklogWidgets, error) {
+	now := time.Now()
+	widgets := &BacklogWidgets{}
+
+	ages, err := s.ticketRepo.OpenAgeHistogram(ctx, now, backlogAgeLimits)
+	if err != nil {
+		return nil, err
+	}
+	for _, age := range ages {
+		bucket := BacklogAgeBucket{MinHours: age.MinHours, Count: age.Count}
+		if age.MaxHours > 0 {
+			maxHours := age.MaxHours
+			bucket.MaxHours = &maxHours
+		}
+		widgets.AgeHistogram = append(widgets.AgeHistogram, bucket)
+	}
+
+	if widgets.SLARisk.Breached, err = s.ticketRepo.GetSLABreachesCount(ctx); err != nil {
+		return nil, err
+	}
+	if widgets.SLARisk.NextHour, err = s.ticketRepo.CountSLADueBetween(ctx, now, now.Add(time.Hour)); err != nil {
+		return nil, err
+	}
+	if widgets.SLARisk.NextDay, err = s.ticketRepo.CountSLADueBetween(ctx, now, now.Add(24*time.Hour)); err != nil {
+		return nil, err
+	}
+
+	last7, err := s.ticketRepo.SLAComplianceByPriority(ctx, now.AddDate(0, 0, -7))
+	if err != nil {
+		return nil, err
+	}
+	last30, err := s.ticketRepo.SLAComplianceByPriority(ctx, now.AddDate(0, 0, -30))
+	if err != nil {
+		return nil, err
+	}
+	for _, priority := range []domain.TicketPriority{domain.CriticalPriority, domain.HighPriority, domain.MediumPriority, domain.LowPriority} {
+		widgets.SLACompliance = append(widgets.SLACompliance, PrioritySLACompliance{
+			Priority:   priority,
+			Last7Days:  slaComplianceWindow(last7, priority),
+			Last30Days: slaComplianceWindow(last30, priority),
+		})
+	}
+
+	if widgets.OldestUnassigned, err = s.ticketRepo.ListOldestUnassigned(ctx, oldestUnassignedLimit); err != nil {
+		return nil, err
+	}
+	return widgets, nil
+}
+
+func slaComplianceWindow(rows []repository.SLAComplianceRow, priority domain.TicketPriority) SLAComplianceWindow {
+	for _, row := range rows {
+		if row.Priority != priority {
+			continue
+		}
+		window := SLAComplianceWindow{Resolved: row.Resolved, Met: row.Met}
+		if row.Resolved > 0 {
+			percentage := math.Round(float64(row.Met)/float64(row.Resolved)*1000) / 10
+			window.Percentage = &percentage
+		}
+		return window
+	}
+	return SLAComplianceWindow{}
+}
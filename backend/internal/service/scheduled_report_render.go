@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/mail"
+	"helpdesk-backend/internal/repository"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportTable is one table of a rendered report. Each table becomes a CSV
+// attachment named after Slug.
+type reportTable struct {
+	Slug   string
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// reportDocument is a report built for delivery
+type reportDocument struct {
+	Title   string
+	Period  string
+	Summary []string
+	Tables  []reportTable
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, Helvetica, sans-serif; font-size: 14px; color: #222;">
+<h2>{{.Title}}</h2>
+<p>{{.Period}}</p>
+{{if .Summary}}<ul>
+{{range .Summary}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{range .Tables}}<h3>{{.Title}}</h3>
+{{if .Rows}}<table cellpadding="4" cellspacing="0" style="border-collapse: collapse;">
+<tr>{{range .Header}}<th style="border: 1px solid #ccc; background: #f4f4f4; text-align: left;">{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td style="border: 1px solid #ccc;">{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{else}}<p>No data.</p>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// build runs the report as of now. Date ranges cover the complete days before
+// the day of the run in the report's time zone.
+func (s *ScheduledReportService) build(ctx context.Context, report *domain.ScheduledReport, now time.Time) (*reportDocument, error) {
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	to := today.AddDate(0, 0, -1)
+	from := today.AddDate(0, 0, -report.Parameters.RangeDays)
+
+	switch report.Kind {
+	case domain.TicketsReport:
+		return s.buildTicketsReport(ctx, report, from, to, loc)
+	case domain.AgentsReport:
+		return s.buildAgentsReport(ctx, report, from, to)
+	case domain.BacklogReport:
+		return s.buildBacklogReport(ctx, report, local)
+	}
+	return nil, fmt.Errorf("unknown report kind %q", report.Kind)
+}
+
+func (s *ScheduledReportService) buildTicketsReport(ctx context.Context, report *domain.ScheduledReport, from, to time.Time, loc *time.Location) (*reportDocument, error) {
+	params := report.Parameters
+	series, err := s.reportService.TicketSeries(ctx, TicketSeriesQuery{
+		From:     from,
+		To:       to,
+		Interval: SeriesInterval(params.Interval),
+		Location: loc,
+		Filter: repository.TicketReportFilter{
+			Category:   params.Category,
+			Priority:   params.Priority,
+			AssigneeID: params.AssigneeID,
+			Department: params.Department,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	table := reportTable{
+		Slug:   "tickets",
+		Title:  "Tickets by " + params.Interval,
+		Header: []string{"Period start", "Created", "Resolved", "Reopened", "Backlog", "Median resolution (h)", "P90 resolution (h)", "SLA compliance"},
+	}
+	var created, resolved int
+	for _, bucket := range series.Buckets {
+		created += bucket.Created
+		resolved += bucket.Resolved
+		table.Rows = append(table.Rows, []string{
+			bucket.Start.Format("2006-01-02 15:04"),
+			strconv.Itoa(bucket.Created),
+			strconv.Itoa(bucket.Resolved),
+			strconv.Itoa(bucket.Reopened),
+			strconv.Itoa(bucket.Backlog),
+			formatReportHours(bucket.MedianResolutionHours),
+			formatReportHours(bucket.P90ResolutionHours),
+			formatReportPercentage(bucket.SLACompliance),
+		})
+	}
+
+	document := &reportDocument{
+		Title:  report.Name,
+		Period: reportPeriod(from, to, report.TimeZone),
+		Summary: []string{
+			fmt.Sprintf("Created: %d", created),
+			fmt.Sprintf("Resolved: %d", resolved),
+		},
+		Tables: []reportTable{table},
+	}
+	if n := len(series.Buckets); n > 0 {
+		document.Summary = append(document.Summary, fmt.Sprintf("Backlog at the end of the period: %d", series.Buckets[n-1].Backlog))
+	}
+	return document, nil
+}
+
+func (s *ScheduledReportService) buildAgentsReport(ctx context.Context, report *domain.ScheduledReport, from, to time.Time) (*reportDocument, error) {
+	performance, err := s.reportService.AgentPerformance(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	table := reportTable{
+		Slug:   "agents",
+		Title:  "Agent performance",
+		Header: []string{"Agent", "Email", "Assigned", "Resolved", "Reopened", "Open workload", "Median first response (h)", "Median resolution (h)", "SLA breach rate"},
+	}
+	for _, agent := range performance.Agents {
+		table.Rows = append(table.Rows, []string{
+			agent.Name,
+			agent.Email,
+			strconv.Itoa(agent.Assigned),
+			strconv.Itoa(agent.Resolved),
+			strconv.Itoa(agent.Reopened),
+			strconv.Itoa(agent.OpenWorkload),
+			formatReportHours(agent.MedianFirstResponseHours),
+			formatReportHours(agent.MedianResolutionHours),
+			formatReportPercentage(agent.SLABreachRate),
+		})
+	}
+
+	return &reportDocument{
+		Title:  report.Name,
+		Period: reportPeriod(from, to, report.TimeZone),
+		Tables: []reportTable{table},
+	}, nil
+}
+
+func (s *ScheduledReportService) buildBacklogReport(ctx context.Context, report *domain.ScheduledReport, now time.Time) (*reportDocument, error) {
+	widgets, err := s.reportService.Backlog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ages := reportTable{
+		Slug:   "backlog-age",
+		Title:  "Open tickets by age",
+		Header: []string{"Age", "Tickets"},
+	}
+	for _, bucket := range widgets.AgeHistogram {
+		label := fmt.Sprintf("%dh or more", bucket.MinHours)
+		if bucket.MaxHours != nil {
+			label = fmt.Sprintf("%d-%dh", bucket.MinHours, *bucket.MaxHours)
+		}
+		ages.Rows = append(ages.Rows, []string{label, strconv.Itoa(bucket.Count)})
+	}
+
+	compliance := reportTable{
+		Slug:   "sla-compliance",
+		Title:  "SLA compliance",
+		Header: []string{"Priority", "Resolved (7 days)", "Compliance (7 days)", "Resolved (30 days)", "Compliance (30 days)"},
+	}
+	for _, row := range widgets.SLACompliance {
+		compliance.Rows = append(compliance.Rows, []string{
+			string(row.Priority),
+			strconv.Itoa(row.Last7Days.Resolved),
+			formatReportPercentage(row.Last7Days.Percentage),
+			strconv.Itoa(row.Last30Days.Resolved),
+			formatReportPercentage(row.Last30Days.Percentage),
+		})
+	}
+
+	unassigned := reportTable{
+		Slug:   "oldest-unassigned",
+		Title:  "Oldest unassigned tickets",
+		Header: []string{"Ticket", "Title", "Priority", "Created"},
+	}
+	for _, ticket := range widgets.OldestUnassigned {
+		key := ""
+		if ticket.Key != nil {
+			key = *ticket.Key
+		}
+		unassigned.Rows = append(unassigned.Rows, []string{
+			ticketLabel(ticket.ID, key),
+			ticket.Title,
+			string(ticket.Priority),
+			ticket.CreatedAt.In(now.Location()).Format("2006-01-02 15:04"),
+		})
+	}
+
+	return &reportDocument{
+		Title:  report.Name,
+		Period: fmt.Sprintf("As of %s (%s)", now.Format("2006-01-02 15:04"), report.TimeZone),
+		Summary: []string{
+			fmt.Sprintf("SLA breached: %d", widgets.SLARisk.Breached),
+			fmt.Sprintf("SLA due within the next hour: %d", widgets.SLARisk.NextHour),
+			fmt.Sprintf("SLA due within the next day: %d", widgets.SLARisk.NextDay),
+		},
+		Tables: []reportTable{ages, compliance, unassigned},
+	}, nil
+}
+
+// message renders the document in the report's formats. HTML is sent as the
+// body next to a plain text summary, and every table is attached as CSV.
+func (d *reportDocument) message(report *domain.ScheduledReport) (mail.Message, error) {
+	msg := mail.Message{
+		To:      report.Recipients,
+		Subject: d.Title + " - " + d.Period,
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n%s\n", d.Title, d.Period)
+	if len(d.Summary) > 0 {
+		text.WriteString("\n")
+		for _, line := range d.Summary {
+			fmt.Fprintf(&text, "- %s\n", line)
+		}
+	}
+
+	for _, format := range report.Formats {
+		switch format {
+		case domain.HTMLReportFormat:
+			var html bytes.Buffer
+			if err := reportHTMLTemplate.Execute(&html, d); err != nil {
+				return msg, err
+			}
+			msg.HTML = html.String()
+		case domain.CSVReportFormat:
+			stamp := time.Now().Format("20060102")
+			for _, table := range d.Tables {
+				data, err := table.csv()
+				if err != nil {
+					return msg, err
+				}
+				msg.Attachments = append(msg.Attachments, mail.Attachment{
+					FileName:    fmt.Sprintf("%s-%s.csv", table.Slug, stamp),
+					ContentType: "text/csv",
+					Data:        data,
+				})
+			}
+			text.WriteString("\nThe report data is attached as CSV.\n")
+		}
+	}
+
+	msg.Body = text.String()
+	return msg, nil
+}
+
+func (t reportTable) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.Header); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(t.Rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// reportPeriod describes an inclusive date range
+func reportPeriod(from, to time.Time, timeZone string) string {
+	if from.Equal(to) {
+		return fmt.Sprintf("%s (%s)", from.Format("2006-01-02"), timeZone)
+	}
+	return fmt.Sprintf("%s to %s (%s)", from.Format("2006-01-02"), to.Format("2006-01-02"), timeZone)
+}
+
+func formatReportHours(hours *float64) string {
+	if hours == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*hours, 'f', 1, 64)
+}
+
+func formatReportPercentage(percentage *float64) string {
+	if percentage == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*percentage, 'f', 1, 64) + "%"
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/cron"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/mail"
+	"helpdesk-backend/internal/repository"
+	netmail "net/mail"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	scheduledReportPollInterval = time.Minute
+	scheduledReportBatchSize    = 10
+	// scheduledReportTimeout bounds building and sending one report
+	scheduledReportTimeout = 5 * time.Minute
+
+	defaultScheduledReportDays = 7
+	maxScheduledReportDays     = 366
+	maxReportRecipients        = 50
+)
+
+// ErrInvalidScheduledReport is returned when a scheduled report fails validation
+var ErrInvalidScheduledReport = errors.New("invalid scheduled report")
+
+// ScheduledReportInput holds the editable fields of a scheduled report. Nil
+// fields are left untouched on update.
+type ScheduledReportInput struct {
+	Name       *string
+	Kind       *domain.ScheduledReportKind
+	Parameters *domain.ScheduledReportParameters
+	Formats    []domain.ReportFormat
+	Recipients []string
+	Schedule   *string
+	TimeZone   *string
+	IsActive   *bool
+}
+
+// ScheduledReportService manages saved report definitions and emails them to
+// their recipients on schedule or on demand
+type ScheduledReportService struct {
+	scheduledReportRepo *repository.ScheduledReportRepository
+	reportService       *ReportService
+	mailer              *mail.Mailer
+}
+
+func NewScheduledReportService(scheduledReportRepo *repository.ScheduledReportRepository, reportService *ReportService, mailer *mail.Mailer) *ScheduledReportService {
+	return &ScheduledReportService{
+		scheduledReportRepo: scheduledReportRepo,
+		reportService:       reportService,
+		mailer:              mailer,
+	}
+}
+
+func (s *ScheduledReportService) CreateScheduledReport(ctx context.Context, input ScheduledReportInput, actorID uint) (*domain.ScheduledReport, error) {
+	report := &domain.ScheduledReport{
+		TimeZone:    "UTC",
+		Formats:     []domain.ReportFormat{domain.HTMLReportFormat},
+		IsActive:    true,
+		CreatedByID: actorID,
+	}
+	applyScheduledReportInput(report, input)
+	if err := prepareScheduledReport(report, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduledReportRepo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *ScheduledReportService) GetScheduledReport(ctx context.Context, id uint) (*domain.ScheduledReport, error) {
+	report, err := s.scheduledReportRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return report, err
+}
+
+func (s *ScheduledReportService) ListScheduledReports(ctx context.Context) ([]domain.ScheduledReport, error) {
+	return s.scheduledReportRepo.List(ctx)
+}
+
+// UpdateScheduledReport applies the input and reschedules the report from now
+func (s *ScheduledReportService) UpdateScheduledReport(ctx context.Context, id uint, input ScheduledReportInput) (*domain.ScheduledReport, error) {
+	report, err := s.GetScheduledReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyScheduledReportInput(report, input)
+	if err := prepareScheduledReport(report, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduledReportRepo.Update(ctx, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *ScheduledReportService) DeleteScheduledReport(ctx context.Context, id uint) error {
+	err := s.scheduledReportRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListRuns returns a report's run history, newest first
+func (s *ScheduledReportService) ListRuns(ctx context.Context, id uint, limit, offset int) ([]domain.ReportRun, error) {
+	if _, err := s.GetScheduledReport(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.scheduledReportRepo.ListRuns(ctx, id, limit, offset)
+}
+
+// RunNow builds and sends a report straight away, whether or not it is
+// active, and returns the run. A failed delivery is recorded on the run
+// rather than returned as an error. The schedule is not affected.
+func (s *ScheduledReportService) RunNow(ctx context.Context, id, actorID uint) (*domain.ReportRun, error) {
+	report, err := s.GetScheduledReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.scheduledReportRepo.MarkRun(ctx, report.ID, now); err != nil {
+		return nil, err
+	}
+	return s.run(ctx, report, now, &actorID)
+}
+
+// Start sends the reports that are due every minute until ctx is cancelled
+func (s *ScheduledReportService) Start(ctx context.Context) {
+	ticker := time.NewTicker(scheduledReportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue claims the reports that are due and sends them one after another
+func (s *ScheduledReportService) runDue(ctx context.Context) {
+	now := time.Now()
+	reports, err := s.scheduledReportRepo.ClaimDue(ctx, now, scheduledReportBatchSize, func(report *domain.ScheduledReport) *time.Time {
+		return nextReportRun(report, now)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to claim due scheduled reports", "error", err)
+		return
+	}
+
+	for i := range reports {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.run(ctx, &reports[i], now, nil); err != nil {
+			logging.FromContext(ctx).Warn("Failed to run scheduled report", "scheduled_report_id", reports[i].ID, "error", err)
+		}
+	}
+}
+
+// run builds the report as of now, emails it and records the outcome in the
+// run history. Only failures to record the run are returned.
+func (s *ScheduledReportService) run(ctx context.Context, report *domain.ScheduledReport, now time.Time, triggeredByID *uint) (*domain.ReportRun, error) {
+	run := &domain.ReportRun{
+		ScheduledReportID: report.ID,
+		Manual:            triggeredByID != nil,
+		TriggeredByID:     triggeredByID,
+		Status:            domain.ReportRunRunning,
+		Recipients:        len(report.Recipients),
+		StartedAt:         now,
+	}
+	if err := s.scheduledReportRepo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, scheduledReportTimeout)
+	err := s.deliver(runCtx, report, now)
+	cancel()
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = domain.ReportRunSucceeded
+	if err != nil {
+		run.Status = domain.ReportRunFailed
+		run.Error = err.Error()
+	}
+	if err := s.scheduledReportRepo.UpdateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// deliver builds the report and emails it to every recipient in one message
+func (s *ScheduledReportService) deliver(ctx context.Context, report *domain.ScheduledReport, now time.Time) error {
+	if !s.mailer.Enabled() {
+		return fmt.Errorf("smtp is not configured")
+	}
+
+	document, err := s.build(ctx, report, now)
+	if err != nil {
+		return err
+	}
+	msg, err := document.message(report)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(msg)
+}
+
+// applyScheduledReportInput copies the set fields of input onto report
+func applyScheduledReportInput(report *domain.ScheduledReport, input ScheduledReportInput) {
+	if input.Name != nil {
+		report.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Kind != nil {
+		report.Kind = *input.Kind
+	}
+	if input.Parameters != nil {
+		report.Parameters = *input.Parameters
+	}
+	if input.Formats != nil {
+		report.Formats = input.Formats
+	}
+	if input.Recipients != nil {
+		report.Recipients = input.Recipients
+	}
+	if input.Schedule != nil {
+		report.Schedule = strings.TrimSpace(*input.Schedule)
+	}
+	if input.TimeZone != nil {
+		report.TimeZone = strings.TrimSpace(*input.TimeZone)
+	}
+	if input.IsActive != nil {
+		report.IsActive = *input.IsActive
+	}
+}
+
+// prepareScheduledReport validates and normalizes a report and schedules its
+// next run after now
+func prepareScheduledReport(report *domain.ScheduledReport, now time.Time) error {
+	if report.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidScheduledReport)
+	}
+	if !report.Kind.IsValid() {
+		return fmt.Errorf("%w: kind must be tickets, agents or backlog", ErrInvalidScheduledReport)
+	}
+
+	if report.TimeZone == "" || report.TimeZone == "Local" {
+		return fmt.Errorf("%w: time_zone must be an IANA time zone such as Europe/Berlin", ErrInvalidScheduledReport)
+	}
+	if _, err := time.LoadLocation(report.TimeZone); err != nil {
+		return fmt.Errorf("%w: time_zone must be an IANA time zone such as Europe/Berlin", ErrInvalidScheduledReport)
+	}
+	schedule, err := cron.Parse(report.Schedule)
+	if err != nil {
+		return fmt.Errorf("%w: schedule: %v", ErrInvalidScheduledReport, err)
+	}
+	if schedule.Next(now).IsZero() {
+		return fmt.Errorf("%w: schedule never fires", ErrInvalidScheduledReport)
+	}
+
+	if err := prepareReportParameters(report.Kind, &report.Parameters); err != nil {
+		return err
+	}
+
+	if len(report.Formats) == 0 {
+		return fmt.Errorf("%w: at least one format is required", ErrInvalidScheduledReport)
+	}
+	seenFormats := make(map[domain.ReportFormat]bool)
+	formats := report.Formats[:0]
+	for _, format := range report.Formats {
+		if !format.IsValid() {
+			return fmt.Errorf("%w: unknown format %q, use html or csv", ErrInvalidScheduledReport, format)
+		}
+		if !seenFormats[format] {
+			seenFormats[format] = true
+			formats = append(formats, format)
+		}
+	}
+	report.Formats = formats
+
+	seenRecipients := make(map[string]bool)
+	recipients := make([]string, 0, len(report.Recipients))
+	for _, recipient := range report.Recipients {
+		address, err := netmail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("%w: invalid recipient %q", ErrInvalidScheduledReport, recipient)
+		}
+		key := strings.ToLower(address.Address)
+		if !seenRecipients[key] {
+			seenRecipients[key] = true
+			recipients = append(recipients, address.Address)
+		}
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("%w: at least one recipient is required", ErrInvalidScheduledReport)
+	}
+	if len(recipients) > maxReportRecipients {
+		return fmt.Errorf("%w: at most %d recipients are allowed", ErrInvalidScheduledReport, maxReportRecipients)
+	}
+	report.Recipients = recipients
+
+	report.NextRunAt = nextReportRun(report, now)
+	return nil
+}
+
+// prepareReportParameters checks the parameters apply to the kind of report
+// and fills in the defaults
+func prepareReportParameters(kind domain.ScheduledReportKind, params *domain.ScheduledReportParameters) error {
+	if kind == domain.BacklogReport {
+		// The backlog is a snapshot of the open tickets at the time of the run
+		if *params != (domain.ScheduledReportParameters{}) {
+			return fmt.Errorf("%w: the backlog report takes no parameters", ErrInvalidScheduledReport)
+		}
+		return nil
+	}
+
+	if params.RangeDays == 0 {
+		params.RangeDays = defaultScheduledReportDays
+	}
+	if params.RangeDays < 1 || params.RangeDays > maxScheduledReportDays {
+		return fmt.Errorf("%w: range_days must be between 1 and %d", ErrInvalidScheduledReport, maxScheduledReportDays)
+	}
+
+	if kind == domain.AgentsReport {
+		if params.Interval != "" || params.Category != "" || params.Priority != "" || params.AssigneeID != nil || params.Department != "" {
+			return fmt.Errorf("%w: the agents report only takes range_days", ErrInvalidScheduledReport)
+		}
+		return nil
+	}
+
+	if params.Interval == "" {
+		params.Interval = string(DayInterval)
+	}
+	if !SeriesInterval(params.Interval).IsValid() {
+		return fmt.Errorf("%w: interval must be hour, day, week or month", ErrInvalidScheduledReport)
+	}
+	if params.Priority != "" && !params.Priority.IsValid() {
+		return fmt.Errorf("%w: invalid priority", ErrInvalidScheduledReport)
+	}
+	return nil
+}
+
+// nextReportRun returns when an active report runs next after now, in the
+// report's time zone. It is nil for inactive reports and for schedules that
+// no longer parse.
+func nextReportRun(report *domain.ScheduledReport, now time.Time) *time.Time {
+	if !report.IsActive {
+		return nil
+	}
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		return nil
+	}
+	schedule, err := cron.Parse(report.Schedule)
+	if err != nil {
+		return nil
+	}
+	next := schedule.Next(now.In(loc))
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
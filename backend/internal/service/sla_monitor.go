@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"time"
+)
+
+const (
+	// slaWarningWindow is how long before a breach the SLA warning is raised
+	slaWarningWindow = time.Hour
+	slaCheckInterval = time.Minute
+	slaCheckBatch    = 100
+)
+
+// SLAMonitor raises a warning event for tickets about to breach their SLA
+type SLAMonitor struct {
+	ticketRepo *repository.TicketRepository
+	broker     *events.Broker
+	startedAt  time.Time
+}
+
+func NewSLAMonitor(ticketRepo *repository.TicketRepository, broker *events.Broker) *SLAMonitor {
+	return &SLAMonitor{
+		ticketRepo: ticketRepo,
+		broker:     broker,
+	}
+}
+
+// Start checks for at-risk tickets every minute until ctx is cancelled
+func (m *SLAMonitor) Start(ctx context.Context) {
+	m.startedAt = time.Now()
+	ticker := time.NewTicker(slaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.check(ctx); err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("SLA check failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check warns once about every unresolved ticket breaching within the warning
+// window, even when several servers run the monitor. Tickets that breached before the monitor started are skipped, so
+// that a restart after downtime does not warn about all of them at once.
+func (m *SLAMonitor) check(ctx context.Context) error {
+	for {
+		now := time.Now()
+		tickets, err := m.ticketRepo.ListSLAAtRisk(ctx, m.startedAt, now.Add(slaWarningWindow), slaCheckBatch)
+		if err != nil || len(tickets) == 0 {
+			return err
+		}
+
+		for i := range tickets {
+			ticket := &tickets[i]
+			marked, err := m.ticketRepo.MarkSLAWarned(ctx, ticket.ID, now)
+			if err != nil {
+				return err
+			}
+			if !marked {
+				continue
+			}
+			ticket.SLAWarnedAt = &now
+			publish(m.broker, events.NewTicketEvent(events.SLAWarning, ticket, 0))
+		}
+
+		if len(tickets) < slaCheckBatch {
+			return nil
+		}
+	}
+}
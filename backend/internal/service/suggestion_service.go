@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"helpdesk-backend/internal/textsim"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidDeflection is returned when a deflection does not name exactly one
+// article or ticket that solved the problem
+var ErrInvalidDeflection = errors.New("invalid deflection")
+
+const (
+	// Candidates are ranked in memory, so only the most recent are considered
+	suggestionArticleCandidates = 1000
+	suggestionTicketCandidates  = 2000
+
+	suggestionLimit    = 5
+	suggestionMinScore = 0.1
+
+	// suggestionIndexTTL keeps a draft being typed from listing and indexing
+	// every candidate on each request
+	suggestionIndexTTL = time.Minute
+)
+
+// TicketDraft is a ticket that is being written but not created yet
+type TicketDraft struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ArticleSuggestion is a knowledge base article relevant to a draft ticket
+type ArticleSuggestion struct {
+	Article domain.Article `json:"article"`
+	Score   float64        `json:"score"`
+}
+
+// TicketSuggestion is a resolved ticket similar to a draft ticket
+type TicketSuggestion struct {
+	Ticket domain.Ticket `json:"ticket"`
+	Score  float64       `json:"score"`
+}
+
+// Suggestions are the answers found for a draft ticket, best first
+type Suggestions struct {
+	Articles []ArticleSuggestion `json:"articles"`
+	Tickets  []TicketSuggestion  `json:"tickets"`
+}
+
+// DeflectionReport summarizes the tickets that were never created because a
+// suggestion solved the problem
+type DeflectionReport struct {
+	Total       int                               `json:"total"`
+	ByArticles  int                               `json:"by_articles"`
+	ByTickets   int                               `json:"by_tickets"`
+	TopArticles []repository.DeflectionArticleRow `json:"top_articles"`
+}
+
+// suggestionCorpus is a set of candidates and the index built over them
+type suggestionCorpus[T any] struct {
+	items   []T
+	index   *textsim.Index
+	builtAt time.Time
+}
+
+func newSuggestionCorpus[T any](items []T, document func(T) textsim.Document) *suggestionCorpus[T] {
+	documents := make([]textsim.Document, len(items))
+	for i, item := range items {
+		documents[i] = document(item)
+	}
+	return &suggestionCorpus[T]{items: items, index: textsim.NewIndex(documents), builtAt: time.Now()}
+}
+
+func (c *suggestionCorpus[T]) fresh() bool {
+	return c != nil && time.Since(c.builtAt) < suggestionIndexTTL
+}
+
+// SuggestionService suggests knowledge base articles and similar resolved
+// tickets while a ticket is being written, to deflect tickets that already
+// have an answer. Similarity is TF-IDF computed locally over recent articles
+// and tickets.
+//
+// The indexes over articles and over every resolved ticket are kept for a
+// minute, so new answers may take that long to be suggested. An end user's
+// own resolved tickets are few enough to be indexed on each request.
+type SuggestionService struct {
+	articleRepo    *repository.ArticleRepository
+	ticketRepo     *repository.TicketRepository
+	deflectionRepo *repository.DeflectionRepository
+	articles       *ArticleService
+
+	mu             sync.Mutex
+	articleCorpora map[domain.ArticleVisibility]*suggestionCorpus[domain.Article]
+	ticketCorpus   *suggestionCorpus[domain.Ticket]
+}
+
+func NewSuggestionService(articleRepo *repository.ArticleRepository, ticketRepo *repository.TicketRepository, deflectionRepo *repository.DeflectionRepository, articles *ArticleService) *SuggestionService {
+	return &SuggestionService{
+		articleRepo:    articleRepo,
+		ticketRepo:     ticketRepo,
+		deflectionRepo: deflectionRepo,
+		articles:       articles,
+		articleCorpora: make(map[domain.ArticleVisibility]*suggestionCorpus[domain.Article]),
+	}
+}
+
+// Suggest returns the articles and resolved tickets most similar to a draft.
+// End users only get public articles and their own tickets.
+func (s *SuggestionService) Suggest(ctx context.Context, draft TicketDraft, viewer domain.Viewer) (*Suggestions, error) {
+	suggestions := &Suggestions{Articles: []ArticleSuggestion{}, Tickets: []TicketSuggestion{}}
+	query := textsim.Document{Title: draft.Title, Body: draft.Description}
+	if len(textsim.Tokenize(query.Title+" "+query.Body)) == 0 {
+		return suggestions, nil
+	}
+
+	articles, err := s.articleCorpus(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range articles.index.Query(query, suggestionMinScore, suggestionLimit) {
+		suggestions.Articles = append(suggestions.Articles, ArticleSuggestion{Article: articles.items[match.Index], Score: match.Score})
+	}
+
+	tickets, err := s.ticketCorpusFor(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range tickets.index.Query(query, suggestionMinScore, suggestionLimit) {
+		suggestions.Tickets = append(suggestions.Tickets, TicketSuggestion{Ticket: tickets.items[match.Index], Score: match.Score})
+	}
+
+	return suggestions, nil
+}
+
+// articleCorpus returns the published articles the viewer may read, indexed
+func (s *SuggestionService) articleCorpus(ctx context.Context, viewer domain.Viewer) (*suggestionCorpus[domain.Article], error) {
+	var visibility domain.ArticleVisibility
+	if !viewer.IsStaff() {
+		visibility = domain.PublicArticle
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if corpus := s.articleCorpora[visibility]; corpus.fresh() {
+		return corpus, nil
+	}
+	articles, err := s.articleRepo.ListPublished(ctx, visibility, suggestionArticleCandidates)
+	if err != nil {
+		return nil, err
+	}
+	corpus := newSuggestionCorpus(articles, func(article domain.Article) textsim.Document {
+		return textsim.Document{Title: article.Title, Body: article.Body}
+	})
+	s.articleCorpora[visibility] = corpus
+	return corpus, nil
+}
+
+// ticketCorpusFor returns the resolved tickets the viewer may read, indexed.
+// Only the staff corpus is shared between requests.
+func (s *SuggestionService) ticketCorpusFor(ctx context.Context, viewer domain.Viewer) (*suggestionCorpus[domain.Ticket], error) {
+	if !viewer.IsStaff() {
+		return s.loadTicketCorpus(ctx, viewer.UserID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticketCorpus.fresh() {
+		return s.ticketCorpus, nil
+	}
+	corpus, err := s.loadTicketCorpus(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.ticketCorpus = corpus
+	return corpus, nil
+}
+
+func (s *SuggestionService) loadTicketCorpus(ctx context.Context, requesterID uint) (*suggestionCorpus[domain.Ticket], error) {
+	tickets, err := s.ticketRepo.ListResolved(ctx, requesterID, suggestionTicketCandidates)
+	if err != nil {
+		return nil, err
+	}
+	return newSuggestionCorpus(tickets, func(ticket domain.Ticket) textsim.Document {
+		return textsim.Document{Title: ticket.Title, Body: ticket.Description}
+	}), nil
+}
+
+// RecordDeflection records that an article or resolved ticket solved the
+// viewer's problem, so the draft ticket will not be created
+func (s *SuggestionService) RecordDeflection(ctx context.Context, deflection *domain.TicketDeflection, viewer domain.Viewer) error {
+	if (deflection.ArticleID == nil) == (deflection.TicketID == nil) {
+		return fmt.Errorf("%w: give either article_id or ticket_id", ErrInvalidDeflection)
+	}
+	if deflection.ArticleID != nil {
+		article, err := s.articles.GetArticle(ctx, *deflection.ArticleID, viewer)
+		if err != nil {
+			return err
+		}
+		if article.Status != domain.ArticlePublished {
+			return fmt.Errorf("%w: article is not published", ErrInvalidDeflection)
+		}
+	}
+	if deflection.TicketID != nil {
+		ticket, err := ticketForViewer(ctx, s.ticketRepo, *deflection.TicketID, viewer)
+		if err != nil {
+			return err
+		}
+		if ticket.Status != domain.ResolvedStatus && ticket.Status != domain.ClosedStatus {
+			return fmt.Errorf("%w: ticket is not resolved", ErrInvalidDeflection)
+		}
+	}
+
+	deflection.Title = strings.TrimSpace(deflection.Title)
+	deflection.UserID = viewer.UserID
+	return s.deflectionRepo.Create(ctx, deflection)
+}
+
+// DeflectionReport counts deflections and the articles that deflected most
+func (s *SuggestionService) DeflectionReport(ctx context.Context, filter repository.DeflectionFilter) (*DeflectionReport, error) {
+	byArticles, byTickets, err := s.deflectionRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	top, err := s.deflectionRepo.SummarizeByArticle(ctx, filter, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeflectionReport{
+		Total:       byArticles + byTickets,
+		ByArticles:  byArticles,
+		ByTickets:   byTickets,
+		TopArticles: top,
+	}, nil
+}
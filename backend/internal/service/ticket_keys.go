@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketKeyFormat describes how human-readable ticket keys are built, e.g.
+// HD-2026-00042. Every prefix (and year, when Yearly is set) has its own gapless sequence.
+type TicketKeyFormat struct {
+	Prefix           string
+	CategoryPrefixes map[string]string // lowercase category to prefix
+	Yearly           bool
+	Digits           int
+}
+
+// scope returns the counter scope for a ticket of the given category created at t
+func (f TicketKeyFormat) scope(category string, t time.Time) string {
+	prefix := f.Prefix
+	if p, ok := f.CategoryPrefixes[strings.ToLower(strings.TrimSpace(category))]; ok {
+		prefix = p
+	}
+	if prefix == "" {
+		prefix = "HD"
+	}
+	if f.Yearly {
+		return fmt.Sprintf("%s-%d", prefix, t.Year())
+	}
+	return prefix
+}
+
+// keyFunc returns the function formatting sequence numbers of scope into keys
+func (f TicketKeyFormat) keyFunc(scope string) repository.TicketKeyFunc {
+	digits := f.Digits
+	if digits < 1 {
+		digits = 5
+	}
+	return func(seq int) string {
+		return fmt.Sprintf("%s-%0*d", scope, digits, seq)
+	}
+}
+
+// ResolveTicketRef turns a ticket ID or key (case-insensitive) into a ticket ID
+func (s *TicketService) ResolveTicketRef(ctx context.Context, ref string) (uint, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return uint(id), nil
+	}
+	if ref == "" {
+		return 0, ErrNotFound
+	}
+
+	id, err := s.ticketRepo.GetIDByKey(ctx, strings.ToUpper(ref))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+// AssignMissingKeys gives tickets created before keys were introduced a key,
+// in creation order
+func (s *TicketService) AssignMissingKeys(ctx context.Context) error {
+	var afterID uint
+	for {
+		tickets, err := s.ticketRepo.ListWithoutKey(ctx, afterID, renderBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, ticket := range tickets {
+			scope := s.keyFormat.scope(ticket.Category, ticket.CreatedAt)
+			err := s.ticketRepo.AssignKey(ctx, ticket.ID, scope, s.keyFormat.keyFunc(scope))
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				logging.FromContext(ctx).Warn("Failed to assign a ticket key", "ticket_id", ticket.ID, "error", err)
+			}
+			afterID = ticket.ID
+		}
+		if len(tickets) < renderBatchSize {
+			return nil
+		}
+	}
+}
@@ -2,42 +2,245 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
 	"helpdesk-backend/internal/repository"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrInvalidAssignee is returned when a ticket is assigned to a user who is
+// not an agent or admin
+var ErrInvalidAssignee = errors.New("invalid assignee")
+
 type TicketService struct {
-	ticketRepo *repository.TicketRepository
+	ticketRepo        *repository.TicketRepository
+	userRepo          *repository.UserRepository
+	workLogRepo       *repository.WorkLogRepository
+	attachmentService *AttachmentService
+	txManager         *repository.TxManager
+	priorities        *PriorityService
+	broker            *events.Broker
+	keyFormat         TicketKeyFormat
 }
 
-func NewTicketService(ticketRepo *repository.TicketRepository) *TicketService {
+func NewTicketService(ticketRepo *repository.TicketRepository, userRepo *repository.UserRepository, workLogRepo *repository.WorkLogRepository, attachmentService *AttachmentService, txManager *repository.TxManager, priorities *PriorityService, broker *events.Broker, keyFormat TicketKeyFormat) *TicketService {
 	return &TicketService{
-		ticketRepo: ticketRepo,
+		ticketRepo:        ticketRepo,
+		userRepo:          userRepo,
+		workLogRepo:       workLogRepo,
+		attachmentService: attachmentService,
+		txManager:         txManager,
+		priorities:        priorities,
+		broker:            broker,
+		keyFormat:         keyFormat,
 	}
 }
 
-func (s *TicketService) CreateTicket(ctx context.Context, ticket *domain.Ticket) error {
+// CreateTicket files a new ticket. Its priority is computed from impact and
+// urgency unless a different priority is given together with a reason.
+func (s *TicketService) CreateTicket(ctx context.Context, ticket *domain.Ticket, actorID uint) error {
+	matrix, err := s.priorities.Matrix(ctx)
+	if err != nil {
+		return err
+	}
+	override, err := applyPriority(matrix, ticket, nil, actorID)
+	if err != nil {
+		return err
+	}
+	if ticket.AlertFingerprint != nil {
+		// The priority comes from the alert's severity, which is not an
+		// agent's decision the override report should show
+		override = nil
+	}
+
 	// Calculate SLA breach time based on priority
-	slaHours := s.getSLAHours(ticket.Priority)
+	slaHours := getSLAHours(ticket.Priority)
 	breachTime := time.Now().Add(time.Duration(slaHours) * time.Hour)
 	ticket.SLABreachAt = &breachTime
 
-	return s.ticketRepo.Create(ctx, ticket)
+	// A new ticket has no attachments yet, so inline images render as links
+	html, err := renderMarkdown(ctx, nil, 0, ticket.Description)
+	if err != nil {
+		return err
+	}
+	ticket.DescriptionHTML = html
+	s.flagPossibleDuplicate(ctx, ticket)
+
+	scope := s.keyFormat.scope(ticket.Category, time.Now())
+	err = s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		if err := tx.Tickets.CreateWithKey(ctx, ticket, scope, s.keyFormat.keyFunc(scope)); err != nil {
+			return err
+		}
+		return recordOverride(ctx, tx, ticket, override)
+	})
+	if err != nil {
+		return err
+	}
+
+	publish(s.broker, events.NewTicketEvent(events.TicketCreated, ticket, actorID))
+	return nil
 }
 
 func (s *TicketService) GetTicketByID(ctx context.Context, id uint) (*domain.Ticket, error) {
-	return s.ticketRepo.GetByID(ctx, id)
+	ticket, err := s.ticketRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []domain.Ticket{*ticket}
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return &tickets[0], nil
+}
+
+// GetTicketForViewer returns a ticket with its comments, leaving out internal
+// notes and deleted comments for end users. Staff see deleted comments as tombstones.
+func (s *TicketService) GetTicketForViewer(ctx context.Context, id uint, viewer domain.Viewer) (*domain.Ticket, error) {
+	ticket, err := s.ticketRepo.GetByIDForViewer(ctx, id, viewer.IsStaff())
+	if err != nil {
+		return nil, err
+	}
+	redactDeletedComments(ticket.Comments)
+
+	tickets := []domain.Ticket{*ticket}
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return &tickets[0], nil
+}
+
+// UpdateTicket saves changes to a ticket. A changed priority that differs from
+// the one computed from impact and urgency requires an override reason.
+func (s *TicketService) UpdateTicket(ctx context.Context, ticket *domain.Ticket, actorID uint) error {
+	return s.updateTicket(ctx, ticket, actorID, events.TicketUpdated)
+}
+
+// updateTicket saves a ticket and publishes the event of the given type
+func (s *TicketService) updateTicket(ctx context.Context, ticket *domain.Ticket, actorID uint, eventType events.Type) error {
+	previous, err := s.ticketRepo.GetByIDShallow(ctx, ticket.ID)
+	if err != nil {
+		return err
+	}
+	matrix, err := s.priorities.Matrix(ctx)
+	if err != nil {
+		return err
+	}
+	override, err := applyPriority(matrix, ticket, previous, actorID)
+	if err != nil {
+		return err
+	}
+
+	applyTicketRules(ticket)
+	applySLADeadline(previous, ticket)
+
+	html, err := renderMarkdown(ctx, s.attachmentService, ticket.ID, ticket.Description)
+	if err != nil {
+		return err
+	}
+	ticket.DescriptionHTML = html
+
+	err = s.txManager.Run(ctx, func(tx *repository.Tx) error {
+		if err := tx.Tickets.Update(ctx, ticket); err != nil {
+			return err
+		}
+		if err := rearmSLAWarning(ctx, tx, previous, ticket); err != nil {
+			return err
+		}
+		return recordOverride(ctx, tx, ticket, override)
+	})
+	if err != nil {
+		return err
+	}
+	s.recordStatusChange(ctx, previous, ticket, actorID)
+
+	publish(s.broker, events.NewTicketEvent(eventType, ticket, actorID))
+	return nil
+}
+
+// ModifyTicket applies change to the stored ticket and saves it, retrying
+// once with a fresh copy if another update got there first
+func (s *TicketService) ModifyTicket(ctx context.Context, id, actorID uint, change func(ticket *domain.Ticket)) (*domain.Ticket, error) {
+	return s.modifyTicket(ctx, id, actorID, events.TicketUpdated, change)
+}
+
+func (s *TicketService) modifyTicket(ctx context.Context, id, actorID uint, eventType events.Type, change func(ticket *domain.Ticket)) (*domain.Ticket, error) {
+	for attempt := 0; ; attempt++ {
+		ticket, err := s.ticketRepo.GetByIDShallow(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		change(ticket)
+
+		err = s.updateTicket(ctx, ticket, actorID, eventType)
+		if errors.Is(err, repository.ErrVersionConflict) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ticket, nil
+	}
+}
+
+// recordOverride logs a priority override for the report in the transaction
+// that saves the ticket
+func recordOverride(ctx context.Context, tx *repository.Tx, ticket *domain.Ticket, override *domain.PriorityOverride) error {
+	if override == nil {
+		return nil
+	}
+	override.TicketID = ticket.ID
+	return tx.Priorities.CreateOverride(ctx, override)
+}
+
+// rearmSLAWarning clears the SLA warning in the transaction that moves the
+// deadline, so that the new deadline is warned about too. It does not rely on
+// previous having been warned, since the monitor may warn in the meantime.
+func rearmSLAWarning(ctx context.Context, tx *repository.Tx, previous, ticket *domain.Ticket) error {
+	if sameTime(previous.SLABreachAt, ticket.SLABreachAt) {
+		return nil
+	}
+	ticket.SLAWarnedAt = nil
+	return tx.Tickets.ClearSLAWarning(ctx, ticket.ID)
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
-func (s *TicketService) UpdateTicket(ctx context.Context, ticket *domain.Ticket) error {
-	// If status is being changed to resolved, set resolved time
-	if ticket.Status == domain.ResolvedStatus && ticket.ResolvedAt == nil {
-		now := time.Now()
-		ticket.ResolvedAt = &now
+// recordStatusChange logs a status transition for reporting. The ticket is
+// already saved, so a failure is only logged.
+func (s *TicketService) recordStatusChange(ctx context.Context, previous, ticket *domain.Ticket, actorID uint) {
+	change := statusChange(previous, ticket, actorID)
+	if change == nil {
+		return
+	}
+	if err := s.ticketRepo.CreateStatusChange(ctx, change); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record status change", "ticket_id", ticket.ID, "error", err)
 	}
+}
 
-	return s.ticketRepo.Update(ctx, ticket)
+// statusChange returns the status transition between two states of a ticket,
+// or nil if the status did not change
+func statusChange(previous, ticket *domain.Ticket, actorID uint) *domain.TicketStatusChange {
+	if previous.Status == ticket.Status {
+		return nil
+	}
+	return &domain.TicketStatusChange{
+		TicketID:    ticket.ID,
+		FromStatus:  previous.Status,
+		ToStatus:    ticket.Status,
+		ChangedByID: actorID,
+	}
 }
 
 func (s *TicketService) DeleteTicket(ctx context.Context, id uint) error {
@@ -45,29 +248,73 @@ func (s *TicketService) DeleteTicket(ctx context.Context, id uint) error {
 }
 
 func (s *TicketService) ListTickets(ctx context.Context, limit, offset int) ([]domain.Ticket, error) {
-	return s.ticketRepo.List(ctx, limit, offset)
+	tickets, err := s.ticketRepo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
 }
 
 func (s *TicketService) ListTicketsByRequester(ctx context.Context, requesterID uint, limit, offset int) ([]domain.Ticket, error) {
-	return s.ticketRepo.ListByRequester(ctx, requesterID, limit, offset)
+	tickets, err := s.ticketRepo.ListByRequester(ctx, requesterID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
 }
 
 func (s *TicketService) ListTicketsByAssignee(ctx context.Context, assigneeID uint, limit, offset int) ([]domain.Ticket, error) {
-	return s.ticketRepo.ListByAssignee(ctx, assigneeID, limit, offset)
+	tickets, err := s.ticketRepo.ListByAssignee(ctx, assigneeID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
 }
 
-func (s *TicketService) AssignTicket(ctx context.Context, ticketID, assigneeID uint) error {
-	ticket, err := s.ticketRepo.GetByID(ctx, ticketID)
-	if err != nil {
+// AssignTicket assigns a ticket to an agent or admin and starts work on it if
+// it was open
+func (s *TicketService) AssignTicket(ctx context.Context, ticketID, assigneeID, actorID uint) error {
+	if err := s.checkAssignee(ctx, assigneeID); err != nil {
 		return err
 	}
 
-	ticket.AssigneeID = &assigneeID
-	if ticket.Status == domain.OpenStatus {
-		ticket.Status = domain.InProgressStatus
+	_, err := s.modifyTicket(ctx, ticketID, actorID, events.TicketAssigned, func(ticket *domain.Ticket) {
+		ticket.AssigneeID = &assigneeID
+		if ticket.Status == domain.OpenStatus {
+			ticket.Status = domain.InProgressStatus
+		}
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
 	}
+	return err
+}
 
-	return s.ticketRepo.Update(ctx, ticket)
+// checkAssignee ensures that tickets are only assigned to agents and admins
+func (s *TicketService) checkAssignee(ctx context.Context, assigneeID uint) error {
+	assignee, err := s.userRepo.GetByID(ctx, assigneeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: assignee not found", ErrInvalidAssignee)
+		}
+		return err
+	}
+	if assignee.Role != domain.AgentRole && assignee.Role != domain.AdminRole {
+		return fmt.Errorf("%w: tickets can only be assigned to agents or admins", ErrInvalidAssignee)
+	}
+	return nil
 }
 
 // GetDashboardStats returns statistics for the dashboard
@@ -124,7 +371,36 @@ func (s *TicketService) GetDashboardStats(ctx context.Context, userID uint, user
 
 // GetRecentTickets returns the most recent tickets with limit
 func (s *TicketService) GetRecentTickets(ctx context.Context, limit int) ([]domain.Ticket, error) {
-	return s.ticketRepo.GetRecentTickets(ctx, limit)
+	tickets, err := s.ticketRepo.GetRecentTickets(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTimeTotals(ctx, tickets); err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// attachTimeTotals sets the time spent and billable minutes on each ticket
+func (s *TicketService) attachTimeTotals(ctx context.Context, tickets []domain.Ticket) error {
+	ids := make([]uint, len(tickets))
+	for i := range tickets {
+		ids[i] = tickets[i].ID
+	}
+
+	totals, err := s.workLogRepo.TotalsByTicket(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range tickets {
+		if t, ok := totals[tickets[i].ID]; ok {
+			tickets[i].TimeSpentMinutes = t.TotalMinutes
+			tickets[i].BillableMinutes = t.BillableMinutes
+		}
+	}
+	return nil
 }
 
 // DashboardStats represents the statistics for the dashboard
@@ -137,8 +413,45 @@ type DashboardStats struct {
 	AverageResolutionTime int `json:"averageResolutionTime"`
 }
 
+// RenderMissingHTML renders the HTML of ticket descriptions stored before
+// Markdown rendering was introduced
+func (s *TicketService) RenderMissingHTML(ctx context.Context) error {
+	var lastID uint
+	for {
+		tickets, err := s.ticketRepo.ListUnrendered(ctx, lastID, renderBatchSize)
+		if err != nil || len(tickets) == 0 {
+			return err
+		}
+		for _, ticket := range tickets {
+			lastID = ticket.ID
+			html, err := renderMarkdown(ctx, s.attachmentService, ticket.ID, ticket.Description)
+			if err != nil {
+				return err
+			}
+			if err := s.ticketRepo.SetDescriptionHTML(ctx, ticket.ID, ticket.Version, html); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// applyTicketRules keeps fields derived from the ticket status consistent
+func applyTicketRules(ticket *domain.Ticket) {
+	switch ticket.Status {
+	case domain.ResolvedStatus, domain.ClosedStatus:
+		// Tickets closed without being resolved first end there too
+		if ticket.ResolvedAt == nil {
+			now := time.Now()
+			ticket.ResolvedAt = &now
+		}
+	default:
+		// A reopened ticket is resolved again later, not when it was first resolved
+		ticket.ResolvedAt = nil
+	}
+}
+
 // getSLAHours returns the SLA hours based on priority
-func (s *TicketService) getSLAHours(priority domain.TicketPriority) int {
+func getSLAHours(priority domain.TicketPriority) int {
 	switch priority {
 	case domain.CriticalPriority:
 		return 4 // 4 hours
@@ -152,3 +465,13 @@ func (s *TicketService) getSLAHours(priority domain.TicketPriority) int {
 		return 24 // Default to 24 hours
 	}
 }
+
+// applySLADeadline moves the SLA breach time of an unresolved ticket whose
+// priority changed to what the new priority allows since the ticket was created
+func applySLADeadline(previous, ticket *domain.Ticket) {
+	if ticket.Priority == previous.Priority || (ticket.Status != domain.OpenStatus && ticket.Status != domain.InProgressStatus) {
+		return
+	}
+	breachTime := previous.CreatedAt.Add(time.Duration(getSLAHours(ticket.Priority)) * time.Hour)
+	ticket.SLABreachAt = &breachTime
+}
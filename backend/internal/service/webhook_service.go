@@ -0,0 +1,513 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/events"
+	"helpdesk-backend/internal/logging"
+	"helpdesk-backend/internal/repository"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is dead-lettered
+	webhookMaxAttempts = 8
+	// webhookRetryBase is the delay before the first retry; it doubles with every attempt
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = 6 * time.Hour
+
+	webhookTimeout       = 10 * time.Second
+	webhookLease         = 2 * time.Minute
+	webhookBatchSize     = 50
+	webhookConcurrency   = 4
+	webhookPollInterval  = 5 * time.Second
+	webhookResponseLimit = 1024
+
+	// webhookTestEvent is the type of the event sent by SendTest
+	webhookTestEvent = "webhook.test"
+)
+
+// ErrInvalidWebhook is returned when a webhook subscription fails validation
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// WebhookInput holds the editable fields of a webhook. Nil fields are left
+// untouched on update.
+type WebhookInput struct {
+	Name       *string
+	URL        *string
+	Secret     *string
+	EventTypes []string
+	IsActive   *bool
+}
+
+// WebhookService delivers published events to the webhooks subscribed to them.
+// Every delivery is signed, logged and retried with exponential backoff until
+// it succeeds or runs out of attempts.
+type WebhookService struct {
+	webhookRepo *repository.WebhookRepository
+	broker      *events.Broker
+	client      *http.Client
+	wake        chan struct{}
+}
+
+func NewWebhookService(webhookRepo *repository.WebhookRepository, broker *events.Broker) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		broker:      broker,
+		client:      &http.Client{Timeout: webhookTimeout},
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// CreateWebhook validates and stores a new subscription. A signing secret is
+// generated when none is given; it is returned since it cannot be read back later.
+func (s *WebhookService) CreateWebhook(ctx context.Context, input WebhookInput, actorID uint) (*domain.Webhook, string, error) {
+	webhook := &domain.Webhook{IsActive: true, CreatedByID: actorID}
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, "", err
+	}
+	if webhook.Name == "" || webhook.URL == "" || len(webhook.EventTypes) == 0 {
+		return nil, "", fmt.Errorf("%w: name, url and event_types are required", ErrInvalidWebhook)
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		webhook.Secret = secret
+	}
+
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+	return webhook, webhook.Secret, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id uint) (*domain.Webhook, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return webhook, err
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return s.webhookRepo.List(ctx)
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id uint, input WebhookInput) (*domain.Webhook, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookInput(webhook, input); err != nil {
+		return nil, err
+	}
+	if webhook.Name == "" || webhook.URL == "" || webhook.Secret == "" || len(webhook.EventTypes) == 0 {
+		return nil, fmt.Errorf("%w: name, url, secret and event_types cannot be empty", ErrInvalidWebhook)
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	err := s.webhookRepo.Delete(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListDeliveries returns a webhook's delivery log, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID uint, status domain.WebhookDeliveryStatus, limit, offset int) ([]domain.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, webhookID, status, limit, offset)
+}
+
+// RetryDelivery queues a delivery again with a fresh set of attempts. It is
+// meant for dead-lettered deliveries once the receiver has been fixed.
+func (s *WebhookService) RetryDelivery(ctx context.Context, webhookID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery.Status = domain.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.Error = ""
+	if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	s.notifyWorker()
+	return delivery, nil
+}
+
+// SendTest sends a webhook.test event to the webhook right away and returns
+// the logged delivery. A failed test is retried like any other delivery.
+func (s *WebhookService) SendTest(ctx context.Context, id uint) (*domain.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	event := events.Event{
+		Type: webhookTestEvent,
+		Data: map[string]interface{}{
+			"webhook_id": webhook.ID,
+			"message":    "This is a test event from the helpdesk",
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+	// The delivery starts out leased so the background worker leaves it alone
+	deliveries, err := s.enqueue(ctx, event, []domain.Webhook{*webhook}, time.Now().Add(webhookLease))
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &deliveries[0]
+	s.attempt(ctx, webhook, delivery)
+	return delivery, nil
+}
+
+// Start queues a delivery for every published event a webhook subscribes to
+// and sends due deliveries until ctx is cancelled
+func (s *WebhookService) Start(ctx context.Context) {
+	go s.runDeliveries(ctx)
+
+	var lastID uint64
+	for {
+		sub, replay, complete := s.broker.Subscribe(lastID)
+		if !complete {
+			logging.FromContext(ctx).Warn("Some events were no longer available; their webhooks were skipped", "after_event_id", lastID)
+		}
+		for _, event := range replay {
+			s.queueEvent(ctx, event)
+			lastID = event.ID
+		}
+
+		closed := false
+		for !closed {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					closed = true
+					continue
+				}
+				s.queueEvent(ctx, event)
+				lastID = event.ID
+			}
+		}
+	}
+}
+
+// queueEvent stores a pending delivery for every active webhook subscribed to the event
+func (s *WebhookService) queueEvent(ctx context.Context, event events.Event) {
+	webhooks, err := s.webhookRepo.ListActive(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load webhooks", "event_id", event.ID, "error", err)
+		return
+	}
+
+	var subscribed []domain.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(string(event.Type)) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	if _, err := s.enqueue(ctx, event, subscribed, time.Now()); err != nil {
+		logging.FromContext(ctx).Warn("Failed to queue webhook deliveries", "event_id", event.ID, "error", err)
+		return
+	}
+	s.notifyWorker()
+}
+
+// enqueue stores a pending delivery of the event for each webhook, due at nextAttempt
+func (s *WebhookService) enqueue(ctx context.Context, event events.Event, webhooks []domain.Webhook, nextAttempt time.Time) ([]domain.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]domain.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     string(event.Type),
+			Payload:       string(payload),
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: &nextAttempt,
+		})
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// notifyWorker wakes the delivery loop without blocking
+func (s *WebhookService) notifyWorker() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *WebhookService) runDeliveries(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue sends every delivery that is due, a few at a time
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := s.webhookRepo.ClaimDue(ctx, time.Now(), webhookLease, webhookBatchSize)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Failed to claim webhook deliveries", "error", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		webhooks := make(map[uint]*domain.Webhook)
+		for _, delivery := range deliveries {
+			if _, ok := webhooks[delivery.WebhookID]; ok {
+				continue
+			}
+			webhook, err := s.webhookRepo.GetByID(ctx, delivery.WebhookID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				logging.FromContext(ctx).Warn("Failed to load webhook", "webhook_id", delivery.WebhookID, "error", err)
+				return
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, webhookConcurrency)
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				s.attempt(ctx, webhooks[delivery.WebhookID], delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome. Failed deliveries
+// are rescheduled with exponential backoff or dead-lettered after the last attempt.
+func (s *WebhookService) attempt(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) {
+	now := time.Now()
+	delivery.ResponseCode = nil
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	if webhook == nil || !webhook.IsActive {
+		// Dead-letter without sending so the delivery can be retried once the
+		// webhook is enabled again
+		delivery.Status = domain.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.Error = "webhook is disabled"
+		s.recordAttempt(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	err := s.send(ctx, webhook, delivery)
+	delivery.DurationMs = time.Since(now).Milliseconds()
+
+	switch {
+	case err == nil:
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = domain.WebhookDeliveryDead
+		delivery.NextAttemptAt = nil
+		delivery.Error = err.Error()
+	default:
+		next := time.Now().Add(webhookBackoff(delivery.Attempts))
+		delivery.Status = domain.WebhookDeliveryPending
+		delivery.NextAttemptAt = &next
+		delivery.Error = err.Error()
+	}
+
+	s.recordAttempt(ctx, delivery)
+}
+
+func (s *WebhookService) recordAttempt(ctx context.Context, delivery *domain.WebhookDelivery) {
+	// Record the outcome even if the worker is shutting down
+	if err := s.webhookRepo.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send POSTs the payload, signed with the webhook secret. Any status outside
+// 2xx counts as a failure.
+func (s *WebhookService) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Helpdesk-Webhooks/1.0")
+	req.Header.Set("X-Helpdesk-Event", delivery.EventType)
+	req.Header.Set("X-Helpdesk-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Helpdesk-Timestamp", timestamp)
+	req.Header.Set("X-Helpdesk-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	delivery.ResponseCode = &code
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	delivery.ResponseBody = string(snippet)
+
+	if code < 200 || code > 299 {
+		return fmt.Errorf("receiver responded with status %d", code)
+	}
+	return nil
+}
+
+// SignWebhookPayload returns the hex encoded HMAC-SHA256 of "timestamp.body".
+// Receivers recompute it with their copy of the secret to verify a delivery.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns how long to wait after the given number of failed attempts
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+func applyWebhookInput(webhook *domain.Webhook, input WebhookInput) error {
+	if input.Name != nil {
+		webhook.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.URL != nil {
+		target, err := url.Parse(strings.TrimSpace(*input.URL))
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+		}
+		webhook.URL = target.String()
+	}
+	if input.Secret != nil && *input.Secret != "" {
+		webhook.Secret = *input.Secret
+	}
+	if input.EventTypes != nil {
+		eventTypes, err := normalizeWebhookEventTypes(input.EventTypes)
+		if err != nil {
+			return err
+		}
+		webhook.EventTypes = eventTypes
+	}
+	if input.IsActive != nil {
+		webhook.IsActive = *input.IsActive
+	}
+	return nil
+}
+
+// normalizeWebhookEventTypes checks the requested event types and removes
+// duplicates. "*" subscribes to every event type.
+func normalizeWebhookEventTypes(requested []string) ([]string, error) {
+	known := make(map[string]bool, len(events.Types)+1)
+	known["*"] = true
+	for _, t := range events.Types {
+		known[string(t)] = true
+	}
+
+	seen := make(map[string]bool, len(requested))
+	eventTypes := make([]string, 0, len(requested))
+	for _, t := range requested {
+		t = strings.TrimSpace(t)
+		if !known[t] {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, t)
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		eventTypes = append(eventTypes, t)
+	}
+	return eventTypes, nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
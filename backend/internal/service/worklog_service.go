@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"helpdesk-backend/internal/domain"
+	"helpdesk-backend/internal/repository"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxWorkLogMinutes caps a single work log entry at one day
+const maxWorkLogMinutes = 24 * 60
+
+var (
+	ErrTimerAlreadyRunning = errors.New("a timer is already running on this ticket")
+	ErrTimerNotRunning     = errors.New("no timer is running on this ticket")
+	// ErrTimerTooLong is returned when a timer ran longer than one entry may
+	// hold, so the agent has to say how much of that time was worked
+	ErrTimerTooLong    = errors.New("timer ran for more than 24 hours; give the duration_minutes actually worked")
+	ErrInvalidDuration = errors.New("invalid duration")
+)
+
+// WorkLogService handles time tracking on tickets
+type WorkLogService struct {
+	workLogRepo *repository.WorkLogRepository
+	ticketRepo  *repository.TicketRepository
+}
+
+// NewWorkLogService creates a new work log service
+func NewWorkLogService(workLogRepo *repository.WorkLogRepository, ticketRepo *repository.TicketRepository) *WorkLogService {
+	return &WorkLogService{
+		workLogRepo: workLogRepo,
+		ticketRepo:  ticketRepo,
+	}
+}
+
+// LogWork records a manual work log entry on a ticket
+func (s *WorkLogService) LogWork(ctx context.Context, workLog *domain.WorkLog) error {
+	if err := validateDuration(workLog.DurationMinutes); err != nil {
+		return err
+	}
+
+	if _, err := s.ticketRepo.GetByID(ctx, workLog.TicketID); err != nil {
+		return errors.New("ticket not found")
+	}
+
+	if workLog.WorkDate.IsZero() {
+		workLog.WorkDate = today()
+	}
+
+	return s.workLogRepo.Create(ctx, workLog)
+}
+
+func (s *WorkLogService) GetWorkLogByID(ctx context.Context, id uint) (*domain.WorkLog, error) {
+	return s.workLogRepo.GetByID(ctx, id)
+}
+
+// UpdateWorkLog saves changes to an existing work log entry
+func (s *WorkLogService) UpdateWorkLog(ctx context.Context, workLog *domain.WorkLog) error {
+	if err := validateDuration(workLog.DurationMinutes); err != nil {
+		return err
+	}
+
+	return s.workLogRepo.Update(ctx, workLog)
+}
+
+func (s *WorkLogService) DeleteWorkLog(ctx context.Context, id uint) error {
+	return s.workLogRepo.Delete(ctx, id)
+}
+
+func (s *WorkLogService) ListWorkLogsByTicket(ctx context.Context, ticketID uint) ([]domain.WorkLog, error) {
+	return s.workLogRepo.ListByTicket(ctx, ticketID)
+}
+
+// StartTimer starts a timer for an agent on a ticket
+func (s *WorkLogService) StartTimer(ctx context.Context, ticketID, agentID uint, billable bool, note string) (*domain.WorkTimer, error) {
+	if _, err := s.ticketRepo.GetByID(ctx, ticketID); err != nil {
+		return nil, errors.New("ticket not found")
+	}
+
+	if existing, err := s.workLogRepo.GetTimer(ctx, ticketID, agentID); err == nil && existing != nil {
+		return nil, ErrTimerAlreadyRunning
+	}
+
+	timer := &domain.WorkTimer{
+		TicketID:  ticketID,
+		AgentID:   agentID,
+		Billable:  billable,
+		Note:      note,
+		StartedAt: time.Now(),
+	}
+
+	if err := s.workLogRepo.CreateTimer(ctx, timer); err != nil {
+		// Another request started a timer since the check above
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrTimerAlreadyRunning
+		}
+		return nil, err
+	}
+	return timer, nil
+}
+
+// StopTimer stops an agent's running timer and records the elapsed time as a work log.
+// A non-empty note replaces the note given when the timer was started, and a
+// duration replaces the elapsed time. A timer that ran longer than 24 hours
+// can only be stopped with a duration.
+func (s *WorkLogService) StopTimer(ctx context.Context, ticketID, agentID uint, note string, durationMinutes *int) (*domain.WorkLog, error) {
+	timer, err := s.workLogRepo.GetTimer(ctx, ticketID, agentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimerNotRunning
+		}
+		return nil, err
+	}
+
+	// Round up to whole minutes so a short timer is never logged as zero
+	minutes := int(math.Ceil(time.Since(timer.StartedAt).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	if durationMinutes != nil {
+		if err := validateDuration(*durationMinutes); err != nil {
+			return nil, err
+		}
+		if *durationMinutes > minutes {
+			return nil, fmt.Errorf("%w: the timer only ran for %d minutes", ErrInvalidDuration, minutes)
+		}
+		minutes = *durationMinutes
+	} else if minutes > maxWorkLogMinutes {
+		return nil, ErrTimerTooLong
+	}
+
+	if note == "" {
+		note = timer.Note
+	}
+
+	workLog := &domain.WorkLog{
+		TicketID:        timer.TicketID,
+		AgentID:         timer.AgentID,
+		DurationMinutes: minutes,
+		Billable:        timer.Billable,
+		Note:            note,
+		WorkDate:        timer.StartedAt.UTC().Truncate(24 * time.Hour),
+	}
+
+	if err := s.workLogRepo.StopTimer(ctx, timer, workLog); err != nil {
+		// Another request stopped the timer since it was read
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTimerNotRunning
+		}
+		return nil, err
+	}
+	return workLog, nil
+}
+
+// ListActiveTimers returns the timers an agent currently has running
+func (s *WorkLogService) ListActiveTimers(ctx context.Context, agentID uint) ([]domain.WorkTimer, error) {
+	return s.workLogRepo.ListTimersByAgent(ctx, agentID)
+}
+
+// GetTimeReport aggregates logged time by agent, category, department or computer
+func (s *WorkLogService) GetTimeReport(ctx context.Context, groupBy string, filter repository.TimeReportFilter) ([]repository.TimeReportRow, error) {
+	switch groupBy {
+	case "agent", "category", "department", "computer":
+	default:
+		return nil, errors.New("group_by must be one of agent, category, department, computer")
+	}
+
+	return s.workLogRepo.SummarizeBy(ctx, groupBy, filter)
+}
+
+func validateDuration(minutes int) error {
+	if minutes <= 0 {
+		return fmt.Errorf("%w: must be greater than zero", ErrInvalidDuration)
+	}
+	if minutes > maxWorkLogMinutes {
+		return fmt.Errorf("%w: cannot exceed 24 hours", ErrInvalidDuration)
+	}
+	return nil
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,181 @@
+// Package textsim scores how similar short texts such as ticket titles and
+// descriptions are, using TF-IDF weighted cosine similarity. Everything is
+// computed in memory, so it suits candidate sets of a few thousand documents.
+package textsim
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Document is a text to compare. Title terms count twice as much as body
+// terms since titles tend to name the problem.
+type Document struct {
+	Title string
+	Body  string
+}
+
+// Match is a document of an index that is similar to a query
+type Match struct {
+	Index int     // position of the document in the slice given to NewIndex
+	Score float64 // cosine similarity, from 0 to 1
+}
+
+// Index holds the TF-IDF vectors of a set of documents. It is never modified
+// once built, so it may be queried from several goroutines at once.
+type Index struct {
+	idf     map[string]float64
+	vectors []vector
+}
+
+type vector map[string]float64
+
+// NewIndex builds an index over documents, using them to weigh terms: terms
+// found in many of the documents count less than rare ones
+func NewIndex(documents []Document) *Index {
+	counts := make([]map[string]float64, len(documents))
+	df := make(map[string]int)
+	for i, doc := range documents {
+		counts[i] = termCounts(doc)
+		for term := range counts[i] {
+			df[term]++
+		}
+	}
+
+	n := float64(len(documents))
+	idf := make(map[string]float64, len(df))
+	for term, count := range df {
+		// Smoothed so that terms found in every document still count a little
+		idf[term] = math.Log((1+n)/(1+float64(count))) + 1
+	}
+
+	index := &Index{idf: idf, vectors: make([]vector, len(documents))}
+	for i, tf := range counts {
+		index.vectors[i] = index.weigh(tf)
+	}
+	return index
+}
+
+// Query returns the documents whose similarity to doc is at least minScore,
+// most similar first, at most limit of them. A limit of zero or less returns
+// every match.
+func (ix *Index) Query(doc Document, minScore float64, limit int) []Match {
+	query := ix.weigh(termCounts(doc))
+	if len(query) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for i, v := range ix.vectors {
+		if score := dot(query, v); score > 0 && score >= minScore {
+			matches = append(matches, Match{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// weigh turns term counts into a unit length TF-IDF vector. Terms the index
+// has never seen are dropped since they cannot match anything.
+func (ix *Index) weigh(tf map[string]float64) vector {
+	v := make(vector, len(tf))
+	var norm float64
+	for term, count := range tf {
+		idf, ok := ix.idf[term]
+		if !ok {
+			continue
+		}
+		w := (1 + math.Log(count)) * idf
+		v[term] = w
+		norm += w * w
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	for term := range v {
+		v[term] /= norm
+	}
+	return v
+}
+
+func dot(a, b vector) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var sum float64
+	for term, w := range a {
+		sum += w * b[term]
+	}
+	return sum
+}
+
+func termCounts(doc Document) map[string]float64 {
+	counts := make(map[string]float64)
+	for _, term := range Tokenize(doc.Title) {
+		counts[term] += 2
+	}
+	for _, term := range Tokenize(doc.Body) {
+		counts[term]++
+	}
+	return counts
+}
+
+// Tokenize splits text into lower-case terms, dropping stop words and
+// single characters and reducing plurals and common verb endings to a stem
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < 2 || stopWords[field] {
+			continue
+		}
+		terms = append(terms, stem(field))
+	}
+	return terms
+}
+
+// stem strips a few English suffixes so that "prints", "printed" and
+// "printing" share the term "print", and "printers" shares "printer". It is
+// deliberately crude: over-stemming only merges terms that rarely need
+// telling apart in support tickets.
+func stem(term string) string {
+	for _, suffix := range []string{"ing", "ed", "s"} {
+		if strings.HasSuffix(term, suffix) && len(term)-len(suffix) >= 3 {
+			if suffix == "s" && strings.HasSuffix(term, "ss") {
+				return term
+			}
+			return strings.TrimSuffix(term, suffix)
+		}
+	}
+	return term
+}
+
+var stopWords = toSet(
+	"a", "about", "after", "again", "all", "am", "an", "and", "any", "are", "as", "at",
+	"be", "been", "before", "but", "by", "can", "cannot", "could", "did", "do", "does",
+	"doesn", "don", "for", "from", "get", "got", "had", "has", "have", "hello", "hi",
+	"how", "i", "if", "in", "into", "is", "it", "its", "just", "me", "my", "no", "not",
+	"of", "on", "or", "our", "please", "so", "some", "still", "than", "thanks", "that",
+	"the", "their", "them", "then", "there", "these", "this", "to", "up", "us", "was",
+	"we", "were", "what", "when", "where", "which", "while", "who", "why", "will",
+	"with", "would", "you", "your",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
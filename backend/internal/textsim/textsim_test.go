@@ -0,0 +1,103 @@
+package textsim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"prints", "print"},
+		{"printed", "print"},
+		{"printing", "print"},
+		{"printer", "printer"},
+		{"printers", "printer"},
+		{"access", "access"},
+		{"bus", "bus"},
+		{"red", "red"},
+		{"sing", "sing"},
+		{"vpn", "vpn"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.term); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Hi, I cannot print!", []string{"print"}},
+		{"VPN keeps FAILING after 5 minutes", []string{"vpn", "keep", "fail", "minute"}},
+		{"e-mail sync-errors", []string{"mail", "sync", "error"}},
+		{"Outlook 2016 crashed", []string{"outlook", "2016", "crash"}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	index := NewIndex([]Document{
+		{Title: "Printer offline", Body: "The office printer shows as offline after the update."},
+		{Title: "Reset password", Body: "How to reset a forgotten password."},
+		{Title: "VPN disconnects", Body: "The VPN connection drops every few minutes."},
+		{Title: "Printing is slow", Body: "Documents take minutes to print."},
+	})
+
+	tests := []struct {
+		name     string
+		query    Document
+		minScore float64
+		limit    int
+		want     []int
+	}{
+		{"best match first", Document{Title: "printer is offline"}, 0, 0, []int{0}},
+		{"stems match", Document{Title: "cannot print documents"}, 0, 0, []int{3}},
+		{"several matches", Document{Body: "printer offline and printing slow"}, 0, 0, []int{0, 3}},
+		{"limit", Document{Body: "printer offline and printing slow"}, 0, 1, []int{0}},
+		{"min score", Document{Title: "password", Body: "vpn printer offline slow print minutes"}, 0.5, 0, nil},
+		{"unknown terms", Document{Title: "keyboard"}, 0, 0, nil},
+		{"only stop words", Document{Title: "how do I"}, 0, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := index.Query(tt.query, tt.minScore, tt.limit)
+			var got []int
+			for i, match := range matches {
+				got = append(got, match.Index)
+				if match.Score <= 0 || match.Score > 1+1e-9 {
+					t.Errorf("match %d has score %v, want a score in (0, 1]", match.Index, match.Score)
+				}
+				if i > 0 && match.Score > matches[i-1].Score {
+					t.Errorf("matches are not sorted by score: %v", matches)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Query(%+v) matched %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryIdenticalDocument(t *testing.T) {
+	doc := Document{Title: "Outlook crashes", Body: "Outlook crashes when opening attachments."}
+	index := NewIndex([]Document{doc, {Title: "Unrelated", Body: "Monitor flickers."}})
+
+	matches := index.Query(doc, 0, 0)
+	if len(matches) != 1 || matches[0].Index != 0 {
+		t.Fatalf("Query matched %v, want only document 0", matches)
+	}
+	if score := matches[0].Score; score < 1-1e-9 {
+		t.Errorf("identical document scored %v, want 1", score)
+	}
+}